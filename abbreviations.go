@@ -0,0 +1,70 @@
+package cmdline
+
+import "strings"
+
+// makes Process accept an unambiguous prefix of a long option, so --verb is
+// processed as --verbose when no other option starts with --verb
+func (cl *CommandLine) EnableOptionAbbreviations() {
+	cl.abbreviations = true
+}
+
+// rewrites abbreviated long options into their full names; an abbreviation shared
+// by more than one option is an error
+func expandAbbreviations(args []string, names []string, lookup func(name string) (*argSpec, bool)) ([]string, error) {
+	expanded := make([]string, 0, len(args))
+
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "--") || arg == "--" {
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		prefix, value := arg, ""
+		delimiter := strings.IndexAny(arg, ":=")
+		if delimiter >= 0 {
+			prefix, value = arg[:delimiter], arg[delimiter:]
+		}
+
+		if _, exists := lookup(prefix); exists {
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		candidates := []string{}
+		matched := map[*argSpec]bool{}
+		for _, name := range names {
+			if !strings.HasPrefix(name, "--") || !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			as, _ := lookup(name)
+			if !matched[as] {
+				matched[as] = true
+				candidates = append(candidates, name)
+			}
+		}
+
+		switch len(candidates) {
+		case 0:
+			expanded = append(expanded, arg)
+		case 1:
+			expanded = append(expanded, candidates[0]+value)
+		default:
+			return nil, newKindError(ErrAmbiguousOption, prefix, "", "Ambiguous option: %s could be %s", prefix, strings.Join(candidates, ", "))
+		}
+	}
+
+	return expanded, nil
+}
+
+// determines if an arg names an option of the unnamed command, which is the command
+// to run before any command token, so that a global abbreviation doesn't take it
+func (cl *CommandLine) isUnnamedCommandOption(arg string) bool {
+	if cl.unnamedCmd == nil {
+		return false
+	}
+	if delimiter := strings.IndexAny(arg, ":="); delimiter >= 0 {
+		arg = arg[:delimiter]
+	}
+	_, exists := cl.unnamedCmd.OptionSpecs.lookup(arg)
+	return exists
+}
@@ -1,23 +1,48 @@
 package cmdline
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/jimsnab/go-simpleutils"
 )
 
 type argValueSpec struct {
-	ArgIndex     int
-	OptionName   string
-	Optional     bool
-	Multi        bool
-	DefaultValue any
+	ArgIndex        int
+	TypeName        string
+	OptionName      string
+	Optional        bool
+	Multi           bool
+	Ellipsis        bool // repeated by a trailing ..., leaving values for the positions after it
+	DefaultValue    any
+	DefaultText     string // a default from the spec, which can refer to ${NAME}
+	HelpText        string // the help of a positional value
+	Streamer        ValueStreamer
+	RangeText       string
+	RangeMin        any
+	RangeMax        any
+	Pattern         *regexp.Regexp
+	Normalizers     []ValueNormalizer // the normalizers named in the spec
+	NormalizerNames []string
+}
+
+// the value type that counts the times a flag is given, such as -v -v -v
+const countTypeName = "count"
+
+// value types that accept negative numbers, which otherwise look like option switches
+var numericTypeNames = map[string]bool{
+	"int":     true,
+	"float64": true,
+	"int64":   true,
 }
 
 type argSpec struct {
 	CmdLine     *CommandLine
 	Key         string
+	Aliases     []string
 	Unnamed     bool
 	Optional    bool
 	ValuesDelim rune // the delimiter between value name and list of values
@@ -25,27 +50,22 @@ type argSpec struct {
 	ValueSpecs  []*argValueSpec
 	MultiValue  bool
 	HelpText    string
+	Negation    string // the --no-name counterpart of a negatable flag
+	MinRepeat   int    // the fewest times a repeated option can be given
+	MaxRepeat   int    // the most times a repeated option can be given, or 0 for no limit
+	Group       string // the help section of an option
 }
 
-func indexOf(str string, substr string, pos int) int {
-	index := strings.Index(str[pos:], substr)
-	if index >= 0 {
-		index += pos
-	}
-	return index
-}
-
-func parseError(expected string, orgSpec string, specRemaining string, parsePos int) error {
-	if len(specRemaining) <= parsePos || orgSpec == specRemaining[parsePos:] {
-		return fmt.Errorf("%s%s in \"%s\"", basePanic, expected, orgSpec)
-	} else {
-		return fmt.Errorf("%s%s at \"%s\" of \"%s\"", basePanic, expected, specRemaining[parsePos:], orgSpec)
+// subcommand levels are separated by '+', or by '|' in a primary argument
+func specKey(token string, primaryArg bool) string {
+	key := strings.ReplaceAll(token, "+", " ")
+	if primaryArg {
+		key = strings.ReplaceAll(key, "|", " ")
 	}
+	return key
 }
 
-func (cl *CommandLine) newArgSpec(spec string, primaryArg bool) *argSpec {
-	orgSpec := spec
-
+func (cl *CommandLine) parseArgSpec(spec string, primaryArg bool) *argSpec {
 	//
 	// Syntaxes:
 	//
@@ -68,11 +88,36 @@ func (cl *CommandLine) newArgSpec(spec string, primaryArg bool) *argSpec {
 	//      [-arg <value> <value>] ...
 	//      [-arg <value> [<value>]] ...
 	//
+	// A numeric value can be limited to a range, with either bound left open. Example:
+	//
+	//      --port <int[1..65535]-port>
+	//      --timeout <duration[1s..]-timeout>
+	//
+	// A string value can be required to match a regular expression. Example:
+	//
+	//      --name <string/^[a-z0-9-]+$/-name>
+	//
+	// A value can be normalized before it is converted, by naming trim, lower or
+	// upper after the type. Example:
+	//
+	//      --email <string:trim:lower-email>
+	//
+	// A value can repeat by prefixing it with an asterisk or following it with an
+	// ellipsis. An asterisk value takes all of the remaining values, while an ellipsis
+	// value leaves enough values for the positions that follow it. Example:
+	//
+	//      copy <path-src>... <path-dst>
+	//
 	// And the entire spec can be prefixed with asterisk (*) for a value list.
 	// Example:
 	//
 	//      *-arg:<value>
 	//
+	// The number of times a repeated option can be given can be limited, with
+	// either bound left open. Example:
+	//
+	//      *{1..5}[-t:<string-tag>]
+	//
 	// A value that can potentially contain commas can only appear as the only value. Example:
 	//
 	//      -a:<string-a> -b:<string-b>     # values a and b can have a comma
@@ -82,179 +127,375 @@ func (cl *CommandLine) newArgSpec(spec string, primaryArg bool) *argSpec {
 	//
 	//      [-t:<string-text>]?Specifies the text to save
 	//
+	// An option can be given alternate names separated by a pipe (|). Its values
+	// are keyed by the first name. Example:
+	//
+	//      [-v|--verbose]
+	//
+	// A flag without values can be made negatable, which also accepts --no-name to
+	// turn it off. Example:
+	//
+	//      [--[no-]color]
+	//
+	// A primary argument can name a subcommand path, with levels separated by
+	// a pipe (|) or a plus (+). Example:
+	//
+	//      users|create <string-username>
+	//
 
 	as := argSpec{}
 	as.CmdLine = cl
+	as.ValueSpecs = []*argValueSpec{}
 
-	as.HelpText = ""
-	helpCutPoint := strings.LastIndex(spec, "?")
-	if helpCutPoint >= 0 {
+	s := newSpecScanner(spec)
+	if helpCutPoint := lastIndexOutsidePatterns(spec, "?"); helpCutPoint >= 0 {
 		as.HelpText = spec[helpCutPoint+1:]
-		spec = spec[:helpCutPoint]
+		s.end = helpCutPoint
 	}
 
-	as.ValueSpecs = []*argValueSpec{}
-
-	if strings.HasPrefix(spec, "*") {
-		spec = spec[1:]
+	if s.accept('*') {
 		as.MultiValue = true
+
+		if s.peek() == '{' {
+			as.parseRepeatCount(s)
+		}
 	}
 
-	if strings.HasPrefix(spec, "[") && strings.HasSuffix(spec, "]") {
-		spec = spec[1 : len(spec)-1]
+	if s.peek() == '[' && s.end-s.pos >= 2 && spec[s.end-1] == ']' {
 		as.Optional = true
+		s.pos++
+		s.end--
 	}
 
-	argDelimiter := strings.IndexAny(spec, ": ")
+	keyStart := s.pos
+	var key string
+	valuesStarts := []int{}
+	argDelimiter := s.indexAny(": ")
 	if argDelimiter < 0 {
-		as.Key = strings.ReplaceAll(spec, "+", " ")
+		key = spec[keyStart:s.end]
+		s.pos = s.end
 	} else {
-		as.Key = strings.ReplaceAll(spec[:argDelimiter], "+", " ")
+		key = spec[keyStart:argDelimiter]
 		as.ValuesDelim = rune(spec[argDelimiter])
+		s.pos = argDelimiter + 1
 
-		suffix := simpleutils.WhichSuffix(as.Key, " [", "[")
-		if suffix != nil {
-			as.Key = as.Key[:argDelimiter-1]
-			if *suffix == " [" {
-				*suffix = "[ "
-			}
-			spec = *suffix + spec[argDelimiter+1:]
-		} else {
-			spec = spec[argDelimiter+1:]
+		// -arg[:<value>] makes the first value optional
+		optionalFirst := strings.HasSuffix(key, "[")
+		if optionalFirst {
+			key = key[:len(key)-1]
 		}
 
-		parsePos := 0
-		if len(spec) == 0 {
-			panic(parseError("value spec", orgSpec, spec, parsePos))
+		if s.done() {
+			panic(s.errorAt(s.pos, "value spec"))
 		}
 
-		for parsePos < len(spec) {
-			avs := argValueSpec{}
-			avs.Optional = false
+		for !s.done() {
+			valuesStarts = append(valuesStarts, s.pos)
+			as.parseValueSpec(s, optionalFirst && len(as.ValueSpecs) == 0)
+		}
+	}
+	as.Key = specKey(key, primaryArg)
 
-			var optionType string
+	if len(as.Key) == 0 {
+		panic(s.errorAt(keyStart, "argument name"))
+	}
 
-			c := spec[parsePos]
-			if c == '[' {
-				avs.Optional = true
-				parsePos++
-				c = spec[parsePos]
-			} else if parsePos+1 < len(spec) && c == ' ' && spec[parsePos+1] == '[' {
-				avs.Optional = true
-				parsePos++
-			}
+	if as.Key == "~" {
+		as.Unnamed = true
+	}
 
-			if (c == ',' || c == ' ') && len(as.ValueSpecs) > 0 {
-				if as.ValueDelim == 0 {
-					if c == ' ' && as.ValuesDelim == ':' {
-						panic(parseError("comma-separated value spec list", orgSpec, spec, parsePos))
-					}
-					as.ValueDelim = rune(c)
-				} else if as.ValueDelim != rune(c) {
-					panic(parseError("uniform value delimiter", orgSpec, spec, parsePos))
-				}
-				parsePos++
-				c = spec[parsePos]
-			}
+	// an option can have aliases, such as -v|--verbose, that set the first name's key
+	fullKey := as.Key
+	if !primaryArg && strings.Contains(as.Key, "|") {
+		names := strings.Split(as.Key, "|")
+		as.Key = names[0]
+		as.Aliases = names[1:]
+	}
 
-			if c == '*' {
-				avs.Multi = true
-				parsePos++
-				c = spec[parsePos]
-			}
+	// a flag written --[no-]name also accepts --no-name to turn it off
+	if !primaryArg && strings.HasPrefix(as.Key, "--[no-]") {
+		as.Key = "--" + strings.TrimPrefix(as.Key, "--[no-]")
+		as.Negation = "--no-" + strings.TrimPrefix(as.Key, "--")
+		if len(as.ValueSpecs) > 0 {
+			panic(s.errorAt(valuesStarts[0], "negatable flag without values"))
+		}
+		if as.MultiValue {
+			panic(s.errorAt(0, "negatable flag without values"))
+		}
+	}
 
-			if c != '<' {
-				panic(parseError("'<'", orgSpec, spec, parsePos))
-			}
+	for _, name := range append([]string{as.Key}, as.Aliases...) {
+		// remove leading dash or dash-dash
+		trimmedKey := strings.TrimPrefix(name, "-")
+		trimmedKey = strings.TrimPrefix(trimmedKey, "-")
 
-			parsePos++
-			dashPos := indexOf(spec, "-", parsePos)
-			if dashPos < 0 {
-				panic(parseError("'-'", orgSpec, spec, parsePos))
+		if !simpleutils.IsTokenNameWithMiddleChars(trimmedKey, cl.nameMiddleChars(primaryArg)) && !as.Unnamed {
+			namePos := keyStart
+			if index := strings.Index(fullKey, name); index >= 0 {
+				namePos += index
 			}
+			panic(s.errorAt(namePos, "a valid argument token"))
+		}
+	}
 
-			optionType = spec[parsePos:dashPos]
-			ot := cl.optionTypes.StringToAttributes(optionType, orgSpec)
+	// a count value tallies the times its option is given, so it takes no input
+	for i, valueSpec := range as.ValueSpecs {
+		if valueSpec.TypeName == countTypeName && (primaryArg || len(as.ValueSpecs) > 1 || as.MultiValue || valueSpec.Optional || valueSpec.Multi) {
+			panic(s.errorAt(valuesStarts[i], "count value as the only value of a single option"))
+		}
+	}
 
-			// defensive
-			if ot == nil {
-				panic(parseError("valid option type", orgSpec, spec, parsePos))
+	if primaryArg {
+		if as.Optional {
+			panic(s.errorAt(0, "non-optional primary argument"))
+		}
+		if as.MultiValue {
+			// a repeated primary argument repeats its last positional value
+			if len(as.ValueSpecs) == 0 || as.ValuesDelim != ' ' {
+				panic(s.errorAt(0, "single-value primary argument"))
 			}
+			as.ValueSpecs[len(as.ValueSpecs)-1].Multi = true
+			as.MultiValue = false
+		}
+		if as.Unnamed && as.ValuesDelim == ':' {
+			panic(s.errorAt(argDelimiter, "unnamed argument without a value spec"))
+		}
+	} else {
+		if as.Unnamed {
+			panic(s.errorAt(keyStart, "named seconary argument"))
+		}
+	}
 
-			parsePos = dashPos + 1
+	return &as
+}
 
-			closeBracket := indexOf(spec, ">", parsePos)
-			if closeBracket < 0 {
-				panic(parseError("'>'", orgSpec, spec, parsePos))
-			}
+// parses the value spec at the cursor, such as <int[1..9]-count=2>, along with the
+// delimiter that precedes it
+func (as *argSpec) parseValueSpec(s *specScanner, optional bool) {
+	cl := as.CmdLine
+	avs := argValueSpec{}
+	avs.Optional = optional
+
+	c := s.peek()
+	if c == '[' {
+		avs.Optional = true
+		s.pos++
+		c = s.peek()
+	} else if c == ' ' && s.peekAt(1) == '[' {
+		// " [" is a space delimiter followed by an optional value
+		avs.Optional = true
+		s.pos++
+	}
 
-			avs.OptionName = spec[parsePos:closeBracket]
-			if !simpleutils.IsTokenName(avs.OptionName) {
-				panic(parseError("valid option name", orgSpec, spec, parsePos))
+	if (c == ',' || c == ' ') && len(as.ValueSpecs) > 0 {
+		if as.ValueDelim == 0 {
+			if c == ' ' && as.ValuesDelim == ':' {
+				panic(s.errorAt(s.pos, "comma-separated value spec list"))
 			}
+			as.ValueDelim = rune(c)
+		} else if as.ValueDelim != rune(c) {
+			panic(s.errorAt(s.pos, "uniform value delimiter"))
+		}
+		s.pos++
+		c = s.peek()
+	}
 
-			parsePos = closeBracket + 1
+	if c == '*' {
+		avs.Multi = true
+		s.pos++
+		c = s.peek()
+	}
 
-			if avs.Optional {
-				if parsePos >= len(spec) || spec[parsePos] != ']' {
-					panic(parseError("']'", orgSpec, spec, parsePos))
-				}
-				parsePos++
-			}
+	if c != '<' {
+		panic(s.errorAt(s.pos, "'<'"))
+	}
+	s.pos++
 
-			attribs := cl.optionTypes.StringToAttributes(optionType, orgSpec)
+	typeStart := s.pos
+	typeEnd := typeStart
+	for typeEnd < s.end && strings.IndexByte("-[/>", s.spec[typeEnd]) < 0 {
+		typeEnd++
+	}
 
-			avs.ArgIndex = attribs.Index
-			avs.DefaultValue = attribs.DefaultValue
+	dashPos := typeEnd
+	var rangeText, patternText string
+	if typeEnd < s.end && s.spec[typeEnd] == '[' {
+		closeRange := s.index("]", typeEnd)
+		if closeRange < 0 {
+			panic(s.errorAt(s.end, "']'"))
+		}
+		rangeText = s.spec[typeEnd+1 : closeRange]
+		dashPos = closeRange + 1
+	} else if typeEnd < s.end && s.spec[typeEnd] == '/' {
+		closePattern := s.index("/-", typeEnd+1)
+		if closePattern < 0 {
+			panic(s.errorAt(s.end, "'/'"))
+		}
+		patternText = s.spec[typeEnd+1 : closePattern]
+		dashPos = closePattern + 1
+	}
 
-			// check for a dup
-			for _, arg := range as.ValueSpecs {
-				if avs.OptionName == arg.OptionName {
-					panic(fmt.Errorf("duplicate value spec \"%s\" in \"%s\"", arg.OptionName, orgSpec))
-				}
-			}
+	if dashPos >= s.end || s.spec[dashPos] != '-' {
+		panic(s.errorAt(dashPos, "'-'"))
+	}
+
+	optionType := parseSpecNormalizers(&avs, s.spec[typeStart:typeEnd], s, typeStart)
+	ot := s.typeAttributes(cl.optionTypes, optionType, typeStart)
 
-			as.ValueSpecs = append(as.ValueSpecs, &avs)
-		} // for parsePos
+	if typeEnd != dashPos {
+		if s.spec[typeEnd] == '[' {
+			cl.parseValueRange(&avs, ot.Index, rangeText, s, typeEnd)
+		} else {
+			parseValuePattern(&avs, ot.DefaultValue, patternText, s, typeEnd)
+		}
 	}
 
-	if len(as.Key) == 0 {
-		panic(parseError("argument name", orgSpec, spec, 0))
+	s.pos = dashPos + 1
+
+	closeBracket := s.index(">", s.pos)
+	if closeBracket < 0 {
+		panic(s.errorAt(s.end, "'>'"))
 	}
 
-	if as.Key == "~" {
-		as.Unnamed = true
+	namePos := s.pos
+	avs.OptionName = s.spec[namePos:closeBracket]
+	hasDefault := false
+	if equals := strings.IndexByte(avs.OptionName, '='); equals >= 0 {
+		avs.DefaultText = avs.OptionName[equals+1:]
+		avs.OptionName = avs.OptionName[:equals]
+		hasDefault = true
+	}
+	if !simpleutils.IsTokenName(avs.OptionName) {
+		panic(s.errorAt(namePos, "valid option name"))
 	}
+	defaultPos := namePos + len(avs.OptionName) + 1
 
-	// remove leading dash or dash-dash
-	trimmedKey := strings.TrimPrefix(as.Key, "-")
-	trimmedKey = strings.TrimPrefix(trimmedKey, "-")
+	s.pos = closeBracket + 1
 
-	if !simpleutils.IsTokenNameWithMiddleChars(trimmedKey, "- ") && !as.Unnamed {
-		panic(parseError("a valid argument token", orgSpec, spec, 0))
+	if s.hasPrefix("...") {
+		avs.Multi = true
+		avs.Ellipsis = true
+		s.pos += 3
 	}
 
-	if primaryArg {
-		if as.Optional {
-			panic(parseError("non-optional primary argument", orgSpec, spec, 0))
-		}
-		if as.MultiValue {
-			panic(parseError("single-value primary argument", orgSpec, spec, 0))
+	if avs.Optional && !s.accept(']') {
+		panic(s.errorAt(s.pos, "']'"))
+	}
+
+	avs.ArgIndex = ot.Index
+	avs.TypeName = optionType
+	avs.DefaultValue = ot.DefaultValue
+
+	if hasDefault {
+		if avs.Multi || avs.TypeName == countTypeName {
+			panic(s.errorAt(defaultPos, "default for a single value"))
 		}
-		if as.Unnamed && as.ValuesDelim == ':' {
-			panic(parseError("unnamed argument without a value spec", orgSpec, spec, 0))
+		if !avs.interpolated() {
+			value, err := cl.optionTypes.MakeValue(avs.ArgIndex, avs.DefaultText)
+			if err != nil {
+				panic(s.errorAt(defaultPos, "valid default value"))
+			}
+			avs.DefaultValue = value
 		}
-	} else {
-		if as.Unnamed {
-			panic(parseError("named seconary argument", orgSpec, spec, 0))
+	}
+
+	// check for a dup
+	for _, arg := range as.ValueSpecs {
+		if avs.OptionName == arg.OptionName {
+			panic(fmt.Errorf("duplicate value spec \"%s\" in \"%s\"", arg.OptionName, s.spec))
 		}
 	}
 
-	return &as
+	as.ValueSpecs = append(as.ValueSpecs, &avs)
+}
+
+func isNegativeNumber(arg string) bool {
+	if len(arg) < 2 || arg[0] != '-' {
+		return false
+	}
+
+	c := arg[1]
+	if c != '.' && (c < '0' || c > '9') {
+		return false
+	}
+
+	_, err := strconv.ParseFloat(arg, 64)
+	return err == nil
+}
+
+// determines if a subsequent arg is a value, rather than the next option switch
+func (avs *argValueSpec) isValueArg(arg string) bool {
+	if !strings.HasPrefix(arg, "-") {
+		return true
+	}
+
+	return numericTypeNames[avs.TypeName] && isNegativeNumber(arg)
+}
+
+// provides the aliases, including the --no-name of a negatable flag
+func (as *argSpec) allAliases() []string {
+	if as.Negation == "" {
+		return as.Aliases
+	}
+	return append(append([]string{}, as.Aliases...), as.Negation)
+}
+
+// records whether a negatable flag was given as --no-name, which turns the flag off
+func (as *argSpec) applyNegation(values map[string]any, name string) {
+	if as.Negation == "" {
+		return
+	}
+
+	negated := name == as.Negation
+	values[as.Key] = !negated
+	values[as.Negation] = negated
+}
+
+// determines if the option is a flag that counts its repetitions
+func (as *argSpec) isCount() bool {
+	return len(as.ValueSpecs) == 1 && as.ValueSpecs[0].TypeName == countTypeName
+}
+
+// fills in the spec of a value error raised without knowledge of the spec
+func (as *argSpec) withSpec(err error) error {
+	var cle *CommandLineError
+	if errors.As(err, &cle) && cle.kind != nil && cle.spec == "" {
+		cle.spec = as.String()
+	}
+	return err
+}
+
+// makes a failure to convert an input an invalid value error, unless it already is
+// a command line error; the conversion error is kept for errors.As
+func (as *argSpec) conversionError(spec *argValueSpec, input string, err error) error {
+	var cle *CommandLineError
+	if errors.As(err, &cle) {
+		return as.withSpec(err)
+	}
+
+	kindErr := newKindError(ErrInvalidValue, input, as.String(), "Invalid %s value %s: %s", spec.TypeName, input, err.Error())
+	kindErr.cause = err
+	return kindErr
 }
 
 func (as *argSpec) storeArg(effectiveArgs *map[string]any, spec *argValueSpec, input string) error {
-	if as.MultiValue || spec.Multi {
+	clearValueSource(*effectiveArgs, spec.OptionName)
+	return maskSecretError(spec, input, as.storeValue(effectiveArgs, spec, input))
+}
+
+func (as *argSpec) storeValue(effectiveArgs *map[string]any, spec *argValueSpec, input string) error {
+	input = as.CmdLine.normalizeValue(spec, input)
+
+	if spec.Streamer != nil {
+		value, err := as.CmdLine.optionTypes.MakeValue(spec.ArgIndex, input)
+		if err != nil {
+			return as.conversionError(spec, input, err)
+		}
+		if err = as.CmdLine.validateValue(spec, value); err != nil {
+			return as.withSpec(err)
+		}
+		return spec.Streamer(value)
+	} else if as.MultiValue || spec.Multi {
 		//
 		// The very first arg will exist in effectiveArgs map with nil; convert it to a list.
 		// Subsequent args of the same option will be added to the list.
@@ -275,13 +516,19 @@ func (as *argSpec) storeArg(effectiveArgs *map[string]any, spec *argValueSpec, i
 
 		list, err = as.CmdLine.optionTypes.AppendList(spec.ArgIndex, list, input)
 		if err != nil {
-			return err
+			return as.conversionError(spec, input, err)
+		}
+		if err = as.CmdLine.validateLastElement(spec, list); err != nil {
+			return as.withSpec(err)
 		}
 		(*effectiveArgs)[spec.OptionName] = list
 	} else {
 		value, err := as.CmdLine.optionTypes.MakeValue(spec.ArgIndex, input)
 		if err != nil {
-			return err
+			return as.conversionError(spec, input, err)
+		}
+		if err = as.CmdLine.validateValue(spec, value); err != nil {
+			return as.withSpec(err)
 		}
 		(*effectiveArgs)[spec.OptionName] = value
 	}
@@ -291,37 +538,66 @@ func (as *argSpec) storeArg(effectiveArgs *map[string]any, spec *argValueSpec, i
 
 func (as *argSpec) Parse(effectiveArgs *map[string]any, colonValue *string, subsequentArgs []string) (int, error) {
 
+	if as.isCount() {
+		if colonValue != nil {
+			return 0, newKindError(ErrUnexpectedValue, *colonValue, as.String(), "Unexpected command argument: %s", *colonValue)
+		}
+
+		count, _ := (*effectiveArgs)[as.ValueSpecs[0].OptionName].(int)
+		(*effectiveArgs)[as.ValueSpecs[0].OptionName] = count + 1
+		(*effectiveArgs)[as.Key] = true
+		return 0, nil
+	}
+
 	argsUsed := 0
 	input := colonValue
 
 	if input == nil && as.ValuesDelim == ' ' {
-		if len(subsequentArgs) > 0 && !strings.HasPrefix(subsequentArgs[0], "-") {
+		if len(subsequentArgs) > 0 && len(as.ValueSpecs) > 0 && as.ValueSpecs[0].isValueArg(subsequentArgs[0]) {
 			input = &subsequentArgs[0]
 			argsUsed = 1
 		}
 	}
 
+	prompted := false
+	if input == nil && len(as.ValueSpecs) > 0 && !as.ValueSpecs[0].Optional {
+		var err error
+		input, err = as.promptSecret(as.ValueSpecs[0])
+		if err != nil {
+			return 0, err
+		}
+		prompted = input != nil
+	}
+
 	if input == nil {
 		if len(as.ValueSpecs) > 0 && !as.ValueSpecs[0].Optional {
-			return 0, NewCommandLineError("Required value %s is missing", as.ValueSpecs[0].OptionName)
+			return 0, newKindError(ErrMissingValue, as.ValueSpecs[0].OptionName, as.String(), "Required value %s is missing", as.ValueSpecs[0].OptionName)
 		}
 
 		if len(as.ValueSpecs) > 0 {
 			for _, valueSpec := range as.ValueSpecs {
-				(*effectiveArgs)[valueSpec.OptionName] = valueSpec.DefaultValue
+				value, source, err := as.defaultValue(valueSpec, *effectiveArgs)
+				if err != nil {
+					return 0, err
+				}
+				(*effectiveArgs)[valueSpec.OptionName] = value
+				setValueSource(*effectiveArgs, valueSpec.OptionName, source)
 			}
 		}
 	} else if len(as.ValueSpecs) == 0 {
-		return 0, NewCommandLineError("Unexpected command argument: %s", *input)
+		return 0, newKindError(ErrUnexpectedValue, *input, as.String(), "Unexpected command argument: %s", *input)
 	} else if len(as.ValueSpecs) == 1 {
 		err := as.storeArg(effectiveArgs, as.ValueSpecs[0], *input)
 		if err != nil {
 			return 0, err
 		}
+		if prompted {
+			setValueSource(*effectiveArgs, as.ValueSpecs[0].OptionName, SourcePrompt)
+		}
 
 		if as.ValueSpecs[0].Multi && as.ValuesDelim == ' ' {
 			for {
-				if argsUsed >= len(subsequentArgs) || strings.HasPrefix(subsequentArgs[argsUsed], "-") {
+				if argsUsed >= len(subsequentArgs) || !as.ValueSpecs[0].isValueArg(subsequentArgs[argsUsed]) {
 					break
 				}
 
@@ -345,14 +621,14 @@ func (as *argSpec) Parse(effectiveArgs *map[string]any, colonValue *string, subs
 				if argsUsed >= len(subsequentArgs) {
 					break
 				}
-				if strings.HasPrefix(subsequentArgs[argsUsed], "-") {
+				if !as.ValueSpecs[i].isValueArg(subsequentArgs[argsUsed]) {
 					break
 				}
 				values = append(values, subsequentArgs[argsUsed])
 				argsUsed++
 
 				if as.ValueSpecs[i].Multi {
-					for argsUsed < len(subsequentArgs) && !strings.HasPrefix(subsequentArgs[argsUsed], "-") {
+					for argsUsed < len(subsequentArgs) && as.ValueSpecs[i].isValueArg(subsequentArgs[argsUsed]) {
 						values = append(values, subsequentArgs[argsUsed])
 						argsUsed++
 					}
@@ -373,7 +649,7 @@ func (as *argSpec) Parse(effectiveArgs *map[string]any, colonValue *string, subs
 				} else if valueSpec.Optional {
 					break
 				} else {
-					return 0, NewCommandLineError("Required value %s is missing", valueSpec.OptionName)
+					return 0, newKindError(ErrMissingValue, valueSpec.OptionName, as.String(), "Required value %s is missing", valueSpec.OptionName)
 				}
 			} else {
 				err := as.storeArg(effectiveArgs, as.ValueSpecs[i], values[i])
@@ -382,13 +658,18 @@ func (as *argSpec) Parse(effectiveArgs *map[string]any, colonValue *string, subs
 				}
 
 				if valueSpec.Multi && as.ValuesDelim == ' ' {
+					// an ellipsis leaves the remaining values for the value specs that follow
+					trailing := 0
+					if valueSpec.Ellipsis {
+						trailing = len(as.ValueSpecs) - i - 1
+					}
 					for {
-						if i+1 >= len(values) {
+						if i+1+trailing >= len(values) {
 							break
 						}
 
 						value := values[i+1]
-						values = append(values[0:i], values[i+2:]...)
+						values = append(values[:i+1], values[i+2:]...)
 
 						err := as.storeArg(effectiveArgs, as.ValueSpecs[i], value)
 						if err != nil {
@@ -409,18 +690,36 @@ func (as *argSpec) String() string {
 	var sb strings.Builder
 	if as.MultiValue {
 		sb.WriteString("*")
+		sb.WriteString(as.repeatCountText())
 	}
 	if as.Optional {
 		sb.WriteString("[")
 	}
 
 	if !as.Unnamed {
-		sb.WriteString(as.Key)
+		if as.Negation != "" {
+			sb.WriteString("--[no-]" + strings.TrimPrefix(as.Key, "--"))
+		} else {
+			sb.WriteString(as.Key)
+		}
+		for _, alias := range as.Aliases {
+			sb.WriteString("|")
+			sb.WriteString(alias)
+		}
 	}
 
 	first := true
 	optionalValues := 0
 
+	if as.isCount() {
+		// a count flag takes no value, and can be repeated
+		if as.Optional {
+			sb.WriteString("]")
+		}
+		sb.WriteString("...")
+		return sb.String()
+	}
+
 	for _, valueSpec := range as.ValueSpecs {
 		chars := make([]rune, 0, 2)
 		if valueSpec.Optional {
@@ -444,7 +743,13 @@ func (as *argSpec) String() string {
 
 		sb.WriteString("<")
 		sb.WriteString(valueSpec.OptionName)
+		if valueSpec.RangeText != "" {
+			sb.WriteString("[" + valueSpec.RangeText + "]")
+		}
 		sb.WriteString(">")
+		if valueSpec.Ellipsis {
+			sb.WriteString("...")
+		}
 	}
 
 	for optionalValues > 0 {
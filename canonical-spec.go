@@ -0,0 +1,137 @@
+package cmdline
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parses a command or option spec with the default option types and provides its
+// normalized form, such as "[--only[:<string-name>]]" for "[--only:[<string-name>]]";
+// the normalized form registers the same as the spec, and normalizes to itself. A
+// spec is taken as an option when it starts with '-' or '[', after any '*' repeat
+// count.
+func CanonicalizeSpec(spec string) (canonical string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			recovered, isError := r.(error)
+			if !isError {
+				recovered = fmt.Errorf("%v", r)
+			}
+			err = recovered
+		}
+	}()
+
+	as := NewCommandLine().parseArgSpec(spec, !isOptionSpec(spec))
+	return as.canonicalSpec(), nil
+}
+
+// determines if a spec is an option spec rather than a command spec
+func isOptionSpec(spec string) bool {
+	if strings.HasPrefix(spec, "*") {
+		spec = spec[1:]
+		if strings.HasPrefix(spec, "{") {
+			if closeBrace := strings.Index(spec, "}"); closeBrace >= 0 {
+				spec = spec[closeBrace+1:]
+			}
+		}
+	}
+	return strings.HasPrefix(spec, "-") || strings.HasPrefix(spec, "[")
+}
+
+// renders the spec in its normalized form, which can be registered; String still
+// renders the form shown in help, which leaves out the value types
+func (as *argSpec) canonicalSpec() string {
+	var sb strings.Builder
+	if as.MultiValue {
+		sb.WriteString("*")
+		sb.WriteString(as.repeatCountText())
+	}
+	if as.Optional {
+		sb.WriteString("[")
+	}
+
+	switch {
+	case as.Unnamed:
+		sb.WriteString("~")
+	case as.Negation != "":
+		sb.WriteString("--[no-]" + canonicalKey(strings.TrimPrefix(as.Key, "--")))
+	default:
+		sb.WriteString(canonicalKey(as.Key))
+	}
+	for _, alias := range as.Aliases {
+		sb.WriteString("|")
+		sb.WriteString(canonicalKey(alias))
+	}
+
+	for i, valueSpec := range as.ValueSpecs {
+		delim := as.ValueDelim
+		if i == 0 {
+			delim = as.ValuesDelim
+		}
+
+		// an optional value is bracketed with its delimiter; a space goes outside
+		// for readability, and values written without a delimiter stay that way
+		switch {
+		case delim == ' ' && valueSpec.Optional:
+			sb.WriteString(" [")
+		case valueSpec.Optional:
+			sb.WriteString("[")
+			fallthrough
+		case delim != 0:
+			sb.WriteRune(delim)
+		}
+
+		sb.WriteString(valueSpec.canonicalSpec())
+
+		if valueSpec.Optional {
+			sb.WriteString("]")
+		}
+	}
+
+	if as.Optional {
+		sb.WriteString("]")
+	}
+
+	if as.HelpText != "" {
+		sb.WriteString("?")
+		sb.WriteString(as.HelpText)
+	}
+
+	return sb.String()
+}
+
+// a key is written with '+' for a space, as specKey reads it
+func canonicalKey(key string) string {
+	return strings.ReplaceAll(key, " ", "+")
+}
+
+func (avs *argValueSpec) canonicalSpec() string {
+	var sb strings.Builder
+	if avs.Multi && !avs.Ellipsis {
+		sb.WriteString("*")
+	}
+
+	sb.WriteString("<")
+	sb.WriteString(avs.TypeName)
+	for _, name := range avs.NormalizerNames {
+		sb.WriteString(":")
+		sb.WriteString(name)
+	}
+	if avs.RangeText != "" {
+		sb.WriteString("[" + avs.RangeText + "]")
+	} else if avs.Pattern != nil {
+		sb.WriteString("/" + avs.Pattern.String() + "/")
+	}
+
+	sb.WriteString("-")
+	sb.WriteString(avs.OptionName)
+	if avs.DefaultText != "" {
+		sb.WriteString("=")
+		sb.WriteString(avs.DefaultText)
+	}
+	sb.WriteString(">")
+	if avs.Ellipsis {
+		sb.WriteString("...")
+	}
+	return sb.String()
+}
@@ -0,0 +1,163 @@
+package cmdline
+
+// provides a copy of the command line whose commands, options and settings can be
+// changed without affecting the original, such as to add experimental commands to
+// a shared base; handlers, printers, observers and other provided interfaces are
+// shared, and the most recent invocation and result aren't copied
+func (cl *CommandLine) Clone() *CommandLine {
+	c := &CommandLine{
+		optionTypes:           cloneOptionTypes(cl.optionTypes),
+		output:                cl.output,
+		versionInfo:           cl.versionInfo,
+		versionTemplate:       cl.versionTemplate,
+		autoHelpEnabled:       cl.autoHelpEnabled,
+		appName:               cl.appName,
+		usage:                 cl.usage,
+		commandGroups:         cloneSlice(cl.commandGroups),
+		helpOrder:             cl.helpOrder,
+		helpWidth:             cl.helpWidth,
+		errorFormat:           cl.errorFormat,
+		globalNames:           cloneMap(cl.globalNames),
+		commandNames:          cloneMap(cl.commandNames),
+		lazyCommands:          cloneMap(cl.lazyCommands),
+		lazyOrder:             cloneSlice(cl.lazyOrder),
+		shortFlagClustering:   cl.shortFlagClustering,
+		slashOptions:          cl.slashOptions,
+		abbreviations:         cl.abbreviations,
+		completionCommand:     cl.completionCommand,
+		normalizers:           cloneMap(cl.normalizers),
+		patterns:              cloneMap(cl.patterns),
+		completers:            cloneMap(cl.completers),
+		namedHandlers:         cloneMap(cl.namedHandlers),
+		middleware:            cloneSlice(cl.middleware),
+		wrapGlobals:           cl.wrapGlobals,
+		recoverPanics:         cl.recoverPanics,
+		verbosityEnabled:      cl.verbosityEnabled,
+		secretPrompts:         cl.secretPrompts,
+		dryRun:                cl.dryRun,
+		dryRunEnabled:         cl.dryRunEnabled,
+		dryRunFormat:          cl.dryRunFormat,
+		synopsisEnabled:       cl.synopsisEnabled,
+		unknownArgPolicy:      cl.unknownArgPolicy,
+		quiet:                 cl.quiet,
+		commandNameChars:      cl.commandNameChars,
+		optionParsing:         cl.optionParsing,
+		duplicateOptionPolicy: cl.duplicateOptionPolicy,
+		observer:              cl.observer,
+		logger:                cl.logger,
+		globalValues:          cl.globalValues,
+		valueSources:          cl.valueSources,
+		spinnerFrames:         cloneSlice(cl.spinnerFrames),
+		dataStream:            cl.dataStream,
+		diagnosticStream:      cl.diagnosticStream,
+		colorMode:             cl.colorMode,
+		outputFormats:         cloneSlice(cl.outputFormats),
+		outputFormatters:      cloneMap(cl.outputFormatters),
+		resultRenderer:        cl.resultRenderer,
+		commandSeparator:      cl.commandSeparator,
+		aliases:               cloneMap(cl.aliases),
+		confirmProvider:       cl.confirmProvider,
+		prompter:              cl.prompter,
+		translator:            cl.translator,
+	}
+
+	// validators are appended to per value name, so each list is copied
+	if cl.validators != nil {
+		c.validators = make(map[string][]ValueValidator, len(cl.validators))
+		for name, validators := range cl.validators {
+			c.validators[name] = cloneSlice(validators)
+		}
+	}
+
+	if format, isString := cl.outputFormat.Load().(string); isString {
+		c.outputFormat.Store(format)
+	}
+	c.verbosity.Store(cl.verbosity.Load())
+
+	c.commands = newOrderedCommandLineMap()
+	for _, name := range cl.commands.order {
+		cmd := cl.commands.values[name].clone(c)
+		c.commands.add(name, cmd)
+		if cl.commands.values[name] == cl.unnamedCmd {
+			c.unnamedCmd = cmd
+		}
+	}
+
+	c.globalOptions = newOrderedGlobalOptionMap()
+	for _, name := range cl.globalOptions.order {
+		globalOpt := *cl.globalOptions.values[name]
+		globalOpt.argSpec = globalOpt.argSpec.cloneFor(c)
+		c.globalOptions.add(name, &globalOpt)
+	}
+
+	return c
+}
+
+// copies a command, with its specs bound to the command line cl
+func (cmd *command) clone(cl *CommandLine) *command {
+	copied := *cmd
+	copied.PrimaryArgSpec = cmd.PrimaryArgSpec.cloneFor(cl)
+	copied.OptionSpecs = newOrderedArgSpecMap()
+	for _, name := range cmd.OptionSpecs.order {
+		copied.OptionSpecs.add(name, cmd.OptionSpecs.values[name].cloneFor(cl))
+	}
+	copied.Constraints = cloneSlice(cmd.Constraints)
+	copied.Examples = cloneSlice(cmd.Examples)
+	copied.OptionGroups = cloneSlice(cmd.OptionGroups)
+
+	// the streamed value is one of the command's value specs
+	if cmd.Streamed != nil {
+		copied.Streamed = nil
+		specs := []*argSpec{copied.PrimaryArgSpec}
+		for _, name := range copied.OptionSpecs.order {
+			specs = append(specs, copied.OptionSpecs.values[name])
+		}
+		for _, as := range specs {
+			for _, valueSpec := range as.ValueSpecs {
+				if valueSpec.Streamer != nil && valueSpec.OptionName == cmd.Streamed.OptionName {
+					copied.Streamed = valueSpec
+				}
+			}
+		}
+	}
+	return &copied
+}
+
+// copies a spec for the command line cl
+func (as *argSpec) cloneFor(cl *CommandLine) *argSpec {
+	copied := as.clone()
+	copied.CmdLine = cl
+	return copied
+}
+
+// copies registered option types, which RegisterOptionType extends, and the default
+// option types, which SetMaxFileSize changes; other option types are shared
+func cloneOptionTypes(optionTypes OptionTypes) OptionTypes {
+	switch ot := optionTypes.(type) {
+	case *registeredOptionTypes:
+		return &registeredOptionTypes{base: cloneOptionTypes(ot.base), types: cloneSlice(ot.types), names: cloneMap(ot.names)}
+	case *DefaultOptionTypes:
+		copied := *ot
+		return &copied
+	default:
+		return optionTypes
+	}
+}
+
+func cloneSlice[T any](s []T) []T {
+	if s == nil {
+		return nil
+	}
+	return append(make([]T, 0, len(s)), s...)
+}
+
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	if m == nil {
+		return nil
+	}
+	copied := make(map[K]V, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
+}
@@ -1,18 +1,121 @@
 package cmdline
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// the kinds of CommandLineError, for use with errors.Is
+var (
+	ErrMissingCommand        = errors.New("missing command")
+	ErrUnknownCommand        = errors.New("unknown command")
+	ErrUnknownOption         = errors.New("unknown option")
+	ErrMissingRequiredOption = errors.New("missing required option")
+	ErrMissingValue          = errors.New("missing value")
+	ErrUnexpectedValue       = errors.New("unexpected value")
+	ErrInvalidValue          = errors.New("invalid value")
+	ErrConstraintViolation   = errors.New("constraint violation")
+	ErrMalformedLine         = errors.New("malformed command line")
+	ErrNotConfirmed          = errors.New("not confirmed")
+	ErrAmbiguousOption       = errors.New("ambiguous option")
+	ErrRepeatCount           = errors.New("repeat count")
+	ErrDuplicateOption       = errors.New("duplicate option")
+)
 
 type CommandLineError struct {
-	reason string
+	reason       string
+	suggestion   string
+	alternatives []string
+	kind         error
+	token        string
+	spec         string
+	format       string
+	args         []any
+	cause        error
 }
 
 func (e *CommandLineError) Error() string {
 	return e.reason
 }
 
+// provides the registered name closest to the unrecognized input, if any
+func (e *CommandLineError) Suggestion() string {
+	return e.suggestion
+}
+
+// provides the valid names, when the input is not close to any of them
+func (e *CommandLineError) Alternatives() []string {
+	return e.alternatives
+}
+
+// provides the error kind, such as ErrUnknownCommand, or nil for an error made by NewCommandLineError
+func (e *CommandLineError) Kind() error {
+	return e.kind
+}
+
+// provides the offending command line token, if any
+func (e *CommandLineError) Token() string {
+	return e.token
+}
+
+// provides the spec of the command or option involved, if any
+func (e *CommandLineError) Spec() string {
+	return e.spec
+}
+
+// provides the error that caused an invalid value, such as a *strconv.NumError, if any
+func (e *CommandLineError) Unwrap() error {
+	return e.cause
+}
+
+// matches the error kind, so errors.Is(err, ErrUnknownCommand) works
+func (e *CommandLineError) Is(target error) bool {
+	return e.kind != nil && e.kind == target
+}
+
 func NewCommandLineError(format string, args ...any) error {
 	err := new(CommandLineError)
 	err.reason = fmt.Sprintf(format, args...)
+	err.format = format
+	err.args = args
 
 	return err
 }
+
+func newKindError(kind error, token string, spec string, format string, args ...any) *CommandLineError {
+	err := new(CommandLineError)
+	err.reason = fmt.Sprintf(format, args...)
+	err.format = format
+	err.args = args
+	err.kind = kind
+	err.token = token
+	err.spec = spec
+
+	return err
+}
+
+// the message appended to an error that has a suggestion
+const suggestionFormat = "; did you mean '%s'?"
+
+// the message appended to an error that lists the valid names instead
+const alternativesFormat = "; valid options: %s"
+
+func newSuggestionError(suggestion string, token string, format string, args ...any) error {
+	err := newKindError(ErrUnknownCommand, token, "", format, args...)
+	err.suggestion = suggestion
+	err.reason += err.hintText(fmt.Sprintf)
+
+	return err
+}
+
+// the suggestion or the valid alternatives, in the form appended to the message
+func (e *CommandLineError) hintText(sprintf func(format string, args ...any) string) string {
+	if e.suggestion != "" {
+		return sprintf(suggestionFormat, e.suggestion)
+	}
+	if len(e.alternatives) > 0 {
+		return sprintf(alternativesFormat, strings.Join(e.alternatives, ", "))
+	}
+	return ""
+}
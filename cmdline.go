@@ -1,12 +1,18 @@
 package cmdline
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"regexp"
 	"sort"
 	"strings"
-	"unicode/utf8"
+	"sync"
+	"sync/atomic"
+	"text/template"
 
 	"github.com/jimsnab/go-simpleutils"
+	"github.com/jimsnab/go-toolprinter"
 )
 
 const maxLineWidth = 120
@@ -20,12 +26,79 @@ type helpLine struct {
 	cols   int
 }
 
+// queues the lines of one help request, so concurrent requests don't mix
+type helpPrinter struct {
+	*CommandLine
+	printQueue []helpLine
+	detailed   bool
+}
+
 type CommandLine struct {
-	commands      *orderedCommandLineMap
-	unnamedCmd    *command
-	globalOptions *orderedGlobalOptionMap
-	optionTypes   OptionTypes
-	printQueue    []helpLine
+	commands        *orderedCommandLineMap
+	unnamedCmd      *command
+	globalOptions   *orderedGlobalOptionMap
+	optionTypes     OptionTypes
+	output          toolprinter.ToolPrinter
+	versionInfo     *VersionInfo
+	versionTemplate *template.Template
+	autoHelpEnabled bool
+	appName         string
+	usage           string
+	commandGroups   []string
+	helpOrder       HelpOrder
+	helpWidth       int
+	errorFormat     ErrorFormat
+	renderMu        sync.Mutex
+	lastMu          sync.Mutex
+	lastInvocation  *Invocation
+	lastResult      any
+
+	specCache             map[specCacheKey]*argSpec
+	globalNames           map[string]bool
+	commandNames          map[string]bool
+	lazyCommands          map[string]LazyCommandLoader
+	lazyOrder             []string
+	shortFlagClustering   bool
+	slashOptions          bool
+	abbreviations         bool
+	completionCommand     bool
+	validators            map[string][]ValueValidator
+	normalizers           map[string][]ValueNormalizer
+	patterns              map[string]*regexp.Regexp
+	completers            map[string]ValueCompleter
+	namedHandlers         map[string]CommandHandlerCtx
+	middleware            []MiddlewareCtx
+	wrapGlobals           bool
+	recoverPanics         bool
+	verbosityEnabled      bool
+	secretPrompts         bool
+	dryRun                bool
+	dryRunEnabled         bool
+	dryRunFormat          DryRunFormat
+	synopsisEnabled       bool
+	unknownArgPolicy      UnknownArgPolicy
+	quiet                 bool
+	commandNameChars      string
+	optionParsing         OptionParsing
+	duplicateOptionPolicy DuplicateOptionPolicy
+	observer              Observer
+	logger                Logger
+	globalValues          bool
+	valueSources          bool
+	spinnerFrames         []string
+	dataStream            io.Writer
+	diagnosticStream      io.Writer
+	colorMode             ColorMode
+	outputFormats         []string
+	outputFormatters      map[string]OutputFormatter
+	resultRenderer        ResultRenderer
+	commandSeparator      string
+	aliases               map[string][]string
+	outputFormat          atomic.Value
+	confirmProvider       ConfirmProvider
+	prompter              Prompter
+	translator            Translator
+	verbosity             atomic.Int32
 }
 
 func NewCommandLine() *CommandLine {
@@ -41,6 +114,7 @@ func newCommandLine(optionTypes *OptionTypes) *CommandLine {
 
 	cl.commands = newOrderedCommandLineMap()
 	cl.globalOptions = newOrderedGlobalOptionMap()
+	cl.commandNameChars = DefaultCommandNameChars
 
 	if optionTypes == nil {
 		cl.optionTypes, _ = NewDefaultOptionTypes()
@@ -51,6 +125,44 @@ func newCommandLine(optionTypes *OptionTypes) *CommandLine {
 	return &cl
 }
 
+// limits the size of the file read for a file type value; requires the default option types
+func (cl *CommandLine) SetMaxFileSize(maxBytes int64) {
+	optionTypes := cl.optionTypes
+	if rot, ok := optionTypes.(*registeredOptionTypes); ok {
+		optionTypes = rot.base
+	}
+
+	dot, ok := optionTypes.(*DefaultOptionTypes)
+	if !ok {
+		panic(fmt.Errorf("argument error: the max file size applies only to the default option types"))
+	}
+	dot.MaxFileSize = maxBytes
+}
+
+// directs help output to w instead of the package printer Prn; nil restores Prn
+func (cl *CommandLine) SetOutput(w io.Writer) {
+	if w == nil {
+		cl.output = nil
+	} else {
+		cl.output = NewWriterPrinter(w)
+	}
+}
+
+func (cl *CommandLine) newHelpPrinter() *helpPrinter {
+	return &helpPrinter{CommandLine: cl}
+}
+
+func (cl *CommandLine) printer() toolprinter.ToolPrinter {
+	prn := Prn
+	if cl.output != nil {
+		prn = cl.output
+	}
+	if cl.quiet {
+		return quietPrinter{prn}
+	}
+	return prn
+}
+
 func (cl *CommandLine) cmdToSummary(cmd *command) (summary map[string]any) {
 	summary = map[string]any{}
 
@@ -70,6 +182,7 @@ func (cl *CommandLine) cmdToSummary(cmd *command) (summary map[string]any) {
 
 // provides a map of the registered commands and options
 func (cl *CommandLine) Summary() (summary map[string]any) {
+	cl.LoadLazyCommands()
 	summary = map[string]any{}
 
 	if len(cl.globalOptions.values) > 0 {
@@ -106,47 +219,99 @@ func (cl *CommandLine) checkForDuplicateName(names map[string]bool, spec string)
 	names[spec] = true
 }
 
-func (cl *CommandLine) checkForDuplicateNames(newCmd *command) {
-	names := make(map[string]bool)
+// checks the names of a new command against the global options and each other,
+// then indexes them, so each registration only checks its own names
+func (cl *CommandLine) checkCommandNames(cmd *command) {
+	key := cmd.PrimaryArgSpec.Key
+	if _, exists := cl.commands.values[key]; exists || cl.globalNames[key] || cl.lazyCommands[key] != nil {
+		panic(fmt.Errorf("%sunique argument \"%s\"", basePanic, key))
+	}
 
-	for _, globalOpt := range cl.globalOptions.values {
-		cl.checkForDuplicateName(names, globalOpt.argSpec.Key)
+	cmdNames := map[string]bool{key: true}
+	for _, name := range commandNameList(cmd) {
+		if cl.globalNames[name] {
+			panic(fmt.Errorf("%sunique argument \"%s\"", basePanic, name))
+		}
+		cl.checkForDuplicateName(cmdNames, name)
 	}
 
-	allCommands := make([]*command, 0, len(cl.commands.values)+1)
-	for _, cmd := range cl.commands.values {
-		allCommands = append(allCommands, cmd)
+	if cl.commandNames == nil {
+		cl.commandNames = map[string]bool{}
 	}
-	if newCmd != nil {
-		allCommands = append(allCommands, newCmd)
+	for name := range cmdNames {
+		cl.commandNames[name] = true
 	}
+}
 
-	for _, cmd := range allCommands {
-		cl.checkForDuplicateName(names, cmd.PrimaryArgSpec.Key)
-
-		cmdNames := make(map[string]bool)
-		for k, v := range names {
-			cmdNames[k] = v
+// checks the names of a new global option against all the registered names
+func (cl *CommandLine) checkGlobalOptionNames(as *argSpec) {
+	optionNames := map[string]bool{}
+	for _, name := range append([]string{as.Key}, as.allAliases()...) {
+		if _, exists := cl.commands.values[name]; exists || cl.globalNames[name] || cl.commandNames[name] {
+			panic(fmt.Errorf("%sunique argument \"%s\"", basePanic, name))
 		}
+		cl.checkForDuplicateName(optionNames, name)
+	}
 
-		for _, valueSpec := range cmd.PrimaryArgSpec.ValueSpecs {
-			cl.checkForDuplicateName(cmdNames, valueSpec.OptionName)
-		}
+	if cl.globalNames == nil {
+		cl.globalNames = map[string]bool{}
+	}
+	for name := range optionNames {
+		cl.globalNames[name] = true
+	}
+}
 
-		for _, optionSpec := range cmd.OptionSpecs.values {
-			cl.checkForDuplicateName(cmdNames, optionSpec.Key)
+// checks the names of an option added to a registered command
+func (cl *CommandLine) checkAddedOptionNames(cmd *command, as *argSpec) {
+	cmdNames := map[string]bool{cmd.PrimaryArgSpec.Key: true}
+	for _, name := range commandNameList(cmd) {
+		cmdNames[name] = true
+	}
 
-			for _, valueSpec := range optionSpec.ValueSpecs {
-				cl.checkForDuplicateName(cmdNames, valueSpec.OptionName)
-			}
+	for _, name := range argSpecNameList(as) {
+		if cl.globalNames[name] {
+			panic(fmt.Errorf("%sunique argument \"%s\"", basePanic, name))
 		}
+		cl.checkForDuplicateName(cmdNames, name)
+	}
+
+	if cl.commandNames == nil {
+		cl.commandNames = map[string]bool{}
+	}
+	for name := range cmdNames {
+		cl.commandNames[name] = true
+	}
+}
+
+// lists the names used within a command: its values, options, aliases and option values
+func commandNameList(cmd *command) []string {
+	names := []string{}
+	for _, valueSpec := range cmd.PrimaryArgSpec.ValueSpecs {
+		names = append(names, valueSpec.OptionName)
+	}
+	for _, optionName := range cmd.OptionSpecs.order {
+		names = append(names, argSpecNameList(cmd.OptionSpecs.values[optionName])...)
+	}
+	return names
+}
+
+func argSpecNameList(as *argSpec) []string {
+	names := append([]string{as.Key}, as.allAliases()...)
+	for _, valueSpec := range as.ValueSpecs {
+		names = append(names, valueSpec.OptionName)
 	}
+	return names
 }
 
 func (cl *CommandLine) RegisterCommand(handler CommandHandler, specList ...string) {
+	cl.RegisterCommandCtx(ctxHandler(handler), specList...)
+}
+
+// registers a command handler that receives the context given to ProcessContext
+func (cl *CommandLine) RegisterCommandCtx(handler CommandHandlerCtx, specList ...string) {
 	cmd := cl.newCommand(handler, specList...)
 
-	cl.checkForDuplicateNames(cmd)
+	cl.checkCommandNames(cmd)
 
 	cl.commands.add(cmd.PrimaryArgSpec.Key, cmd)
 
@@ -159,11 +324,28 @@ func (cl *CommandLine) RegisterCommand(handler CommandHandler, specList ...strin
 }
 
 func (cl *CommandLine) RegisterGlobalOption(handler CommandHandler, spec string) {
+	cl.RegisterGlobalOptionCtx(ctxHandler(handler), spec)
+}
+
+// registers a global option handler that receives the context given to ProcessContext
+func (cl *CommandLine) RegisterGlobalOptionCtx(handler CommandHandlerCtx, spec string) {
+	cl.RegisterGlobalOptionCtxWithPriority(handler, spec, 0)
+}
+
+// registers a global option whose handler runs ahead of lower priority global options,
+// regardless of where it appears on the command line; the default priority is 0
+func (cl *CommandLine) RegisterGlobalOptionWithPriority(handler CommandHandler, spec string, priority int) {
+	cl.RegisterGlobalOptionCtxWithPriority(ctxHandler(handler), spec, priority)
+}
+
+// registers a prioritized global option handler that receives the context given to ProcessContext
+func (cl *CommandLine) RegisterGlobalOptionCtxWithPriority(handler CommandHandlerCtx, spec string, priority int) {
 	globalOpt := cl.newGlobalOption(handler, spec)
+	globalOpt.Priority = priority
 
-	cl.globalOptions.add(globalOpt.argSpec.Key, globalOpt)
+	cl.checkGlobalOptionNames(globalOpt.argSpec)
 
-	cl.checkForDuplicateNames(nil)
+	cl.globalOptions.add(globalOpt.argSpec.Key, globalOpt)
 }
 
 func (cl *CommandLine) shouldShow(primaryArgSpec *argSpec, optionSpecs *[]*argSpec, filter string) bool {
@@ -200,51 +382,57 @@ func (cl *CommandLine) shouldShow(primaryArgSpec *argSpec, optionSpecs *[]*argSp
 	return false
 }
 
-func (cl *CommandLine) helpPrintln(text string) {
-	cl.printQueue = append(cl.printQueue, helpLine{str1: text, str2: "", cols: 1})
+func (hp *helpPrinter) helpPrintln(text string) {
+	hp.printQueue = append(hp.printQueue, helpLine{str1: text, str2: "", cols: 1})
 }
 
-func (cl *CommandLine) helpPrintf(fmtString string, args ...any) {
-	cl.printQueue = append(cl.printQueue, helpLine{str1: fmt.Sprintf(fmtString, args...), str2: "", cols: 1})
+func (hp *helpPrinter) helpPrintf(fmtString string, args ...any) {
+	hp.printQueue = append(hp.printQueue, helpLine{str1: fmt.Sprintf(fmtString, args...), str2: "", cols: 1})
 }
 
-func (cl *CommandLine) helpPrintCols(indent int, argText string, description string) {
+func (hp *helpPrinter) helpPrintCols(indent int, argText string, description string) {
 	if len(argText) == 0 {
 		if len(description) > 0 {
 			text := strings.Repeat("  ", indent) + description
-			cl.printQueue = append(cl.printQueue, helpLine{str1: text, str2: "", cols: 2})
+			hp.printQueue = append(hp.printQueue, helpLine{str1: text, str2: "", cols: 2})
 		}
 	} else {
-		cl.printQueue = append(cl.printQueue, helpLine{indent: indent, str1: argText, str2: description, cols: 2})
+		hp.printQueue = append(hp.printQueue, helpLine{indent: indent, str1: argText, str2: description, cols: 2})
 	}
 }
 
-func (cl *CommandLine) helpPrintBlanklnFirst() {
-	if len(cl.printQueue) == 0 {
-		cl.helpPrintln("")
+func (hp *helpPrinter) helpPrintBlanklnFirst() {
+	if len(hp.printQueue) == 0 {
+		hp.helpPrintln("")
 	}
 }
 
-func (cl *CommandLine) helpPrintBlankln() {
-	if len(cl.printQueue) > 0 {
-		lastLine := cl.printQueue[len(cl.printQueue)-1]
+func (hp *helpPrinter) helpPrintBlankln() {
+	if len(hp.printQueue) > 0 {
+		lastLine := hp.printQueue[len(hp.printQueue)-1]
 		if len(lastLine.str1) > 0 {
-			cl.helpPrintln("")
+			hp.helpPrintln("")
 		}
 	}
 }
 
-func (cl *CommandLine) helpRender() {
+func (hp *helpPrinter) helpRender() {
+	hp.renderMu.Lock()
+	defer hp.renderMu.Unlock()
+
+	lineWidth := hp.helpLineWidth()
+	riverLimit := helpRiverLimit(lineWidth)
+
 	// determine the position of the second column
 	riverWidth := 0
-	for _, help := range cl.printQueue {
+	for _, help := range hp.printQueue {
 		if help.cols > 1 {
 			argText := strings.Repeat("  ", help.indent) + help.str1
-			width := utf8.RuneCountInString(argText)
+			width := displayWidth(argText)
 			if width > 0 {
 				width += riverSpaces
-				if width > maxRiver {
-					riverWidth = maxRiver
+				if width > riverLimit {
+					riverWidth = riverLimit
 					break
 				} else if width > riverWidth {
 					riverWidth = width
@@ -254,31 +442,40 @@ func (cl *CommandLine) helpRender() {
 	}
 
 	// print the lines
-	for _, help := range cl.printQueue {
+	prn := hp.printer()
+	for _, help := range hp.printQueue {
 		argText := strings.Repeat("  ", help.indent) + help.str1
 		if help.cols == 1 {
-			Prn.Println(argText)
+			prn.Println(argText)
 		} else {
-			cl.indentedPrint(argText, riverWidth, maxLineWidth, help.str2)
+			hp.indentedPrint(prn, argText, riverWidth, lineWidth, help.str2)
 		}
 	}
 
-	cl.printQueue = []helpLine{}
+	hp.printQueue = []helpLine{}
 }
 
-func (cl *CommandLine) indentedPrint(arg string, indent int, wrap int, text string) {
+func (cl *CommandLine) indentedPrint(prn toolprinter.ToolPrinter, arg string, indent int, wrap int, text string) {
+	// each line is printed whole, so concurrent output interleaves only by line
+	var sb strings.Builder
+	endLine := func(text string) {
+		sb.WriteString(text)
+		prn.Println(sb.String())
+		sb.Reset()
+	}
+
 	column := 0
 	if len(arg) > 0 {
-		Prn.BeginPrint(arg)
-		column = utf8.RuneCountInString(arg)
+		sb.WriteString(arg)
+		column = displayWidth(arg)
 
 		if len(text) == 0 {
-			Prn.EndPrint("")
+			endLine("")
 			return
 		}
 
 		if column >= indent {
-			Prn.EndPrint("")
+			endLine("")
 			column = 0
 		}
 	}
@@ -288,10 +485,10 @@ func (cl *CommandLine) indentedPrint(arg string, indent int, wrap int, text stri
 	for _, line := range lines {
 		if len(strings.TrimSpace(line)) == 0 {
 			if column > 0 {
-				Prn.EndPrint("")
+				endLine("")
 				column = 0
 			} else {
-				Prn.Println("")
+				prn.Println("")
 			}
 			continue
 		}
@@ -299,7 +496,7 @@ func (cl *CommandLine) indentedPrint(arg string, indent int, wrap int, text stri
 		fullLine := line
 		for len(fullLine) > 0 {
 			if column == 0 {
-				Prn.BeginPrint("")
+				sb.WriteString("")
 			}
 
 			if column < indent {
@@ -308,15 +505,19 @@ func (cl *CommandLine) indentedPrint(arg string, indent int, wrap int, text stri
 			}
 
 			thisLine := fullLine
-			end := column + utf8.RuneCountInString(thisLine)
+			end := column + displayWidth(thisLine)
 			if end > wrap {
+				// cut at the last space that the text before it fits
 				cutPoint := -1
-				for {
-					nextCutPoint := indexOf(thisLine, " ", cutPoint+1)
-					if nextCutPoint < 0 || nextCutPoint+column > wrap {
-						break
+				width := 0
+				for i, r := range thisLine {
+					if r == ' ' {
+						if column+width > wrap {
+							break
+						}
+						cutPoint = i
 					}
-					cutPoint = nextCutPoint
+					width += runeWidth(r)
 				}
 
 				if cutPoint > 0 {
@@ -324,10 +525,10 @@ func (cl *CommandLine) indentedPrint(arg string, indent int, wrap int, text stri
 				}
 			}
 
-			Prn.ContinuePrint(nextIndent)
+			sb.WriteString(nextIndent)
 			nextIndent = ""
 
-			Prn.EndPrint(strings.TrimSpace(thisLine))
+			endLine(strings.TrimSpace(thisLine))
 			column = 0
 
 			fullLine = strings.TrimSpace(fullLine[len(thisLine):])
@@ -339,32 +540,45 @@ func (cl *CommandLine) PrimaryCommand(args []string) string {
 	filteredArgs := []string{}
 
 	for _, arg := range args {
-		argTokens := strings.Split(arg, ":")
-		argToken := argTokens[0]
-		_, exists := cl.globalOptions.values[argToken]
+		if arg == "--" {
+			break
+		}
+
+		argToken := arg
+		if delimiter := strings.IndexAny(arg, ":="); delimiter >= 0 {
+			argToken = arg[:delimiter]
+		}
+		_, exists := cl.globalOptions.lookup(argToken)
 		if !exists {
 			filteredArgs = append(filteredArgs, arg)
 		}
 	}
 
-	for _, arg := range filteredArgs {
+	for i, arg := range filteredArgs {
 		argTokens := strings.Split(arg, ":")
 		argToken := argTokens[0]
+
+		primary := ""
 		_, exists := cl.commands.values[argToken]
 		if exists {
-			return argToken
+			primary = argToken
 		}
-	}
 
-	// join spaces and try again
-	if len(args) > 1 && !strings.HasPrefix(args[1], "-") {
-		subargs := []string{
-			fmt.Sprintf("%s %s", args[0], args[1]),
+		// join spaces to find the full subcommand path
+		path := arg
+		for _, subArg := range filteredArgs[i+1:] {
+			if strings.HasPrefix(subArg, "-") {
+				break
+			}
+			path = path + " " + subArg
+			_, exists = cl.commands.values[path]
+			if exists {
+				primary = path
+			}
 		}
-		subargs = append(subargs, args[2:]...)
-		recursive := cl.PrimaryCommand(subargs)
-		if recursive != "" {
-			return recursive
+
+		if primary != "" {
+			return primary
 		}
 	}
 
@@ -372,23 +586,25 @@ func (cl *CommandLine) PrimaryCommand(args []string) string {
 }
 
 func (cl *CommandLine) PrintCommand(cmdstr string) error {
-	err := cl.printCommandWorker(cmdstr)
+	cl.loadNamedLazyCommands(strings.Fields(cmdstr))
+	hp := cl.newHelpPrinter()
+	err := hp.printCommandWorker(cmdstr)
 	if err != nil {
 		return err
 	}
 
-	cl.helpRender()
+	hp.helpRender()
 	return nil
 }
 
-func (cl *CommandLine) printCommandWorker(cmdstr string) error {
+func (hp *helpPrinter) printCommandWorker(cmdstr string) error {
 	wantUnnamed := false
 	if len(cmdstr) == 0 || cmdstr == "~" {
 		wantUnnamed = true
 		cmdstr = "~"
 	}
 
-	cmd, exist := cl.commands.values[cmdstr]
+	cmd, exist := hp.enabledCommand(cmdstr)
 	if !exist {
 		if wantUnnamed {
 			return fmt.Errorf("unnamed command not found")
@@ -398,7 +614,7 @@ func (cl *CommandLine) printCommandWorker(cmdstr string) error {
 	}
 
 	// no help text specified by the template
-	if len(cmd.PrimaryArgSpec.HelpText) == 0 && len(cmd.OptionSpecs.values) == 0 {
+	if len(cmd.PrimaryArgSpec.HelpText) == 0 && len(cmd.OptionSpecs.values) == 0 && len(cmd.Examples) == 0 && !hasArgumentHelp(cmd) {
 		if wantUnnamed {
 			return fmt.Errorf("help not available for the unnamed command")
 		} else {
@@ -406,23 +622,26 @@ func (cl *CommandLine) printCommandWorker(cmdstr string) error {
 		}
 	}
 
+	hp.helpPrintSynopsis(cmd)
+
 	optionIndent := 1
 	argSpec := cmd.PrimaryArgSpec.String()
 	if len(argSpec) > 0 {
 		// named arg, might have help
-		cl.helpPrintCols(0, argSpec, cmd.PrimaryArgSpec.HelpText)
+		hp.helpPrintCols(0, argSpec, hp.helpText(cmd.PrimaryArgSpec.HelpText))
+		hp.helpPrintDetails(0, cmd.PrimaryArgSpec)
 	} else if len(cmd.PrimaryArgSpec.HelpText) > 0 {
 		// unnamed arg with help
-		cl.helpPrintln(cmd.PrimaryArgSpec.HelpText)
+		hp.helpPrintln(hp.helpText(cmd.PrimaryArgSpec.HelpText))
 	} else {
 		// unnamed arg without help
 		optionIndent = 0
 	}
 
-	for _, optionName := range cmd.OptionSpecs.order {
-		option := cmd.OptionSpecs.values[optionName]
-		cl.helpPrintCols(optionIndent, option.String(), option.HelpText)
-	}
+	hp.helpPrintArguments(optionIndent, cmd)
+	hp.helpPrintOptions(optionIndent, cmd)
+	hp.helpPrintConstraints(optionIndent, cmd)
+	hp.helpPrintExamples(optionIndent, cmd)
 
 	return nil
 }
@@ -452,16 +671,18 @@ func sortCompare(a string, b string) bool {
 }
 
 func (cl *CommandLine) PrintCommands(filter string, includeGlobal bool) {
-	cl.printCommandsWorker(filter, includeGlobal)
+	cl.LoadLazyCommands()
+	hp := cl.newHelpPrinter()
+	hp.printCommandsWorker(filter, includeGlobal)
 
 	//
 	// Print the queued help lines.
 	//
 
-	cl.helpRender()
+	hp.helpRender()
 }
 
-func (cl *CommandLine) printCommandsWorker(filter string, includeGlobal bool) {
+func (hp *helpPrinter) printCommandsWorker(filter string, includeGlobal bool) {
 
 	//
 	// Include global options if requested.
@@ -470,9 +691,9 @@ func (cl *CommandLine) printCommandsWorker(filter string, includeGlobal bool) {
 	optPartial := false
 	globalOptionsToPrint := []*globalOption{}
 	if includeGlobal {
-		for _, name := range cl.globalOptions.order {
-			v := cl.globalOptions.values[name]
-			if cl.shouldShow(v.argSpec, nil, filter) {
+		for _, name := range hp.globalOptions.order {
+			v := hp.globalOptions.values[name]
+			if hp.shouldShow(v.argSpec, nil, filter) {
 				globalOptionsToPrint = append(globalOptionsToPrint, v)
 			} else {
 				optPartial = true
@@ -490,8 +711,11 @@ func (cl *CommandLine) printCommandsWorker(filter string, includeGlobal bool) {
 	commandsToPrint := []*command{}
 	var singleCmd *command
 
-	for _, name := range cl.commands.order {
-		v := cl.commands.values[name]
+	for _, name := range hp.commands.order {
+		v := hp.commands.values[name]
+		if !v.enabled() {
+			continue
+		}
 		if singleCmd == nil {
 			singleCmd = v
 		} else {
@@ -499,7 +723,7 @@ func (cl *CommandLine) printCommandsWorker(filter string, includeGlobal bool) {
 		}
 
 		osv := optionSpecValues(v.OptionSpecs)
-		if cl.shouldShow(v.PrimaryArgSpec, &osv, filter) {
+		if hp.shouldShow(v.PrimaryArgSpec, &osv, filter) {
 			if !v.PrimaryArgSpec.Unnamed ||
 				len(v.PrimaryArgSpec.HelpText) > 0 ||
 				len(v.PrimaryArgSpec.ValueSpecs) > 0 ||
@@ -523,97 +747,138 @@ func (cl *CommandLine) printCommandsWorker(filter string, includeGlobal bool) {
 
 	if len(globalOptionsToPrint) > 0 {
 		if optPartial {
-			cl.helpPrintln("Matching Global Options:")
+			hp.helpPrintln(hp.tr("Matching Global Options:"))
 		} else {
-			cl.helpPrintln("Global Options:")
+			hp.helpPrintln(hp.tr("Global Options:"))
+		}
+		hp.helpPrintBlankln()
+
+		if hp.helpOrder == Alphabetical {
+			sort.SliceStable(
+				globalOptionsToPrint,
+				func(i, j int) bool {
+					return sortCompare(globalOptionsToPrint[i].String(), globalOptionsToPrint[j].String())
+				},
+			)
 		}
-		cl.helpPrintBlankln()
-
-		sort.SliceStable(
-			globalOptionsToPrint,
-			func(i, j int) bool {
-				return sortCompare(globalOptionsToPrint[i].String(), globalOptionsToPrint[j].String())
-			},
-		)
 
 		for _, option := range globalOptionsToPrint {
-			cl.helpPrintCols(1, option.argSpec.String(), option.argSpec.HelpText)
+			hp.helpPrintCols(1, option.argSpec.String(), hp.helpText(option.argSpec.HelpText))
+			hp.helpPrintDetails(1, option.argSpec)
 		}
 
-		cl.helpPrintBlankln()
+		hp.helpPrintBlankln()
 	}
 
 	if len(commandsToPrint) > 0 {
 		optionIndent := 2
 
 		// which heading
+		var heading string
 		if cmdPartial {
-			cl.helpPrintln("Matching Commands:")
-		} else if len(cl.commands.values) > 1 {
-			cl.helpPrintln("All Commands:")
+			heading = hp.tr("Matching Commands:")
+		} else if len(hp.commands.values) > 1 {
+			heading = hp.tr("All Commands:")
 		} else if simpleDescription {
-			cl.helpPrintln("Description: " + singleCmd.PrimaryArgSpec.HelpText)
+			heading = hp.tr("Description: %s", hp.helpText(singleCmd.PrimaryArgSpec.HelpText))
 			optionIndent = 1
 		} else {
-			cl.helpPrintln("Command Options:")
+			heading = hp.tr("Command Options:")
 			if singleCmd.PrimaryArgSpec.Unnamed {
 				optionIndent = 1
 			}
 		}
 
-		cl.helpPrintBlankln()
-
-		// print each command and its options
-		sort.SliceStable(
-			commandsToPrint,
-			func(i, j int) bool {
-				return sortCompare(commandsToPrint[i].PrimaryArgSpec.String(), commandsToPrint[j].PrimaryArgSpec.String())
-			},
-		)
-
-		for _, cmd := range commandsToPrint {
-			if !simpleDescription {
-				argText := cmd.PrimaryArgSpec.String()
-				if len(argText) == 0 {
-					if len(cmd.PrimaryArgSpec.HelpText) > 0 {
-						cl.helpPrintln(cmd.PrimaryArgSpec.HelpText)
-						cl.helpPrintBlankln()
+		// print each command and its options, keeping subcommands together
+		if hp.helpOrder == Alphabetical {
+			sort.SliceStable(
+				commandsToPrint,
+				func(i, j int) bool {
+					a := commandsToPrint[i].PrimaryArgSpec
+					b := commandsToPrint[j].PrimaryArgSpec
+					if a.Unnamed || b.Unnamed || a.Key == b.Key {
+						return sortCompare(a.String(), b.String())
 					}
-				} else {
-					cl.helpPrintCols(optionIndent-1, argText, cmd.PrimaryArgSpec.HelpText)
-				}
-			}
-
-			for _, optionName := range cmd.OptionSpecs.order {
-				option := cmd.OptionSpecs.values[optionName]
-				cl.helpPrintCols(optionIndent, option.String(), option.HelpText)
-			}
+					return sortCompare(a.Key, b.Key)
+				},
+			)
 		}
 
-		cl.helpPrintBlankln()
+		for _, section := range hp.commandSections(heading, cmdPartial, commandsToPrint) {
+			hp.helpPrintln(section.heading)
+			hp.helpPrintBlankln()
+			hp.printCommandList(section.commands, optionIndent, simpleDescription)
+			hp.helpPrintBlankln()
+		}
 	} else if len(globalOptionsToPrint) == 0 {
 		hasOptions := false
-		for _, cmd := range cl.commands.values {
+		for _, cmd := range hp.commands.values {
 			if len(cmd.OptionSpecs.values) > 0 || len(cmd.PrimaryArgSpec.ValueSpecs) > 0 {
 				hasOptions = true
 				break
 			}
 		}
 
-		cl.helpPrintBlanklnFirst() // space for emphasis
+		hp.helpPrintBlanklnFirst() // space for emphasis
 
 		if len(filter) > 0 {
-			cl.helpPrintf("No commands match help filter '%s'.", filter)
+			hp.helpPrintln(hp.tr("No commands match help filter '%s'.", filter))
 		} else if !hasOptions {
-			cl.helpPrintln("This command has no options.")
+			hp.helpPrintln(hp.tr("This command has no options."))
 		} else {
-			cl.helpPrintln("No help is available.")
+			hp.helpPrintln(hp.tr("No help is available."))
+		}
+
+		hp.helpPrintBlankln()
+	}
+}
+
+func (hp *helpPrinter) printCommandList(commands []*command, optionIndent int, simpleDescription bool) {
+	groupPath := []string{}
+	for _, cmd := range commands {
+		depth := 0
+		if !simpleDescription {
+			argText := cmd.PrimaryArgSpec.String()
+			if len(argText) == 0 {
+				if len(cmd.PrimaryArgSpec.HelpText) > 0 {
+					hp.helpPrintln(hp.helpText(cmd.PrimaryArgSpec.HelpText))
+					hp.helpPrintBlankln()
+				}
+			} else {
+				// subcommands are listed under their parent command path
+				var leafText string
+				depth, leafText = hp.helpPrintParents(optionIndent-1, cmd, &groupPath)
+				hp.helpPrintCols(optionIndent-1+depth, leafText, hp.helpText(cmd.PrimaryArgSpec.HelpText))
+				hp.helpPrintDetails(optionIndent-1+depth, cmd.PrimaryArgSpec)
+			}
 		}
 
-		cl.helpPrintBlankln()
+		hp.helpPrintArguments(optionIndent+depth, cmd)
+		hp.helpPrintOptions(optionIndent+depth, cmd)
+		hp.helpPrintConstraints(optionIndent+depth, cmd)
+		hp.helpPrintExamples(optionIndent+depth, cmd)
 	}
 }
 
+func (hp *helpPrinter) helpPrintParents(indent int, cmd *command, groupPath *[]string) (depth int, leafText string) {
+	argText := cmd.PrimaryArgSpec.String()
+	path := strings.Split(cmd.PrimaryArgSpec.Key, " ")
+	depth = len(path) - 1
+
+	// print the parent levels that weren't already printed by a prior command
+	for level, name := range path[:depth] {
+		if level < len(*groupPath) && (*groupPath)[level] == name {
+			continue
+		}
+		*groupPath = append((*groupPath)[:level], name)
+		hp.helpPrintCols(indent+level, name, "")
+	}
+
+	*groupPath = path
+	leafText = strings.TrimPrefix(argText, strings.Join(path[:depth], " ")+" ")
+	return
+}
+
 func (cl *CommandLine) splitColon(arg string) (string, *string) {
 	//
 	// split an input argument at its colon, if any. Arguments that
@@ -628,11 +893,195 @@ func (cl *CommandLine) splitColon(arg string) (string, *string) {
 	}
 }
 
+// rewrites --name=value into the form the option's spec expects, --name:value
+// or --name value; args that don't name a known option are left alone
+func expandEqualsArgs(args []string, lookup func(name string) (*argSpec, bool)) []string {
+	expanded := make([]string, 0, len(args))
+
+	for _, arg := range args {
+		equals := strings.Index(arg, "=")
+		if !strings.HasPrefix(arg, "-") || equals < 0 || strings.Contains(arg[:equals], ":") {
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		as, exists := lookup(arg[:equals])
+		if !exists {
+			expanded = append(expanded, arg)
+		} else if as.ValuesDelim == ' ' {
+			expanded = append(expanded, arg[:equals], arg[equals+1:])
+		} else {
+			expanded = append(expanded, arg[:equals]+":"+arg[equals+1:])
+		}
+	}
+
+	return expanded
+}
+
+func (cl *CommandLine) lookupGlobalArgSpec(name string) (*argSpec, bool) {
+	globalOpt, exists := cl.globalOptions.lookup(name)
+	if !exists {
+		return nil, false
+	}
+	return globalOpt.argSpec, true
+}
+
 func (cl *CommandLine) Process(args []string) error {
 	return cl.ProcessWithContext(nil, args)
 }
 
 func (cl *CommandLine) ProcessWithContext(processingContext any, args []string) error {
+	return cl.localizeError(cl.process(context.Background(), processingContext, args))
+}
+
+// processes the args with a context that handlers can use to honor cancellation
+// and deadlines; the context is also the processing context in Values[""]
+func (cl *CommandLine) ProcessContext(ctx context.Context, args []string) error {
+	return cl.localizeError(cl.process(ctx, ctx, args))
+}
+
+// the args separated into the global options and the command args
+type parsedGlobalArgs struct {
+	globalOptionsToRun []*globalOptionToRun
+	commandArgs        []string
+	restArgs           []string
+	trace              *parseTrace
+}
+
+func (ga *parsedGlobalArgs) optionValues() []Values {
+	values := make([]Values, 0, len(ga.globalOptionsToRun))
+	for _, gotr := range ga.globalOptionsToRun {
+		values = append(values, gotr.Values)
+	}
+	return values
+}
+
+func (ga *parsedGlobalArgs) optionNames() []string {
+	names := make([]string, 0, len(ga.globalOptionsToRun))
+	for _, gotr := range ga.globalOptionsToRun {
+		names = append(names, gotr.Option.argSpec.Key)
+	}
+	return names
+}
+
+func (cl *CommandLine) process(ctx context.Context, processingContext any, args []string) error {
+	return cl.processStream(ctx, processingContext, args, nil, nil)
+}
+
+// processes the args; the tokens of stream, if any, are streamed to the command's
+// streamed value before its handler runs, and result, if any, receives the resolved
+// command line
+func (cl *CommandLine) processStream(ctx context.Context, processingContext any, args []string, stream *Tokenizer, result *ParseResult) (err error) {
+	cl.recordInvocation(nil, nil)
+	cl.recordResult(nil)
+
+	obs := cl.observe()
+	defer func() { obs.finished(err) }()
+
+	globalArgs, err := cl.parseGlobalArgs(args, nil)
+	if err != nil {
+		cl.logDebug("cmdline parse failed", "error", err)
+		return err
+	}
+
+	cl.logGlobalOptions(globalArgs)
+
+	cl.setVerbosity(globalArgs.optionNames())
+	cl.setOutputFormat(globalArgs.optionNames(), globalArgs.optionValues())
+
+	chain := cl.splitCommandChain(globalArgs)
+
+	if cl.isDryRun(globalArgs.optionNames()) {
+		for _, link := range chain {
+			cmdToRun, err := cl.parseCommandArgs(processingContext, link)
+			if err != nil {
+				cl.logDebug("cmdline parse failed", "error", err)
+				return err
+			}
+
+			cl.logCommand(cmdToRun)
+			cl.recordInvocation(link, cmdToRun)
+			result.fill(args, link, cmdToRun)
+			if err := cl.printDryRun(cl.LastInvocation()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	//
+	// Execute the global options before processing the rest of the args.
+	//
+
+	for _, globalOptToRun := range globalArgs.globalOptionsToRun {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := cl.wrapGlobalHandler(globalOptToRun.Option.Handler)(ctx, globalOptToRun.Values)
+		if err != nil {
+			cl.logDebug("cmdline handler failed", "option", globalOptToRun.Option.argSpec.Key, "error", err)
+			return err
+		}
+	}
+
+	// chained commands are all parsed before any runs
+	cmdsToRun := make([]*commandToRun, 0, len(chain))
+	for _, link := range chain {
+		cmdToRun, err := cl.parseCommandArgs(processingContext, link)
+		if err != nil {
+			cl.logDebug("cmdline parse failed", "error", err)
+			return err
+		}
+		cmdsToRun = append(cmdsToRun, cmdToRun)
+	}
+
+	//
+	// Execute the commands.
+	//
+
+	for i, cmdToRun := range cmdsToRun {
+		if i > 0 {
+			obs.finished(nil)
+			obs = cl.observe()
+		}
+
+		cl.logCommand(cmdToRun)
+		cl.recordInvocation(chain[i], cmdToRun)
+		cl.recordResult(nil)
+		result.fill(args, chain[i], cmdToRun)
+		obs.started(cmdToRun.cmd)
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := cl.confirm(cmdToRun.cmd, cmdToRun.values); err != nil {
+			return err
+		}
+
+		// a token stream goes to the last command
+		if stream != nil && i == len(cmdsToRun)-1 {
+			if err := cl.streamTokens(ctx, cmdToRun, stream); err != nil {
+				return err
+			}
+		}
+
+		err = cl.wrapHandler(cmdToRun.cmd.Handler)(withRunningCommand(ctx, cmdToRun), cmdToRun.values)
+		if err != nil {
+			cl.logDebug("cmdline handler failed", "command", cmdToRun.cmd.PrimaryArgSpec.Key, "error", err)
+		}
+		if result != nil {
+			result.Result = cmdToRun.result
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cl *CommandLine) parseGlobalArgs(args []string, trace *parseTrace) (*parsedGlobalArgs, error) {
 	//
 	// Enforce minimum requirements.
 	//
@@ -641,46 +1090,118 @@ func (cl *CommandLine) ProcessWithContext(processingContext any, args []string)
 		panic(fmt.Errorf("a command option is required"))
 	}
 
+	//
+	// Set aside the args that follow the "--" terminator.
+	//
+
+	var restArgs []string
+	for i, arg := range args {
+		if arg == "--" {
+			restArgs = append([]string{}, args[i+1:]...)
+			args = args[:i]
+			break
+		}
+	}
+
+	cl.loadNamedLazyCommands(args)
+
+	if handled, err := cl.runCompletionCommand(args, restArgs); handled {
+		return nil, err
+	}
+
+	if cl.autoHelp(args) {
+		return nil, ErrHelpShown
+	}
+
 	//
 	// Extract all global args.
 	//
 
 	globalOptionsToRun := []*globalOptionToRun{}
 	commandArgs := []string{}
+	occurrences := make(map[*globalOption]int)
 
+	// the args are expanded one at a time, so that with ordered options, the args
+	// after the command aren't expanded, and abbreviations stop at the command
+	ordered := cl.optionParsing == OrderedOptions
 	for i := 0; i < len(args); i++ {
+		abbreviate := len(commandArgs) == 0 && !cl.isUnnamedCommandOption(args[i])
+		expanded, err := cl.expandGlobalArgs(args[i:i+1], abbreviate)
+		if err != nil {
+			return nil, err
+		}
+		args = spliceArgs(args, i, expanded)
+
 		arg := args[i]
 		globalArgSwitch, globalArgValue := cl.splitColon(arg)
 
-		globalOpt, exists := cl.globalOptions.values[globalArgSwitch]
+		globalOpt, exists := cl.globalOptions.lookup(globalArgSwitch)
 		if exists {
+			skip, err := cl.duplicateOption(globalOpt.argSpec, occurrences[globalOpt], globalArgSwitch)
+			if err != nil {
+				return nil, err
+			}
+			if !skip {
+				trace.addOption(GlobalOptionStep, globalOpt.argSpec, globalArgSwitch, globalArgValue)
+			}
+
+			// a repeated count is tallied by its first occurrence, so its handler runs
+			// once with the total
+			if counted := findGlobalOptionToRun(globalOptionsToRun, globalOpt); counted != nil && globalOpt.argSpec.isCount() {
+				if _, err := globalOpt.argSpec.Parse(&counted.Values, globalArgValue, nil); err != nil {
+					return nil, err
+				}
+				occurrences[globalOpt]++
+				continue
+			}
+
 			gotr, argsUsed, err := cl.newGlobalOptionToRun(globalOpt, globalArgValue, args[i+1:])
 			if err != nil {
-				return err
+				return nil, err
 			}
+			if skip {
+				i += argsUsed
+				continue
+			}
+			globalOpt.argSpec.applyNegation(gotr.Values, globalArgSwitch)
+			trace.addValues(globalOpt.argSpec, args[i+1:i+1+argsUsed])
+			occurrences[globalOpt]++
 			i += argsUsed
 			globalOptionsToRun = append(globalOptionsToRun, gotr)
+		} else if ordered {
+			commandArgs = append(commandArgs, args[i:]...)
+			break
 		} else {
 			commandArgs = append(commandArgs, arg)
 		}
 	}
 
-	//
-	// Execute the global options before processing the rest of the args.
-	//
-
-	for _, globalOptToRun := range globalOptionsToRun {
-		err := globalOptToRun.Option.Handler(globalOptToRun.Values)
-		if err != nil {
-			return err
+	for _, name := range cl.globalOptions.order {
+		globalOpt := cl.globalOptions.values[name]
+		if err := globalOpt.argSpec.validateRepeatCount(occurrences[globalOpt]); err != nil {
+			return nil, err
 		}
 	}
 
+	// higher priority options run first; otherwise, they run in command line order
+	sort.SliceStable(
+		globalOptionsToRun,
+		func(i, j int) bool {
+			return globalOptionsToRun[i].Option.Priority > globalOptionsToRun[j].Option.Priority
+		},
+	)
+
+	return &parsedGlobalArgs{globalOptionsToRun: globalOptionsToRun, commandArgs: commandArgs, restArgs: restArgs, trace: trace}, nil
+}
+
+func (cl *CommandLine) parseCommandArgs(processingContext any, globalArgs *parsedGlobalArgs) (*commandToRun, error) {
 	//
 	// Find the command to run.
 	//
 
-	args = commandArgs
+	globalArgs = cl.expandAlias(globalArgs)
+	args := globalArgs.commandArgs
+	trace := globalArgs.trace
 	argBaseIndex := 1
 	var cmd *command
 	var primaryArgValue *string
@@ -689,16 +1210,24 @@ func (cl *CommandLine) ProcessWithContext(processingContext any, args []string)
 		// Invoke the single unnamed handler, if there is one.
 		//
 
-		cmd = cl.unnamedCmd
+		if cl.unnamedCmd != nil {
+			cmd, _ = cl.enabledCommand("~")
+		}
 
 		if cmd == nil {
-			return NewCommandLineError("A command is required")
+			return nil, newKindError(ErrMissingCommand, "", "", "A command is required")
 		}
 
 		argBaseIndex = 0
+		trace.add(CommandStep, "", "~")
 	} else if cl.unnamedCmd != nil {
-		cmd = cl.unnamedCmd
+		var exists bool
+		cmd, exists = cl.enabledCommand("~")
+		if !exists {
+			return nil, newSuggestionError(cl.suggestCommand(args), args[0], "Unrecognized command: %s", args[0])
+		}
 		argBaseIndex = 0
+		trace.add(CommandStep, "", "~")
 	} else {
 		//
 		// Find the named arg.
@@ -711,41 +1240,47 @@ func (cl *CommandLine) ProcessWithContext(processingContext any, args []string)
 		}
 
 		var exists bool
-		cmd, exists = cl.commands.values[primaryArgSwitch]
-		if !exists {
-			// try multi-token commands
-			for n := 2; n <= len(args); n++ {
-				if strings.HasPrefix(args[n-1], "-") {
-					break
-				}
+		cmd, exists = cl.enabledCommand(primaryArgSwitch)
 
-				primaryArgSwitch = strings.Join(args[0:n], " ")
-				cmd, exists = cl.commands.values[primaryArgSwitch]
-				if exists {
-					args = append([]string{primaryArgSwitch}, args[n:]...)
-					break
-				}
+		// try multi-token commands, preferring the deepest subcommand
+		tokensUsed := 1
+		for n := 2; n <= len(args); n++ {
+			if strings.HasPrefix(args[n-1], "-") {
+				break
 			}
 
-			if !exists {
-				// look for a default arg
-				cmd, exists = cl.commands.values["~"]
-				if !exists {
-					return NewCommandLineError("Unrecognized command: " + primaryArgSwitch)
-				}
-				argBaseIndex = 0
+			subcmdSwitch := strings.Join(args[0:n], " ")
+			subcmd, subExists := cl.enabledCommand(subcmdSwitch)
+			if subExists {
+				primaryArgSwitch = subcmdSwitch
+				cmd = subcmd
+				exists = true
+				tokensUsed = n
 			}
 		}
-	}
 
-	cmdToRun, argsUsed, err := cl.newCommandToRun(cmd, primaryArgValue, args[argBaseIndex:])
-	if err != nil {
-		return err
+		if tokensUsed > 1 {
+			args = append([]string{primaryArgSwitch}, args[tokensUsed:]...)
+		}
+
+		if !exists {
+			// look for a default arg
+			cmd, exists = cl.enabledCommand("~")
+			if !exists {
+				return nil, newSuggestionError(cl.suggestCommand(args), primaryArgSwitch, "Unrecognized command: %s", primaryArgSwitch)
+			}
+			argBaseIndex = 0
+			trace.add(CommandStep, "", "~")
+		} else if tokensUsed == 1 {
+			trace.addOption(CommandStep, cmd.PrimaryArgSpec, primaryArgSwitch, primaryArgValue)
+		} else {
+			for _, token := range globalArgs.commandArgs[:tokensUsed] {
+				trace.add(CommandStep, token, primaryArgSwitch)
+			}
+		}
 	}
 
-	//
-	// Add options to the command.
-	//
+	cmdToRun := &commandToRun{cmd: cmd, values: make(map[string]any)}
 
 	requiredOptions := make(map[string]bool)
 
@@ -755,63 +1290,196 @@ func (cl *CommandLine) ProcessWithContext(processingContext any, args []string)
 		}
 	}
 
-	for i := argBaseIndex + argsUsed; i < len(args); i++ {
+	specifiedOptions := make(map[string]bool)
+	occurrences := make(map[string]int)
+	if cl.unknownArgPolicy == UnknownArgsCollect {
+		cmdToRun.values[UnknownArgsKey] = []string{}
+	}
+
+	// parses the option at args[i], providing the count of the args that follow it
+	// which were used as its values
+	parseOption := func(i int) (int, error) {
 		optionArgSwitch, optionArgValue := cl.splitColon(args[i])
 
-		optionSpec, exists := cmd.OptionSpecs.values[optionArgSwitch]
+		optionSpec, exists := cmd.OptionSpecs.lookup(optionArgSwitch)
 		if !exists {
-			return NewCommandLineError("Unrecognized command argument: " + optionArgSwitch)
+			return 0, cl.unknownArg(cmd, cmdToRun.values, args[i], optionArgSwitch)
 		}
 
-		cmdToRun.values[optionArgSwitch] = true
+		skip, err := cl.duplicateOption(optionSpec, occurrences[optionSpec.Key], optionArgSwitch)
+		if err != nil {
+			return 0, err
+		}
+		if skip {
+			// parse the values aside, only to pass over them
+			return optionSpec.Parse(&map[string]any{}, optionArgValue, args[i+1:])
+		}
+
+		trace.addOption(OptionStep, optionSpec, optionArgSwitch, optionArgValue)
+		specifiedOptions[optionSpec.Key] = true
+		occurrences[optionSpec.Key]++
+		cmdToRun.values[optionSpec.Key] = true
 		argsUsed, err := optionSpec.Parse(&cmdToRun.values, optionArgValue, args[i+1:])
 		if err != nil {
-			return err
+			return 0, err
 		}
+		optionSpec.applyNegation(cmdToRun.values, optionArgSwitch)
+		trace.addValues(optionSpec, args[i+1:i+1+argsUsed])
 
-		i += argsUsed
+		delete(requiredOptions, optionSpec.Key)
+		return argsUsed, nil
+	}
 
-		_, exists = requiredOptions[optionArgSwitch]
-		if exists {
-			delete(requiredOptions, optionArgSwitch)
+	//
+	// With ordered options, the options precede the values, and the first value
+	// ends them.
+	//
+
+	ordered := cl.optionParsing == OrderedOptions
+	valuesStart := argBaseIndex
+	for ordered && valuesStart < len(args) {
+		expanded, err := cl.expandOptionArgs(cmd, args[valuesStart:valuesStart+1])
+		if err != nil {
+			return nil, err
+		}
+		if !cmd.isOptionArg(expanded[0]) {
+			break
+		}
+		args = spliceArgs(args, valuesStart, expanded)
+
+		argsUsed, err := parseOption(valuesStart)
+		if err != nil {
+			return nil, err
+		}
+		valuesStart += 1 + argsUsed
+	}
+
+	argsUsed, err := cmd.PrimaryArgSpec.Parse(&cmdToRun.values, primaryArgValue, args[valuesStart:])
+	if err != nil {
+		return nil, err
+	}
+	trace.addValues(cmd.PrimaryArgSpec, args[valuesStart:valuesStart+argsUsed])
+
+	//
+	// Add options to the command.
+	//
+
+	optionsStart := valuesStart + argsUsed
+	if ordered {
+		// the args after the values aren't options
+		for _, arg := range args[optionsStart:] {
+			if err := cl.unknownArg(cmd, cmdToRun.values, arg, arg); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		optionArgs, err := cl.expandOptionArgs(cmd, args[optionsStart:])
+		if err != nil {
+			return nil, err
+		}
+		args = append(append([]string{}, args[:optionsStart]...), optionArgs...)
+
+		for i := optionsStart; i < len(args); i++ {
+			argsUsed, err := parseOption(i)
+			if err != nil {
+				return nil, err
+			}
+			i += argsUsed
 		}
 	}
 
 	if len(requiredOptions) > 0 {
-		return NewCommandLineError("Arguments required: %s", simpleutils.SortedKeys(requiredOptions))
+		missing := simpleutils.SortedKeys(requiredOptions)
+		return nil, newKindError(ErrMissingRequiredOption, missing[0], cmd.OptionSpecs.values[missing[0]].String(), "Arguments required: %s", missing)
+	}
+
+	for _, name := range cmd.OptionSpecs.order {
+		if err := cmd.OptionSpecs.values[name].validateRepeatCount(occurrences[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, constraint := range cmd.Constraints {
+		if err := constraint.validate(specifiedOptions); err != nil {
+			return nil, err
+		}
 	}
 
 	//
 	// Put empty values in for all optional and unspecified options.
 	//
 
-	for _, optionSpec := range cmd.OptionSpecs.values {
+	var specified map[string]bool
+	if trace != nil {
+		specified = make(map[string]bool, len(cmdToRun.values))
+		for key := range cmdToRun.values {
+			specified[key] = true
+		}
+	}
+
+	for _, name := range cmd.OptionSpecs.order {
+		optionSpec := cmd.OptionSpecs.values[name]
 		if optionSpec.Optional {
-			cl.addDefaults(cmdToRun, optionSpec)
+			if err := cl.addDefaults(cmdToRun, optionSpec); err != nil {
+				return nil, err
+			}
 		}
 	}
 
-	cl.addDefaults(cmdToRun, cmd.PrimaryArgSpec)
+	if err := cl.addDefaults(cmdToRun, cmd.PrimaryArgSpec); err != nil {
+		return nil, err
+	}
 
-	//
-	// Execute the command.
-	//
+	if trace != nil {
+		specs := []*argSpec{cmd.PrimaryArgSpec}
+		for _, name := range cmd.OptionSpecs.order {
+			specs = append(specs, cmd.OptionSpecs.values[name])
+		}
+		trace.addDefaults(cmdToRun.values, specified, specs...)
+	}
+
+	if cl.globalValues {
+		if err := cl.addGlobalValues(cmdToRun, globalArgs); err != nil {
+			return nil, err
+		}
+	}
+	cmdToRun.sources = cl.takeValueSources(cmdToRun.values)
 
 	cmdToRun.values[""] = processingContext
+	if globalArgs.restArgs != nil {
+		cmdToRun.values[RestArgs] = globalArgs.restArgs
+		for _, token := range globalArgs.restArgs {
+			trace.add(RestStep, token, RestArgs)
+		}
+	}
 
-	return cmd.Handler(cmdToRun.values)
+	return cmdToRun, nil
 }
 
-func (cl *CommandLine) addDefaults(cmdToRun *commandToRun, as *argSpec) {
+func (cl *CommandLine) addDefaults(cmdToRun *commandToRun, as *argSpec) error {
 	_, exists := cmdToRun.values[as.Key]
 	if !exists {
 		cmdToRun.values[as.Key] = false
+		setValueSource(cmdToRun.values, as.Key, SourceDefault)
+	}
+	if as.Negation != "" {
+		_, exists = cmdToRun.values[as.Negation]
+		if !exists {
+			cmdToRun.values[as.Negation] = false
+			setValueSource(cmdToRun.values, as.Negation, SourceDefault)
+		}
 	}
 
 	for _, valueSpec := range as.ValueSpecs {
 		_, exists = cmdToRun.values[valueSpec.OptionName]
 		if !exists {
-			cmdToRun.values[valueSpec.OptionName] = valueSpec.DefaultValue
+			value, source, err := as.defaultValue(valueSpec, cmdToRun.values)
+			if err != nil {
+				return err
+			}
+			cmdToRun.values[valueSpec.OptionName] = value
+			setValueSource(cmdToRun.values, valueSpec.OptionName, source)
 		}
 	}
+	return nil
 }
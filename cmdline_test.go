@@ -1,15 +1,27 @@
 package cmdline
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"math/rand"
 	"os"
 	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/jimsnab/go-testutils"
+	"github.com/jimsnab/go-toolprinter"
 )
 
 var (
@@ -103,7 +115,8 @@ func TestCommandCustomOptionTypes(t *testing.T) {
 	received = ""
 	args = []string{"val:skipped"}
 	err = cl.Process(args)
-	expectError(t, fmt.Errorf("unsupported argument value \"skipped\""), err)
+	expectError(t, NewCommandLineError("Invalid test value skipped: unsupported argument value \"skipped\""), err)
+	expectBool(t, true, errors.Is(err, ErrInvalidValue))
 }
 
 func TestCommandRequired(t *testing.T) {
@@ -147,6 +160,51 @@ func TestCommandWithProcessingContext(t *testing.T) {
 	expectString(t, "passed thru", executed.(string))
 }
 
+func TestCommandWithGoContext(t *testing.T) {
+	cl := NewCommandLine()
+
+	type ctxKey string
+	var received any
+	var processingContext any
+	globalSeen := false
+
+	cl.RegisterCommandCtx(
+		func(ctx context.Context, values Values) error {
+			received = ctx.Value(ctxKey("key"))
+			processingContext = values[""]
+			return nil
+		},
+		"test",
+	)
+
+	cl.RegisterGlobalOptionCtx(
+		func(ctx context.Context, values Values) error {
+			globalSeen = ctx.Value(ctxKey("key")) != nil
+			return nil
+		},
+		"--opt",
+	)
+
+	ctx := context.WithValue(context.Background(), ctxKey("key"), "value")
+	err := cl.ProcessContext(ctx, []string{"--opt", "test"})
+	expectError(t, nil, err)
+	expectValue(t, "value", received)
+	expectValue(t, ctx, processingContext)
+	expectBool(t, true, globalSeen)
+
+	received = nil
+	err = cl.Process([]string{"test"})
+	expectError(t, nil, err)
+	expectValue(t, nil, received)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	received = nil
+	err = cl.ProcessContext(canceled, []string{"test"})
+	expectError(t, context.Canceled, err)
+	expectValue(t, nil, received)
+}
+
 func TestOneGlobalFalse(t *testing.T) {
 	cl := NewCommandLine()
 
@@ -472,7 +530,7 @@ func TestNamedSyntaxError(t *testing.T) {
 
 	expectPanicError(
 		t,
-		fmt.Errorf("command line template syntax error! expected value spec in \"test:\""),
+		&SpecError{Spec: "test:", Column: 6, Expected: "value spec"},
 		func() {
 			cl.RegisterCommand(
 				func(values Values) error {
@@ -485,7 +543,7 @@ func TestNamedSyntaxError(t *testing.T) {
 
 	expectPanicError(
 		t,
-		fmt.Errorf("command line template syntax error! expected value spec in \"test \""),
+		&SpecError{Spec: "test ", Column: 6, Expected: "value spec"},
 		func() {
 			cl.RegisterCommand(
 				func(values Values) error {
@@ -498,7 +556,7 @@ func TestNamedSyntaxError(t *testing.T) {
 
 	expectPanicError(
 		t,
-		fmt.Errorf("command line template syntax error! expected a valid argument token in \"test$\""),
+		&SpecError{Spec: "test$", Column: 1, Expected: "a valid argument token"},
 		func() {
 			cl.RegisterCommand(
 				func(values Values) error {
@@ -511,7 +569,7 @@ func TestNamedSyntaxError(t *testing.T) {
 
 	expectPanicError(
 		t,
-		fmt.Errorf("command line template syntax error! expected a valid argument token in \"test<string-value>\""),
+		&SpecError{Spec: "test<string-value>", Column: 1, Expected: "a valid argument token"},
 		func() {
 			cl.RegisterCommand(
 				func(values Values) error {
@@ -524,7 +582,7 @@ func TestNamedSyntaxError(t *testing.T) {
 
 	expectPanicError(
 		t,
-		fmt.Errorf("command line template syntax error! expected valid arg type strings in test:<strings-value>"),
+		&SpecError{Spec: "test:<strings-value>", Column: 7, Expected: "valid arg type strings"},
 		func() {
 			cl.RegisterCommand(
 				func(values Values) error {
@@ -537,7 +595,7 @@ func TestNamedSyntaxError(t *testing.T) {
 
 	expectPanicError(
 		t,
-		fmt.Errorf("command line template syntax error! expected valid option name at \"value$>\" of \"test:<string-value$>\""),
+		&SpecError{Spec: "test:<string-value$>", Column: 14, Expected: "valid option name"},
 		func() {
 			cl.RegisterCommand(
 				func(values Values) error {
@@ -550,7 +608,7 @@ func TestNamedSyntaxError(t *testing.T) {
 
 	expectPanicError(
 		t,
-		fmt.Errorf("command line template syntax error! expected '<' at \"string-value\" of \"test:string-value\""),
+		&SpecError{Spec: "test:string-value", Column: 6, Expected: "'<'"},
 		func() {
 			cl.RegisterCommand(
 				func(values Values) error {
@@ -563,7 +621,7 @@ func TestNamedSyntaxError(t *testing.T) {
 
 	expectPanicError(
 		t,
-		fmt.Errorf("command line template syntax error! expected '-' at \"string=value>\" of \"test:<string=value>\""),
+		&SpecError{Spec: "test:<string=value>", Column: 19, Expected: "'-'"},
 		func() {
 			cl.RegisterCommand(
 				func(values Values) error {
@@ -576,7 +634,7 @@ func TestNamedSyntaxError(t *testing.T) {
 
 	expectPanicError(
 		t,
-		fmt.Errorf("command line template syntax error! expected '>' at \"value\" of \"test:<string-value\""),
+		&SpecError{Spec: "test:<string-value", Column: 19, Expected: "'>'"},
 		func() {
 			cl.RegisterCommand(
 				func(values Values) error {
@@ -589,7 +647,7 @@ func TestNamedSyntaxError(t *testing.T) {
 
 	expectPanicError(
 		t,
-		fmt.Errorf("command line template syntax error! expected ']' in \"test:[<string-value>\""),
+		&SpecError{Spec: "test:[<string-value>", Column: 21, Expected: "']'"},
 		func() {
 			cl.RegisterCommand(
 				func(values Values) error {
@@ -601,6 +659,316 @@ func TestNamedSyntaxError(t *testing.T) {
 	)
 }
 
+func TestSpecErrorCaret(t *testing.T) {
+	cl := NewCommandLine()
+
+	r := func() (r any) {
+		defer func() { r = recover() }()
+		cl.RegisterCommand(func(values Values) error { return nil }, "copy", "--mode:<string-mode>,<intx-level>")
+		return
+	}()
+
+	err, isSpecError := r.(*SpecError)
+	expectBool(t, true, isSpecError)
+	expectValue(t, 23, err.Column)
+	expectString(t, "valid arg type intx", err.Expected)
+	expectString(t, "command line template syntax error! expected valid arg type intx at column 23 of \"--mode:<string-mode>,<intx-level>\"\n"+
+		"    --mode:<string-mode>,<intx-level>\n"+
+		"                          ^", err.Error())
+
+	// columns count runes, so the caret lines up under multibyte text
+	expectPanicError(t, &SpecError{Spec: "[--naïve <string-x]", Column: 19, Expected: "'>'"}, func() {
+		cl.RegisterCommand(func(values Values) error { return nil }, "other", "[--naïve <string-x]")
+	})
+}
+
+// a spec either parses or panics with a syntax error, and never with a runtime error
+func FuzzParseArgSpec(f *testing.F) {
+	seeds := []string{
+		"test",
+		"test <string-name>",
+		"copy <path-src> <path-dest>",
+		"sub+cmd:<int[1..9]-n=2>",
+		"[-v|--verbose]",
+		"*{1..3}[-x:<string-tag>]",
+		"[--[no-]color]",
+		"[--size:<int-width>,<int-height>]",
+		"[--only[:<string-name>]]",
+		"[--name <string/^[a-z]+$/-name>]?The name",
+		"greet <string:trim:lower-name>",
+		"[--env:<string-env=$HOME>]",
+		"*<string-files>",
+		"[-c <count-n>]...",
+		"test:[<string-value>",
+		"--port <int[1-65535]-port>",
+		"A|A+00000",
+		"A <path-A><path-B>",
+	}
+	for _, seed := range seeds {
+		f.Add(seed, false)
+		f.Add(seed, true)
+	}
+
+	f.Fuzz(func(t *testing.T, spec string, primaryArg bool) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+
+			if se, isSpecError := r.(*SpecError); isSpecError {
+				if se.Column < 1 || se.Column > len([]rune(spec))+1 {
+					t.Fatalf("column %d is outside of %q", se.Column, spec)
+				}
+				return
+			}
+
+			err, isError := r.(error)
+			if !isError {
+				t.Fatalf("unexpected panic %v for %q", r, spec)
+			}
+			if !strings.HasPrefix(err.Error(), basePanic) && !strings.HasPrefix(err.Error(), "duplicate value spec") {
+				t.Fatalf("unexpected panic %v for %q", err, spec)
+			}
+		}()
+
+		as := NewCommandLine().parseArgSpec(spec, primaryArg)
+		checkCanonicalSpec(t, as.canonicalSpec(), primaryArg)
+	})
+}
+
+// the canonical form of a spec must parse, and be its own canonical form
+func checkCanonicalSpec(t *testing.T, canonical string, primaryArg bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("canonical spec %q doesn't parse: %v", canonical, r)
+		}
+	}()
+
+	again := NewCommandLine().parseArgSpec(canonical, primaryArg).canonicalSpec()
+	if again != canonical {
+		t.Fatalf("canonical spec %q normalizes to %q", canonical, again)
+	}
+}
+
+// registers a command from random specs and processes random args; processing must
+// return, and never panic
+func FuzzProcess(f *testing.F) {
+	seeds := [][3]string{
+		{"copy <path-src> <path-dest>", "[-f|--force]", "copy a b -f"},
+		{"tag", "[--names <string-first> *<string-middle> <string-last>]", "tag --names a b c d e"},
+		{"tag", "[--names *<string-all>]", "tag --names a b c"},
+		{"size", "[--dims:<int-w>,<int-h>]", "size --dims:1,2"},
+		{"size", "[--dims <int-w>,<int-h>,<int-d>]", "size --dims 1,2"},
+		{"run", "*{1..3}[-x:<string-tag>]", "run -x:a -x:b"},
+		{"run", "[--[no-]color]", "run --no-color --color"},
+		{"run", "[--only[:<string-name>]]", "run --only"},
+		{"set <int[1..9]-level>", "[--mode <string/^[a-z]+$/-mode=fast>]", "set 5 --mode slow"},
+		{"count", "[-v <count-n>]", "count -v -v -v"},
+		{"files *<string-paths>", "[--all]", "files a b c --all"},
+	}
+	for _, seed := range seeds {
+		f.Add(seed[0], seed[1], seed[2])
+	}
+
+	f.Fuzz(func(t *testing.T, primarySpec, optionSpec, args string) {
+		cl := NewCommandLine()
+		cl.SetOutput(io.Discard)
+
+		registered := func() (ok bool) {
+			defer func() { recover() }()
+			cl.RegisterCommand(func(values Values) error { return nil }, primarySpec, optionSpec)
+			return true
+		}()
+		if !registered {
+			return
+		}
+
+		done := make(chan any, 1)
+		go func() {
+			defer func() { done <- recover() }()
+			cl.Process(strings.Fields(args))
+		}()
+
+		select {
+		case r := <-done:
+			if r != nil {
+				t.Fatalf("panic %v processing %q with %q %q", r, args, primarySpec, optionSpec)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("processing %q with %q %q did not return", args, primarySpec, optionSpec)
+		}
+	})
+}
+
+// a randomly generated option, with the rendering String should produce for it, and
+// the args and values of one use of it
+type generatedOption struct {
+	spec    string
+	display string
+	args    []string
+	values  map[string]any
+}
+
+func generateOption(r *rand.Rand, index int) *generatedOption {
+	key := fmt.Sprintf("--o%d", index)
+	keys := key
+	if r.Intn(2) == 0 {
+		keys += fmt.Sprintf("|-%c", 'a'+index)
+	}
+
+	g := generatedOption{values: map[string]any{key: true}}
+	randomValue := func() string {
+		return fmt.Sprintf("%c%d", 'a'+r.Intn(26), r.Intn(1000))
+	}
+
+	count := r.Intn(4)
+	if count == 0 {
+		g.spec = keys
+		g.display = keys
+		g.args = []string{key}
+	} else {
+		// a colon requires a comma-separated list; a space allows either, and a space
+		// separated list can have one repeated value at any position
+		valuesDelim := " "
+		if r.Intn(2) == 0 {
+			valuesDelim = ":"
+		}
+		valueDelim := ","
+		multi := -1
+		if valuesDelim == " " && r.Intn(2) == 0 {
+			valueDelim = " "
+			multi = r.Intn(count)
+		}
+
+		specValues := make([]string, 0, count)
+		displayValues := make([]string, 0, count)
+		inputs := []string{}
+		for i := 0; i < count; i++ {
+			name := fmt.Sprintf("o%dv%d", index, i)
+			if i == multi {
+				specValues = append(specValues, "<string-"+name+">...")
+				displayValues = append(displayValues, "<"+name+">...")
+
+				list := []string{}
+				for n := r.Intn(4) + 1; n > 0; n-- {
+					list = append(list, randomValue())
+				}
+				inputs = append(inputs, list...)
+				g.values[name] = strings.Join(list, " ")
+			} else {
+				specValues = append(specValues, "<string-"+name+">")
+				displayValues = append(displayValues, "<"+name+">")
+
+				value := randomValue()
+				inputs = append(inputs, value)
+				g.values[name] = value
+			}
+		}
+
+		g.spec = keys + valuesDelim + strings.Join(specValues, valueDelim)
+		g.display = keys + valuesDelim + strings.Join(displayValues, valueDelim)
+		switch {
+		case valuesDelim == ":":
+			g.args = []string{key + ":" + strings.Join(inputs, ",")}
+		case valueDelim == ",":
+			g.args = []string{key, strings.Join(inputs, ",")}
+		default:
+			g.args = append([]string{key}, inputs...)
+		}
+	}
+
+	if r.Intn(2) == 0 {
+		g.spec = "[" + g.spec + "]"
+		g.display = "[" + g.display + "]"
+	}
+	return &g
+}
+
+func TestGeneratedSpecsRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for iteration := 0; iteration < 500; iteration++ {
+		options := make([]*generatedOption, r.Intn(4)+1)
+		specs := []string{"cmd"}
+		args := []string{"cmd"}
+		for i := range options {
+			options[i] = generateOption(r, i)
+			specs = append(specs, options[i].spec)
+		}
+		for _, i := range r.Perm(len(options)) {
+			args = append(args, options[i].args...)
+		}
+
+		cl := NewCommandLine()
+		cl.RegisterCommand(func(values Values) error { return nil }, specs...)
+
+		cmd := cl.commands.values["cmd"]
+		for i, option := range options {
+			expectString(t, option.display, cmd.OptionSpecs.values[cmd.OptionSpecs.order[i]].String())
+
+			canonical, err := CanonicalizeSpec(option.spec)
+			expectError(t, nil, err)
+			expectString(t, option.spec, canonical)
+		}
+
+		pc, err := cl.Parse(args)
+		expectError(t, nil, err)
+		if err != nil {
+			t.Fatalf("parsing %q for %q", args, specs)
+		}
+
+		for _, option := range options {
+			for name, expected := range option.values {
+				switch value := pc.Values[name].(type) {
+				case []string:
+					expectString(t, expected.(string), strings.Join(value, " "))
+				default:
+					expectValue(t, expected, value)
+				}
+			}
+		}
+	}
+}
+
+func TestCanonicalizeSpec(t *testing.T) {
+	specs := map[string]string{
+		"~":                                                      "~",
+		"~ <string-name>?Greets":                                 "~ <string-name>?Greets",
+		"users|create <string-name>":                             "users+create <string-name>",
+		"copy <path-src> <path-dest>...":                         "copy <path-src> <path-dest>...",
+		"copy <path-src> *<path-dest>":                           "copy <path-src> *<path-dest>",
+		"[--tags <string-tag>...]":                               "[--tags <string-tag>...]",
+		"[--tag:<string-tag>[,<int-weight>]]":                    "[--tag:<string-tag>[,<int-weight>]]",
+		"[--only[:<string-name>]]":                               "[--only[:<string-name>]]",
+		"[--only:[<string-name>]]":                               "[--only[:<string-name>]]",
+		"[-x <int-a> [<int-b>]]":                                 "[-x <int-a> [<int-b>]]",
+		"*{1..3}[-t:<string-tags>]?Tags":                         "*{1..3}[-t:<string-tags>]?Tags",
+		"[--[no-]color|-c]":                                      "[--[no-]color|-c]",
+		"[-v|--verbose <count-n>]":                               "[-v|--verbose <count-n>]",
+		"--port <int[1..65535]-port=80>":                         "--port <int[1..65535]-port=80>",
+		"[--name <string:trim:lower/^[a-z]+$/-name>]?Lower name": "[--name <string:trim:lower/^[a-z]+$/-name>]?Lower name",
+		"[--home <path-home=${HOME}>]":                           "[--home <path-home=${HOME}>]",
+	}
+
+	cl := NewCommandLine()
+	for spec, expected := range specs {
+		canonical, err := CanonicalizeSpec(spec)
+		expectError(t, nil, err)
+		expectString(t, expected, canonical)
+
+		again, err := CanonicalizeSpec(canonical)
+		expectError(t, nil, err)
+		expectString(t, canonical, again)
+
+		// the canonical form registers the same as the spec
+		expectString(t, cl.parseArgSpec(spec, !isOptionSpec(spec)).String(), cl.parseArgSpec(canonical, !isOptionSpec(canonical)).String())
+	}
+
+	_, err := CanonicalizeSpec("--n <int[1..9-n>")
+	expectError(t, &SpecError{Spec: "--n <int[1..9-n>", Column: 17, Expected: "']'"}, err)
+}
+
 func TestUnnamedUnexpectedArg(t *testing.T) {
 	cl := NewCommandLine()
 
@@ -888,7 +1256,7 @@ func TestNamedPlainWithTokenSpecOptionalBadSyntax(t *testing.T) {
 
 	expectPanicError(
 		t,
-		errors.New(`command line template syntax error! expected '<' at "[<string-test2>]" of "test <string-test1> *[<string-test2>]"`),
+		&SpecError{Spec: "test <string-test1> *[<string-test2>]", Column: 22, Expected: "'<'"},
 		func() {
 			cl.RegisterCommand(
 				func(values Values) error {
@@ -1744,7 +2112,7 @@ func TestParseError(t *testing.T) {
 	args := []string{"test", "-x:invalid"}
 	err := cl.Process(args)
 	_, numError := strconv.ParseBool("invalid")
-	expectError(t, numError, err)
+	expectError(t, NewCommandLineError("Invalid bool value invalid: %s", numError), err)
 }
 
 func TestHandlerError(t *testing.T) {
@@ -1911,6 +2279,54 @@ func TestMultiValuePath(t *testing.T) {
 	expectValue(t, path.Join(dir, "testpath"), flags[1])
 }
 
+func TestMultiValueDuration(t *testing.T) {
+	cl := NewCommandLine()
+
+	timeouts := []time.Duration{}
+
+	cl.RegisterCommand(
+		func(values Values) error {
+			timeouts = values["timeout"].([]time.Duration)
+			return nil
+		},
+		"~",
+		"*[--timeout:<duration-timeout>]",
+	)
+
+	args := []string{"--timeout:1s", "--timeout:1h15m"}
+	err := cl.Process(args)
+	expectError(t, nil, err)
+	expectValue(t, 2, len(timeouts))
+	expectValue(t, time.Second, timeouts[0])
+	expectValue(t, time.Hour+15*time.Minute, timeouts[1])
+}
+
+func TestDuration(t *testing.T) {
+	cl := NewCommandLine()
+
+	var timeout any
+
+	cl.RegisterCommand(
+		func(values Values) error {
+			timeout = values["timeout"]
+			return nil
+		},
+		"~",
+		"[--timeout:<duration-timeout>]",
+	)
+
+	err := cl.Process([]string{"--timeout:250ms"})
+	expectError(t, nil, err)
+	expectValue(t, 250*time.Millisecond, timeout)
+
+	err = cl.Process([]string{})
+	expectError(t, nil, err)
+	expectValue(t, time.Duration(0), timeout)
+
+	err = cl.Process([]string{"--timeout:soon"})
+	expectErrorContainingText(t, "invalid duration", err)
+}
+
 func TestMultiValueInvalid(t *testing.T) {
 	cl := NewCommandLine()
 
@@ -1939,7 +2355,7 @@ func TestMultiValueInvalid(t *testing.T) {
 	err := cl.Process(args)
 
 	_, boolErr := strconv.ParseBool("one")
-	expectError(t, boolErr, err)
+	expectError(t, NewCommandLineError("Invalid bool value one: %s", boolErr), err)
 
 	// int
 	cl = NewCommandLine()
@@ -1956,7 +2372,7 @@ func TestMultiValueInvalid(t *testing.T) {
 	err = cl.Process(args)
 
 	_, intErr := strconv.Atoi("one")
-	expectError(t, intErr, err)
+	expectError(t, NewCommandLineError("Invalid int value one: %s", intErr), err)
 
 	// float64
 	cl = NewCommandLine()
@@ -1973,7 +2389,40 @@ func TestMultiValueInvalid(t *testing.T) {
 	err = cl.Process(args)
 
 	_, floatErr := strconv.ParseFloat("one", 64)
-	expectError(t, floatErr, err)
+	expectError(t, NewCommandLineError("Invalid float64 value one: %s", floatErr), err)
+}
+
+func TestConversionErrorKind(t *testing.T) {
+	cl := NewCommandLine()
+
+	cl.RegisterCommand(func(values Values) error { return nil }, "run", "[--count <int-count>]", "[--ratio <float64-ratio>]", "[--wait <duration-wait>]", "[--ids *<int-id>]")
+
+	err := cl.Process([]string{"run", "--count", "abc"})
+	expectBool(t, true, errors.Is(err, ErrInvalidValue))
+	var numErr *strconv.NumError
+	expectBool(t, true, errors.As(err, &numErr))
+	expectString(t, "Invalid int value abc: strconv.Atoi: parsing \"abc\": invalid syntax", err.Error())
+
+	report := NewErrorReport(err)
+	expectString(t, "invalid_value", report.Code)
+	expectString(t, "abc", report.Arg)
+	expectString(t, "[--count <count>]", report.Spec)
+
+	err = cl.Process([]string{"run", "--ratio", "half"})
+	expectBool(t, true, errors.Is(err, ErrInvalidValue))
+
+	err = cl.Process([]string{"run", "--wait", "soon"})
+	expectBool(t, true, errors.Is(err, ErrInvalidValue))
+	expectString(t, "Invalid duration value soon: time: invalid duration \"soon\"", err.Error())
+
+	err = cl.Process([]string{"run", "--ids", "1", "x"})
+	expectBool(t, true, errors.Is(err, ErrInvalidValue))
+
+	// a default that refers to an environment variable is converted too
+	t.Setenv("CMDLINE_TEST_COUNT", "many")
+	cl.RegisterCommand(func(values Values) error { return nil }, "walk", "[--steps <int-steps=${CMDLINE_TEST_COUNT}>]")
+	err = cl.Process([]string{"walk"})
+	expectBool(t, true, errors.Is(err, ErrInvalidValue))
 }
 
 func TestNonUniformValueDelimiter(t *testing.T) {
@@ -2213,7 +2662,7 @@ func TestArgsWithSpaceBools(t *testing.T) {
 	args = []string{"test", "invalid"}
 	err = cl.Process(args)
 	_, invalidBool := strconv.ParseBool("invalid")
-	expectError(t, invalidBool, err)
+	expectError(t, NewCommandLineError("Invalid bool value invalid: %s", invalidBool), err)
 }
 
 func TestArgsWithSpaceOptional(t *testing.T) {
@@ -2960,7 +3409,7 @@ func TestPrintHelpSyntaxError(t *testing.T) {
 		},
 	)
 
-	expectString(t, "\nstrconv.Atoi: parsing \"false\": invalid syntax\n\n", output)
+	expectString(t, "\nSyntax error.\n\nCommand Help:\n\ncat:<num>  Morris is his name\n\n", output)
 }
 
 func TestPrintHelpGlobalOptions(t *testing.T) {
@@ -3374,3 +3823,4462 @@ func TestTwoByTwoNamed(t *testing.T) {
 	expectBool(t, false, executed)
 	expectBool(t, true, second)
 }
+
+func TestNestedSubcommands(t *testing.T) {
+	cl := NewCommandLine()
+
+	executed := ""
+	var username string
+	cl.RegisterCommand(
+		func(values Values) error {
+			executed = "users"
+			return nil
+		},
+		"users?Lists users",
+	)
+
+	cl.RegisterCommand(
+		func(values Values) error {
+			executed = "users create"
+			username = values["username"].(string)
+			return nil
+		},
+		"users|create <string-username>?Creates a user",
+		"[--admin]?Grants admin rights",
+	)
+
+	cl.RegisterCommand(
+		func(values Values) error {
+			executed = "users delete"
+			return nil
+		},
+		"users|delete <string-name>?Deletes a user",
+	)
+
+	cl.RegisterCommand(
+		func(values Values) error {
+			executed = "groups list"
+			return nil
+		},
+		"groups|list?Lists groups",
+	)
+
+	args := []string{"users", "create", "bob"}
+	err := cl.Process(args)
+	expectError(t, nil, err)
+	expectString(t, "users create", executed)
+	expectString(t, "bob", username)
+	expectString(t, "users create", cl.PrimaryCommand(args))
+
+	args = []string{"users"}
+	err = cl.Process(args)
+	expectError(t, nil, err)
+	expectString(t, "users", executed)
+	expectString(t, "users", cl.PrimaryCommand(args))
+
+	args = []string{"groups", "list"}
+	err = cl.Process(args)
+	expectError(t, nil, err)
+	expectString(t, "groups list", executed)
+	expectString(t, "groups list", cl.PrimaryCommand(args))
+
+	args = []string{"groups"}
+	err = cl.Process(args)
+	expectError(t, NewCommandLineError("Unrecognized command: groups"), err)
+	expectString(t, "", cl.PrimaryCommand(args))
+
+	output := captureStdout(
+		t,
+		func() {
+			cl.PrintCommands("", false)
+		},
+	)
+
+	expectString(
+		t,
+		"All Commands:\n\n"+
+			"  groups\n"+
+			"    list               Lists groups\n"+
+			"  users                Lists users\n"+
+			"    create <username>  Creates a user\n"+
+			"      [--admin]        Grants admin rights\n"+
+			"    delete <name>      Deletes a user\n\n",
+		output,
+	)
+}
+
+func TestValuesBind(t *testing.T) {
+	cl := NewCommandLine()
+
+	type userArgs struct {
+		Create   bool     `cmdline:"--create"`
+		Name     string   `cmdline:"name"`
+		Count    int64    `cmdline:"count"`
+		Ratio    float32  `cmdline:"ratio"`
+		Tags     []string `cmdline:"tag"`
+		Ids      []uint16 `cmdline:"id"`
+		Level    int      `cmdline:"level"`
+		Ignored  string   `cmdline:"-"`
+		Untagged string
+	}
+
+	var bound userArgs
+	cl.RegisterCommand(
+		func(values Values) error {
+			values["level"] = "7"
+			return values.Bind(&bound)
+		},
+		"users",
+		"[--create <string-name>]",
+		"[--count <int-count>]",
+		"[--ratio <float64-ratio>]",
+		"*[--tag <string-tag>]",
+		"*[--id <int-id>]",
+	)
+
+	args := []string{"users", "--create", "bob", "--count", "12", "--ratio", "0.5", "--tag", "a", "--tag", "b", "--id", "3", "--id", "4"}
+	err := cl.Process(args)
+	expectError(t, nil, err)
+	expectString(t, "{true bob 12 0.5 [a b] [3 4] 7  }", fmt.Sprint(bound))
+
+	type badArgs struct {
+		Name int `cmdline:"name"`
+	}
+	var bad badArgs
+	err = Values{"name": "bob"}.Bind(&bad)
+	expectErrorContainingText(t, "cannot bind \"name\" to field Name", err)
+
+	err = Values{}.Bind(bad)
+	expectError(t, fmt.Errorf("bind target must be a pointer to a struct"), err)
+
+	type narrowArgs struct {
+		Small int8    `cmdline:"small"`
+		Whole int     `cmdline:"whole"`
+		Size  uint    `cmdline:"size"`
+		Ratio float32 `cmdline:"ratio"`
+	}
+	var narrow narrowArgs
+	err = Values{"small": 100, "whole": 300, "size": 5, "ratio": 0.25}.Bind(&narrow)
+	expectError(t, nil, err)
+	expectString(t, "{100 300 5 0.25}", fmt.Sprint(narrow))
+
+	err = Values{"small": 300}.Bind(&narrow)
+	expectErrorContainingText(t, "cannot bind \"small\" to field Small: value 300 overflows int8", err)
+
+	err = Values{"whole": 2.9}.Bind(&narrow)
+	expectErrorContainingText(t, "cannot bind \"whole\" to field Whole: type float64 is not convertible to int", err)
+
+	err = Values{"size": -1}.Bind(&narrow)
+	expectErrorContainingText(t, "cannot bind \"size\" to field Size: value -1 overflows uint", err)
+
+	err = Values{"ratio": 1e300}.Bind(&narrow)
+	expectErrorContainingText(t, "cannot bind \"ratio\" to field Ratio: value 1e+300 overflows float32", err)
+}
+
+func TestUnrecognizedCommandSuggestion(t *testing.T) {
+	cl := NewCommandLine()
+
+	cl.RegisterCommand(
+		func(values Values) error {
+			return nil
+		},
+		"users",
+	)
+
+	cl.RegisterCommand(
+		func(values Values) error {
+			return nil
+		},
+		"groups|create <string-name>",
+	)
+
+	cl.RegisterGlobalOption(
+		func(values Values) error {
+			return nil
+		},
+		"--verbose",
+	)
+
+	err := cl.Process([]string{"usrs"})
+	expectError(t, NewCommandLineError("Unrecognized command: usrs; did you mean 'users'?"), err)
+
+	var cle *CommandLineError
+	if !errors.As(err, &cle) {
+		t.Fatal("expected a CommandLineError")
+	}
+	expectString(t, "users", cle.Suggestion())
+
+	err = cl.Process([]string{"groups", "creat", "admins"})
+	expectError(t, NewCommandLineError("Unrecognized command: groups; did you mean 'groups create'?"), err)
+
+	err = cl.Process([]string{"-verbose"})
+	expectError(t, NewCommandLineError("Unrecognized command: -verbose; did you mean '--verbose'?"), err)
+
+	err = cl.Process([]string{"xyz"})
+	expectError(t, NewCommandLineError("Unrecognized command: xyz"), err)
+	errors.As(err, &cle)
+	expectString(t, "", cle.Suggestion())
+}
+
+func TestNegativeNumberValues(t *testing.T) {
+	cl := NewCommandLine()
+
+	var a, b int
+	var offset float64
+	cl.RegisterCommand(
+		func(values Values) error {
+			a = values["a"].(int)
+			b = values["b"].(int)
+			offset = values["offset"].(float64)
+			return nil
+		},
+		"add <int-a> <int-b>",
+		"[--offset <float64-offset>]",
+	)
+
+	var nums []int
+	cl.RegisterCommand(
+		func(values Values) error {
+			nums = values["nums"].([]int)
+			return nil
+		},
+		"sum *<int-nums>",
+	)
+
+	cl.RegisterCommand(
+		func(values Values) error {
+			return nil
+		},
+		"echo <string-text>",
+	)
+
+	err := cl.Process([]string{"add", "-5", "3", "--offset", "-.5"})
+	expectError(t, nil, err)
+	expectValue(t, -5, a)
+	expectValue(t, 3, b)
+	expectValue(t, -0.5, offset)
+
+	err = cl.Process([]string{"sum", "1", "-2", "-3"})
+	expectError(t, nil, err)
+	expectString(t, "[1 -2 -3]", fmt.Sprint(nums))
+
+	err = cl.Process([]string{"add", "-x", "3"})
+	expectError(t, NewCommandLineError("Required value a is missing"), err)
+
+	err = cl.Process([]string{"echo", "-5"})
+	expectError(t, NewCommandLineError("Required value text is missing"), err)
+}
+
+func TestRestArgs(t *testing.T) {
+	cl := NewCommandLine()
+
+	var rest any
+	var name string
+	cl.RegisterCommand(
+		func(values Values) error {
+			rest = values[RestArgs]
+			name = values["name"].(string)
+			return nil
+		},
+		"run <string-name>",
+		"[--verbose]",
+	)
+
+	globalSeen := false
+	cl.RegisterGlobalOption(
+		func(values Values) error {
+			globalSeen = true
+			return nil
+		},
+		"--env",
+	)
+
+	err := cl.Process([]string{"run", "tool", "--", "actual-command", "--with-flags", "--env", "--"})
+	expectError(t, nil, err)
+	expectString(t, "tool", name)
+	expectString(t, "[actual-command --with-flags --env --]", fmt.Sprint(rest))
+	expectBool(t, false, globalSeen)
+	expectString(t, "run", cl.PrimaryCommand([]string{"run", "--", "x"}))
+	expectString(t, "", cl.PrimaryCommand([]string{"--", "run"}))
+
+	err = cl.Process([]string{"run", "tool", "--"})
+	expectError(t, nil, err)
+	expectValue(t, 0, len(rest.([]string)))
+
+	err = cl.Process([]string{"run", "tool"})
+	expectError(t, nil, err)
+	expectValue(t, nil, rest)
+
+	err = cl.Process([]string{"run", "--", "tool"})
+	expectError(t, NewCommandLineError("Required value name is missing"), err)
+}
+
+func TestVariadicPositionalArgs(t *testing.T) {
+	cl := NewCommandLine()
+
+	var srcs []string
+	var dst string
+	cl.RegisterCommand(
+		func(values Values) error {
+			srcs = values["src"].([]string)
+			dst = values["dst"].(string)
+			return nil
+		},
+		"copy <string-src>... <string-dst>?Copies files",
+		"[--force]",
+	)
+
+	err := cl.Process([]string{"copy", "a", "b", "c", "dir", "--force"})
+	expectError(t, nil, err)
+	expectString(t, "[a b c]", fmt.Sprint(srcs))
+	expectString(t, "dir", dst)
+
+	err = cl.Process([]string{"copy", "a", "dir"})
+	expectError(t, nil, err)
+	expectString(t, "[a]", fmt.Sprint(srcs))
+	expectString(t, "dir", dst)
+
+	err = cl.Process([]string{"copy", "a"})
+	expectError(t, NewCommandLineError("Required value dst is missing"), err)
+
+	output := captureStdout(
+		t,
+		func() {
+			err := cl.PrintCommand("copy")
+			expectError(t, nil, err)
+		},
+	)
+	expectString(t, "copy <src>... <dst>  Copies files\n  [--force]\n", output)
+
+	cl = NewCommandLine()
+
+	var files []string
+	cl.RegisterCommand(
+		func(values Values) error {
+			files = values["files"].([]string)
+			return nil
+		},
+		"*~ <string-files>",
+	)
+
+	err = cl.Process([]string{"one", "two", "three"})
+	expectError(t, nil, err)
+	expectString(t, "[one two three]", fmt.Sprint(files))
+
+	expectPanic(t, func() {
+		NewCommandLine().RegisterCommand(
+			func(values Values) error {
+				return nil
+			},
+			"*~",
+		)
+	})
+}
+
+func TestAsteriskPositionalArgs(t *testing.T) {
+	cl := NewCommandLine()
+
+	var srcs []string
+	cl.RegisterCommand(
+		func(values Values) error {
+			srcs = values["src"].([]string)
+			return nil
+		},
+		"copy *<string-src> <string-dst>?Copies files",
+	)
+
+	// unlike an ellipsis, an asterisk takes the rest of the values
+	err := cl.Process([]string{"copy", "a", "b", "dir"})
+	expectError(t, NewCommandLineError("Required value dst is missing"), err)
+
+	cl = NewCommandLine()
+	cl.RegisterCommand(
+		func(values Values) error {
+			srcs = values["src"].([]string)
+			return nil
+		},
+		"copy *<string-src>?Copies files",
+	)
+
+	err = cl.Process([]string{"copy", "a", "b", "dir"})
+	expectError(t, nil, err)
+	expectString(t, "[a b dir]", fmt.Sprint(srcs))
+
+	output := captureStdout(
+		t,
+		func() {
+			err := cl.PrintCommand("copy")
+			expectError(t, nil, err)
+		},
+	)
+	expectString(t, "copy <src>  Copies files\n", output)
+}
+
+func TestDescribe(t *testing.T) {
+	cl := NewCommandLine()
+
+	cl.RegisterGlobalOption(
+		func(values Values) error {
+			return nil
+		},
+		"--env:<string-env>?Selects the environment",
+	)
+
+	cl.RegisterCommand(
+		func(values Values) error {
+			return nil
+		},
+		"users <string-name>?Performs operations on a user",
+		"[--create]?Creates a user",
+		"*[--tag:<string-tag>[,<int-weight>]]",
+	)
+
+	var sb strings.Builder
+	enc := json.NewEncoder(&sb)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(cl.Describe())
+	expectError(t, nil, err)
+	expectString(
+		t,
+		`{"global_options":[{"name":"--env","spec":"--env:<string-env>?Selects the environment","help":"Selects the environment","values":[{"name":"env","type":"string","default":""}]}],`+
+			`"commands":[{"name":"users","spec":"users <string-name>?Performs operations on a user","help":"Performs operations on a user","values":[{"name":"name","type":"string","default":""}],`+
+			`"options":[{"name":"--create","spec":"[--create]?Creates a user","optional":true,"help":"Creates a user"},`+
+			`{"name":"--tag","spec":"*[--tag:<string-tag>[,<int-weight>]]","optional":true,"multi":true,"values":[{"name":"tag","type":"string","default":""},{"name":"weight","type":"int","optional":true,"default":0}]}]}]}`+"\n",
+		sb.String(),
+	)
+}
+
+func TestValuesAccessors(t *testing.T) {
+	cl := NewCommandLine()
+
+	var seen Values
+	cl.RegisterCommand(
+		func(values Values) error {
+			seen = values
+			return nil
+		},
+		"run <string-name>",
+		"[--count <int-count>]",
+		"[--ratio <float64-ratio>]",
+		"[--wait <duration-wait>]",
+		"*[--tag <string-tag>]",
+		"*[--id <int-id>]",
+	)
+
+	err := cl.Process([]string{"run", "job", "--count", "3", "--ratio", "1.5", "--wait", "2s", "--tag", "a", "--id", "7", "--id", "8"})
+	expectError(t, nil, err)
+
+	expectString(t, "job", seen.String("name"))
+	expectValue(t, 3, seen.Int("count"))
+	expectValue(t, 1.5, seen.Float64("ratio"))
+	expectValue(t, 2*time.Second, seen.Duration("wait"))
+	expectBool(t, true, seen.Bool("--count"))
+	expectString(t, "[a]", fmt.Sprint(seen.StringSlice("tag")))
+	expectString(t, "[7 8]", fmt.Sprint(seen.IntSlice("id")))
+
+	// zero value fallback
+	expectString(t, "", seen.String("count"))
+	expectValue(t, 0, seen.Int("missing"))
+	expectBool(t, false, seen.Bool("name"))
+	expectBool(t, true, seen.StringSlice("id") == nil)
+
+	_, ok := seen.IntOk("name")
+	expectBool(t, false, ok)
+	count, ok := seen.IntOk("count")
+	expectBool(t, true, ok)
+	expectValue(t, 3, count)
+	_, ok = seen.StringOk("missing")
+	expectBool(t, false, ok)
+}
+
+func TestOptionConstraints(t *testing.T) {
+	cl := NewCommandLine()
+
+	cl.RegisterCommand(
+		func(values Values) error {
+			return nil
+		},
+		"users?Performs operations on a user",
+		"[--create <string-createUser>]?Creates a user",
+		"[--delete <string-deleteUser>]?Deletes a user",
+		"[--list]?List users",
+		"[--cert <path-cert>]?TLS certificate",
+		"[--key <path-key>]?TLS key",
+		"[--ca <path-ca>]?Certificate authority",
+		"[--json]?JSON output",
+		"[--table]?Table output",
+	)
+
+	cl.RequireAtLeastOneOf("users", "--create", "--delete", "--list")
+	cl.RequireTogether("users", "--cert", "--key")
+	cl.Requires("users", "--cert", "--ca")
+	cl.Conflicts("users", "--json", "--table", "--list")
+
+	err := cl.Process([]string{"users"})
+	expectError(t, NewCommandLineError("At least one of --create, --delete, --list is required"), err)
+
+	err = cl.Process([]string{"users", "--list"})
+	expectError(t, nil, err)
+
+	err = cl.Process([]string{"users", "--list", "--cert", "a.pem"})
+	expectError(t, NewCommandLineError("Arguments --cert, --key must be specified together"), err)
+
+	err = cl.Process([]string{"users", "--list", "--cert", "a.pem", "--key", "a.key"})
+	expectError(t, NewCommandLineError("Argument --cert requires --ca"), err)
+
+	err = cl.Process([]string{"users", "--list", "--cert", "a.pem", "--key", "a.key", "--ca", "ca.pem"})
+	expectError(t, nil, err)
+
+	err = cl.Process([]string{"users", "--create", "bob", "--json", "--table"})
+	expectError(t, NewCommandLineError("Arguments --json, --table cannot be used together"), err)
+	expectBool(t, true, errors.Is(err, ErrConstraintViolation))
+
+	err = cl.Process([]string{"users", "--create", "bob", "--json"})
+	expectError(t, nil, err)
+
+	output := captureStdout(
+		t,
+		func() {
+			err := cl.PrintCommand("users")
+			expectError(t, nil, err)
+		},
+	)
+
+	expectString(
+		t,
+		"users                      Performs operations on a user\n"+
+			"  [--create <createUser>]  Creates a user\n"+
+			"  [--delete <deleteUser>]  Deletes a user\n"+
+			"  [--list]                 List users\n"+
+			"  [--cert <cert>]          TLS certificate\n"+
+			"  [--key <key>]            TLS key\n"+
+			"  [--ca <ca>]              Certificate authority\n"+
+			"  [--json]                 JSON output\n"+
+			"  [--table]                Table output\n"+
+			"  Requires at least one of: --create, --delete, --list\n"+
+			"  Use together: --cert, --key\n"+
+			"  --cert requires: --ca\n"+
+			"  Use only one of: --json, --table, --list\n",
+		output,
+	)
+
+	expectPanic(t, func() {
+		cl.RequireTogether("groups", "--cert", "--key")
+	})
+
+	expectPanic(t, func() {
+		cl.RequireTogether("users", "--cert", "--missing")
+	})
+}
+
+func TestSetOutput(t *testing.T) {
+	cl := NewCommandLine()
+
+	cl.RegisterCommand(
+		func(values Values) error {
+			return nil
+		},
+		"test?This is help for the test command",
+		"[--opt]?An option",
+	)
+
+	var sb strings.Builder
+	cl.SetOutput(&sb)
+
+	output := captureStdout(
+		t,
+		func() {
+			args := []string{"--help"}
+			cl.Help(nil, "unit-test", args)
+			err := cl.PrintCommand("test")
+			expectError(t, nil, err)
+		},
+	)
+
+	expectString(t, "", output)
+	expectString(
+		t,
+		"Command Options:\n\n  test       This is help for the test command\n    [--opt]  An option\n\n"+
+			"test       This is help for the test command\n  [--opt]  An option\n",
+		sb.String(),
+	)
+
+	cl.SetOutput(nil)
+	output = captureStdout(
+		t,
+		func() {
+			cl.PrintCommands("", false)
+		},
+	)
+	expectString(t, "Command Options:\n\n  test       This is help for the test command\n    [--opt]  An option\n\n", output)
+}
+
+func TestSetVersion(t *testing.T) {
+	cl := NewCommandLine()
+
+	executed := false
+	cl.RegisterCommand(
+		func(values Values) error {
+			executed = true
+			return nil
+		},
+		"test",
+	)
+
+	cl.SetVersion("1.2.3", "abc123", "2024-03-04")
+
+	output := captureStdout(
+		t,
+		func() {
+			args := []string{"--version", "test"}
+			err := cl.Process(args)
+			expectError(t, ErrVersionShown, err)
+			cl.Help(err, "unit-test", args)
+		},
+	)
+
+	expectBool(t, false, executed)
+	expectString(t, "Version: 1.2.3\nCommit:  abc123\nDate:    2024-03-04\n", output)
+
+	cl.SetVersion("1.2.4", "", "")
+	cl.SetVersionTemplate("unit-test v{{.Version}}")
+
+	output = captureStdout(
+		t,
+		func() {
+			err := cl.Process([]string{"--version"})
+			expectError(t, ErrVersionShown, err)
+		},
+	)
+	expectString(t, "unit-test v1.2.4\n", output)
+
+	err := cl.Process([]string{"test"})
+	expectError(t, nil, err)
+	expectBool(t, true, executed)
+
+	expectPanic(t, func() {
+		cl.SetVersionTemplate("{{.Version")
+	})
+}
+
+func TestAutoHelp(t *testing.T) {
+	cl := NewCommandLine()
+
+	executed := false
+	cl.RegisterCommand(
+		func(values Values) error {
+			executed = true
+			return nil
+		},
+		"users?Performs operations on a user",
+		"[--list]?List users",
+	)
+
+	cl.RegisterCommand(
+		func(values Values) error {
+			executed = true
+			return nil
+		},
+		"groups?Performs operations on a group",
+		"[-h]?Hides the group",
+	)
+
+	// not enabled
+	err := cl.Process([]string{"--help"})
+	expectError(t, NewCommandLineError("Unrecognized command: --help"), err)
+
+	cl.EnableAutoHelp()
+	cl.SetAppName("unit-test")
+
+	output := captureStdout(
+		t,
+		func() {
+			err := cl.Process([]string{"--help"})
+			expectError(t, ErrHelpShown, err)
+			cl.Help(err, "unit-test", []string{"--help"})
+		},
+	)
+	expectString(
+		t,
+		"Usage: unit-test <command> <options>\n\nAll Commands:\n\n"+
+			"  groups      Performs operations on a group\n    [-h]      Hides the group\n"+
+			"  users       Performs operations on a user\n    [--list]  List users\n\n",
+		output,
+	)
+
+	output = captureStdout(
+		t,
+		func() {
+			err := cl.Process([]string{"help", "list"})
+			expectError(t, ErrHelpShown, err)
+		},
+	)
+	expectString(t, "Matching Commands:\n\n  users       Performs operations on a user\n    [--list]  List users\n\n", output)
+
+	output = captureStdout(
+		t,
+		func() {
+			err := cl.Process([]string{"users", "-h"})
+			expectError(t, ErrHelpShown, err)
+		},
+	)
+	expectString(t, "users       Performs operations on a user\n  [--list]  List users\n", output)
+
+	err = cl.Process([]string{"groups", "-h"})
+	expectError(t, nil, err)
+	expectBool(t, true, executed)
+}
+
+func TestOptionAliases(t *testing.T) {
+	cl := NewCommandLine()
+
+	var seen Values
+	cl.RegisterCommand(
+		func(values Values) error {
+			seen = values
+			return nil
+		},
+		"build?Builds the project",
+		"[-v|--verbose]?Prints more detail",
+		"[-o|--output <path-out>]?The output file",
+	)
+
+	verbose := false
+	cl.RegisterGlobalOption(
+		func(values Values) error {
+			verbose = values["--debug"].(bool)
+			return nil
+		},
+		"--debug|-d",
+	)
+
+	err := cl.Process([]string{"-d", "build", "-v", "-o", "out.bin"})
+	expectError(t, nil, err)
+	expectBool(t, true, verbose)
+	expectBool(t, true, seen.Bool("-v"))
+	expectBool(t, true, seen.Bool("-o"))
+	expectBool(t, true, strings.HasSuffix(seen.String("out"), "out.bin"))
+
+	err = cl.Process([]string{"build", "--verbose"})
+	expectError(t, nil, err)
+	expectBool(t, true, seen.Bool("-v"))
+	expectBool(t, false, seen.Bool("-o"))
+	_, exists := seen["--verbose"]
+	expectBool(t, false, exists)
+
+	output := captureStdout(
+		t,
+		func() {
+			err := cl.PrintCommand("build")
+			expectError(t, nil, err)
+		},
+	)
+	expectString(t, "build                  Builds the project\n  [-v|--verbose]       Prints more detail\n  [-o|--output <out>]  The output file\n", output)
+
+	expectPanic(t, func() {
+		cl.RegisterCommand(
+			func(values Values) error {
+				return nil
+			},
+			"test",
+			"[-a|--all]",
+			"[--any|-a]",
+		)
+	})
+
+	expectPanic(t, func() {
+		cl.RegisterCommand(
+			func(values Values) error {
+				return nil
+			},
+			"test",
+			"[-a|]",
+		)
+	})
+}
+
+func TestShortFlagClustering(t *testing.T) {
+	cl := NewCommandLine()
+
+	var seen Values
+	cl.RegisterCommand(
+		func(values Values) error {
+			seen = values
+			return nil
+		},
+		"tar",
+		"[-x]?Extract",
+		"[-v|--verbose]?Verbose",
+		"[-z]?Compress",
+		"[-f <path-file>]?Archive file",
+		"[-xf]?Not a cluster",
+	)
+
+	err := cl.Process([]string{"tar", "-xvz"})
+	expectError(t, NewCommandLineError("Unrecognized command argument: -xvz; valid options: -x, -v, -z, -f, -xf"), err)
+
+	cl.EnableShortFlagClustering()
+
+	err = cl.Process([]string{"tar", "-xvz"})
+	expectError(t, nil, err)
+	expectBool(t, true, seen.Bool("-x"))
+	expectBool(t, true, seen.Bool("-v"))
+	expectBool(t, true, seen.Bool("-z"))
+	expectBool(t, false, seen.Bool("-f"))
+
+	err = cl.Process([]string{"tar", "-xf"})
+	expectError(t, nil, err)
+	expectBool(t, true, seen.Bool("-xf"))
+	expectBool(t, false, seen.Bool("-x"))
+
+	// -f takes a value, so it can't be clustered
+	err = cl.Process([]string{"tar", "-zf", "a.tgz"})
+	expectError(t, NewCommandLineError("Unrecognized command argument: -zf; did you mean '-f'?"), err)
+}
+
+func TestEqualsValues(t *testing.T) {
+	cl := NewCommandLine()
+
+	var seen Values
+	var level int
+	cl.RegisterGlobalOption(
+		func(values Values) error {
+			level = values.Int("level")
+			return nil
+		},
+		"--log <int-level>",
+	)
+	cl.RegisterCommand(
+		func(values Values) error {
+			seen = values
+			return nil
+		},
+		"build",
+		"[--out:<string-path>]",
+		"[--tag <string-name>]",
+		"[--define <string-def>]",
+		"[--pair <int-x> <int-y>]",
+	)
+
+	err := cl.Process([]string{"build", "--out=a.bin", "--tag=v1", "--log=3"})
+	expectError(t, nil, err)
+	expectString(t, "a.bin", seen.String("path"))
+	expectString(t, "v1", seen.String("name"))
+	expectValue(t, 3, level)
+
+	// only the first equals sign separates the value
+	err = cl.Process([]string{"build", "--define=x=1"})
+	expectError(t, nil, err)
+	expectString(t, "x=1", seen.String("def"))
+
+	err = cl.Process([]string{"build", "--pair=4", "5"})
+	expectError(t, nil, err)
+	expectValue(t, 4, seen.Int("x"))
+	expectValue(t, 5, seen.Int("y"))
+
+	err = cl.Process([]string{"build", "--bogus=1"})
+	expectError(t, NewCommandLineError("Unrecognized command argument: --bogus=1; valid options: --out, --tag, --define, --pair"), err)
+
+	expectString(t, "build", cl.PrimaryCommand([]string{"--log=3", "build"}))
+}
+
+func TestSplitArgs(t *testing.T) {
+	cases := []struct {
+		raw      string
+		expected []string
+	}{
+		{"", []string{}},
+		{"   ", []string{}},
+		{"a b  c", []string{"a", "b", "c"}},
+		{"\ta\n b ", []string{"a", "b"}},
+		{`say "hello world"`, []string{"say", "hello world"}},
+		{`say 'it''s'`, []string{"say", "its"}},
+		{`say 'a "b" \c'`, []string{"say", `a "b" \c`}},
+		{`say "a \"b\" \c \\"`, []string{"say", `a "b" \c \`}},
+		{`a\ b c`, []string{"a b", "c"}},
+		{`--name="x y"`, []string{"--name=x y"}},
+		{`"" ''`, []string{"", ""}},
+	}
+
+	for _, c := range cases {
+		args, err := SplitArgs(c.raw)
+		expectError(t, nil, err)
+		expectString(t, fmt.Sprintf("%q", c.expected), fmt.Sprintf("%q", args))
+	}
+
+	_, err := SplitArgs(`say "hello`)
+	expectError(t, NewCommandLineError("Unterminated \" quote in command line"), err)
+
+	_, err = SplitArgs(`say 'hello`)
+	expectError(t, NewCommandLineError("Unterminated ' quote in command line"), err)
+
+	_, err = SplitArgs(`say hello\`)
+	expectError(t, NewCommandLineError("Unterminated escape at end of command line"), err)
+}
+
+func TestProcessLine(t *testing.T) {
+	cl := NewCommandLine()
+
+	var seen Values
+	cl.RegisterCommand(
+		func(values Values) error {
+			seen = values
+			return nil
+		},
+		"say <string-text>",
+		"[--to <string-name>]",
+	)
+
+	err := cl.ProcessLine(`say "hello world" --to 'Ann Lee'`)
+	expectError(t, nil, err)
+	expectString(t, "hello world", seen.String("text"))
+	expectString(t, "Ann Lee", seen.String("name"))
+
+	err = cl.ProcessLine(`say "hello`)
+	expectError(t, NewCommandLineError("Unterminated \" quote in command line"), err)
+}
+
+func TestFileType(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "cert.pem")
+	err := os.WriteFile(certPath, []byte("CERT DATA"), 0o600)
+	expectError(t, nil, err)
+
+	cl := NewCommandLine()
+
+	var seen Values
+	cl.RegisterCommand(
+		func(values Values) error {
+			seen = values
+			return nil
+		},
+		"connect",
+		"[--cert-file <file-cert>]",
+		"[--body <file-body>]",
+	)
+
+	err = cl.Process([]string{"connect", "--cert-file", certPath, "--body", "@" + certPath})
+	expectError(t, nil, err)
+	expectString(t, "CERT DATA", string(seen.Bytes("cert")))
+	expectString(t, "CERT DATA", string(seen.Bytes("body")))
+
+	err = cl.Process([]string{"connect"})
+	expectError(t, nil, err)
+	expectBool(t, true, seen.Bytes("cert") == nil)
+
+	err = cl.Process([]string{"connect", "--cert-file", filepath.Join(dir, "missing.pem")})
+	expectBool(t, true, errors.Is(err, os.ErrNotExist))
+
+	cl.SetMaxFileSize(4)
+	err = cl.Process([]string{"connect", "--cert-file", certPath})
+	expectError(t, NewCommandLineError("File %s exceeds the 4 byte size limit", certPath), err)
+
+	ccl := NewCustomTypesCommandLine(&testOptionTypes{})
+	expectPanicError(t, fmt.Errorf("argument error: the max file size applies only to the default option types"), func() {
+		ccl.SetMaxFileSize(4)
+	})
+}
+
+func TestParseSize(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected int64
+	}{
+		{"0", 0},
+		{"512", 512},
+		{"512b", 512},
+		{"10k", 10240},
+		{"10K", 10240},
+		{"10kb", 10000},
+		{"4MiB", 4 * 1024 * 1024},
+		{"4m", 4 * 1024 * 1024},
+		{"2GB", 2000000000},
+		{"1.5GiB", 1610612736},
+		{"1 TB", 1000000000000},
+		{"8p", 8 << 50},
+	}
+
+	for _, c := range cases {
+		size, err := ParseSize(c.input)
+		expectError(t, nil, err)
+		expectValue(t, c.expected, size)
+	}
+
+	for _, input := range []string{"", "k", "10x", "1..5k", "-5"} {
+		_, err := ParseSize(input)
+		expectError(t, NewCommandLineError("Invalid size: %s", input), err)
+	}
+
+	_, err := ParseSize("9000000p")
+	expectError(t, NewCommandLineError("Size out of range: 9000000p"), err)
+}
+
+func TestWideNumericTypes(t *testing.T) {
+	cl := NewCommandLine()
+
+	var seen Values
+	cl.RegisterCommand(
+		func(values Values) error {
+			seen = values
+			return nil
+		},
+		"alloc",
+		"[--count <uint-count>]",
+		"[--offset <int64-offset>]",
+		"[--max <size-max>]",
+		"[--chunks <size-chunk>...]",
+	)
+
+	err := cl.Process([]string{"alloc", "--count", "4000000000", "--offset", "-9000000000", "--max", "2GiB", "--chunks", "1k", "2k"})
+	expectError(t, nil, err)
+	expectValue(t, uint(4000000000), seen.Uint("count"))
+	expectValue(t, int64(-9000000000), seen.Int64("offset"))
+	expectValue(t, int64(2147483648), seen.Int64("max"))
+	expectString(t, "[1024 2048]", fmt.Sprint(seen["chunk"]))
+
+	err = cl.Process([]string{"alloc"})
+	expectError(t, nil, err)
+	expectValue(t, uint(0), seen.Uint("count"))
+	expectValue(t, int64(0), seen.Int64("max"))
+
+	err = cl.Process([]string{"alloc", "--count", "-1"})
+	expectError(t, NewCommandLineError("Required value count is missing"), err)
+
+	err = cl.Process([]string{"alloc", "--max", "lots"})
+	expectError(t, NewCommandLineError("Invalid size: lots"), err)
+}
+
+func TestAddValidator(t *testing.T) {
+	cl := NewCommandLine()
+
+	var seen Values
+	var level int
+	cl.RegisterGlobalOption(
+		func(values Values) error {
+			level = values.Int("level")
+			return nil
+		},
+		"--log <int-level>",
+	)
+	cl.RegisterCommand(
+		func(values Values) error {
+			seen = values
+			return nil
+		},
+		"serve",
+		"[--port <int-port>]",
+		"[--host <string-host>...]",
+	)
+	cl.RegisterCommand(
+		func(values Values) error {
+			seen = values
+			return nil
+		},
+		"connect <int-port>",
+	)
+
+	errPort := errors.New("port must be between 1 and 65535")
+	cl.AddValidator("port", func(value any) error {
+		port := value.(int)
+		if port < 1 || port > 65535 {
+			return errPort
+		}
+		return nil
+	})
+	cl.AddValidator("host", func(value any) error {
+		if strings.Contains(value.(string), " ") {
+			return NewCommandLineError("Invalid host: %s", value)
+		}
+		return nil
+	})
+	cl.AddValidator("level", func(value any) error {
+		if value.(int) > 3 {
+			return NewCommandLineError("Log level must be at most 3")
+		}
+		return nil
+	})
+
+	err := cl.Process([]string{"serve", "--port", "8080", "--host", "a", "b", "--log", "2"})
+	expectError(t, nil, err)
+	expectValue(t, 8080, seen.Int("port"))
+	expectString(t, "[a b]", fmt.Sprint(seen.StringSlice("host")))
+	expectValue(t, 2, level)
+
+	// defaults aren't validated
+	err = cl.Process([]string{"serve"})
+	expectError(t, nil, err)
+
+	err = cl.Process([]string{"serve", "--port", "0"})
+	expectBool(t, true, errors.Is(err, errPort))
+
+	err = cl.Process([]string{"connect", "70000"})
+	expectBool(t, true, errors.Is(err, errPort))
+
+	err = cl.Process([]string{"serve", "--host", "a", "b c"})
+	expectError(t, NewCommandLineError("Invalid host: b c"), err)
+
+	err = cl.Process([]string{"serve", "--log", "4"})
+	expectError(t, NewCommandLineError("Log level must be at most 3"), err)
+
+	expectPanicError(t, fmt.Errorf("argument error: a validator requires a value name and a function"), func() {
+		cl.AddValidator("port", nil)
+	})
+}
+
+func TestValueRange(t *testing.T) {
+	cl := NewCommandLine()
+
+	var seen Values
+	cl.RegisterCommand(
+		func(values Values) error {
+			seen = values
+			return nil
+		},
+		"serve",
+		"[--port <int[1..65535]-port>]?Listen port",
+		"[--offset <int[-10..10]-offset>]",
+		"[--timeout <duration[1s..]-timeout>]",
+		"[--ratio <float64[..1]-ratio>]",
+		"[--weight <int[0..9]-weight>...]",
+	)
+
+	err := cl.Process([]string{"serve", "--port", "65535", "--offset", "-10", "--timeout", "5s", "--ratio", "0.5", "--weight", "0", "9"})
+	expectError(t, nil, err)
+	expectValue(t, 65535, seen.Int("port"))
+	expectValue(t, -10, seen.Int("offset"))
+	expectValue(t, 5*time.Second, seen.Duration("timeout"))
+
+	// defaults aren't range checked
+	err = cl.Process([]string{"serve"})
+	expectError(t, nil, err)
+	expectValue(t, 0, seen.Int("port"))
+
+	err = cl.Process([]string{"serve", "--port", "0"})
+	expectError(t, NewCommandLineError("Value port must be in the range 1 to 65535"), err)
+
+	err = cl.Process([]string{"serve", "--offset", "11"})
+	expectError(t, NewCommandLineError("Value offset must be in the range -10 to 10"), err)
+
+	err = cl.Process([]string{"serve", "--timeout", "500ms"})
+	expectError(t, NewCommandLineError("Value timeout must be at least 1s"), err)
+
+	err = cl.Process([]string{"serve", "--ratio", "1.5"})
+	expectError(t, NewCommandLineError("Value ratio must be at most 1"), err)
+
+	err = cl.Process([]string{"serve", "--weight", "3", "10"})
+	expectError(t, NewCommandLineError("Value weight must be in the range 0 to 9"), err)
+
+	out := captureStdout(t, func() {
+		cl.Help(nil, "test", []string{})
+	})
+	expectBool(t, true, strings.Contains(out, "[--port <port[1..65535]>]"))
+
+	registerPanic := func(spec string) func() {
+		return func() {
+			NewCommandLine().RegisterCommand(func(values Values) error { return nil }, "cmd", spec)
+		}
+	}
+
+	expectPanicError(t, &SpecError{Spec: "--port <int[1-65535]-port>", Column: 13, Expected: "range min..max"}, registerPanic("--port <int[1-65535]-port>"))
+	expectPanicError(t, &SpecError{Spec: "--port <int[a..b]-port>", Column: 13, Expected: "valid range bound"}, registerPanic("--port <int[a..b]-port>"))
+	expectPanicError(t, &SpecError{Spec: "--name <string[a..b]-name>", Column: 16, Expected: "numeric type for a range"}, registerPanic("--name <string[a..b]-name>"))
+	expectPanicError(t, &SpecError{Spec: "--n <int[9..1]-n>", Column: 10, Expected: "range min at or below max"}, registerPanic("--n <int[9..1]-n>"))
+	expectPanicError(t, &SpecError{Spec: "--n <int[1..9-n>", Column: 17, Expected: "']'"}, registerPanic("--n <int[1..9-n>"))
+}
+
+func TestValuePattern(t *testing.T) {
+	cl := NewCommandLine()
+
+	var seen Values
+	cl.RegisterCommand(
+		func(values Values) error {
+			seen = values
+			return nil
+		},
+		"create",
+		"--name <string/^[a-z0-9-]+$/-name>?Lowercase name",
+		"[--tag:<string/^v?[0-9]+$/-tag>]",
+		"[--label <string-label>...]",
+	)
+
+	err := cl.Process([]string{"create", "--name", "web-01", "--tag:v2", "--label", "a", "b"})
+	expectError(t, nil, err)
+	expectString(t, "web-01", seen.String("name"))
+	expectString(t, "v2", seen.String("tag"))
+
+	err = cl.Process([]string{"create", "--name", "Web"})
+	expectError(t, NewCommandLineError("Value name must match the pattern ^[a-z0-9-]+$"), err)
+
+	err = cl.Process([]string{"create", "--name", "web", "--tag:x"})
+	expectError(t, NewCommandLineError("Value tag must match the pattern ^v?[0-9]+$"), err)
+
+	cl.SetPattern("label", regexp.MustCompile(`^[a-z]$`))
+	err = cl.Process([]string{"create", "--name", "web", "--label", "a", "bc"})
+	expectError(t, NewCommandLineError("Value label must match the pattern ^[a-z]$"), err)
+
+	cl.SetPattern("label", nil)
+	err = cl.Process([]string{"create", "--name", "web", "--label", "a", "bc"})
+	expectError(t, nil, err)
+
+	desc := cl.Describe()
+	expectString(t, "Lowercase name", desc.Commands[0].Options[0].Help)
+	expectString(t, "^[a-z0-9-]+$", desc.Commands[0].Options[0].Values[0].Pattern)
+	expectString(t, "", desc.Commands[0].Options[1].Help)
+
+	expectPanicError(t, &SpecError{Spec: "--n <int/^[0-9]+$/-n>", Column: 9, Expected: "string type for a pattern"}, func() {
+		NewCommandLine().RegisterCommand(func(values Values) error { return nil }, "cmd", "--n <int/^[0-9]+$/-n>")
+	})
+	expectPanicError(t, &SpecError{Spec: "--n <string/a(/-n>", Column: 13, Expected: "valid pattern"}, func() {
+		NewCommandLine().RegisterCommand(func(values Values) error { return nil }, "cmd", "--n <string/a(/-n>")
+	})
+}
+
+func TestPathExistenceTypes(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "input.txt")
+	err := os.WriteFile(filePath, []byte("x"), 0o600)
+	expectError(t, nil, err)
+	missingPath := filepath.Join(dir, "missing.txt")
+
+	cl := NewCommandLine()
+
+	var seen Values
+	cl.RegisterCommand(
+		func(values Values) error {
+			seen = values
+			return nil
+		},
+		"convert",
+		"[--in <existingfile-in>]",
+		"[--work <existingdir-work>]",
+		"[--out <newpath-out>]",
+	)
+
+	err = cl.Process([]string{"convert", "--in", filePath, "--work", dir, "--out", missingPath})
+	expectError(t, nil, err)
+	expectString(t, filePath, seen.String("in"))
+	expectString(t, dir, seen.String("work"))
+	expectString(t, missingPath, seen.String("out"))
+
+	err = cl.Process([]string{"convert", "--in", missingPath})
+	expectError(t, NewCommandLineError("File %s does not exist", missingPath), err)
+
+	err = cl.Process([]string{"convert", "--in", dir})
+	expectError(t, NewCommandLineError("%s is a directory, not a file", dir), err)
+
+	err = cl.Process([]string{"convert", "--work", missingPath})
+	expectError(t, NewCommandLineError("Directory %s does not exist", missingPath), err)
+
+	err = cl.Process([]string{"convert", "--work", filePath})
+	expectError(t, NewCommandLineError("%s is not a directory", filePath), err)
+
+	err = cl.Process([]string{"convert", "--out", filePath})
+	expectError(t, NewCommandLineError("%s already exists", filePath), err)
+
+	nestedPath := filepath.Join(missingPath, "out.txt")
+	err = cl.Process([]string{"convert", "--out", nestedPath})
+	expectError(t, NewCommandLineError("The directory for %s does not exist", nestedPath), err)
+}
+
+func TestUsageAndExamples(t *testing.T) {
+	cl := NewCommandLine()
+
+	cl.RegisterCommand(
+		func(values Values) error {
+			return nil
+		},
+		"users?Manages users",
+		"[--create <string-name>]?Creates a user",
+	)
+	cl.RegisterCommand(
+		func(values Values) error {
+			return nil
+		},
+		"status",
+	)
+
+	cl.SetUsage("mytool [global options] <command> [args]")
+	cl.AddExample("users", "mytool users --create bob", "Create a user")
+	cl.AddExample("users", "mytool users", "")
+	cl.AddExample("status", "mytool status", "Show the status")
+
+	output := captureStdout(t, func() {
+		cl.Help(nil, "mytool", []string{})
+	})
+	expectString(
+		t,
+		"Usage: mytool [global options] <command> [args]\n\nAll Commands:\n\n"+
+			"  status\n    Examples:\n      mytool status\n        Show the status\n"+
+			"  users                Manages users\n    [--create <name>]  Creates a user\n"+
+			"    Examples:\n      mytool users --create bob\n        Create a user\n      mytool users\n\n",
+		output,
+	)
+
+	output = captureStdout(t, func() {
+		err := cl.PrintCommand("status")
+		expectError(t, nil, err)
+	})
+	expectString(t, "status\n  Examples:\n    mytool status\n      Show the status\n", output)
+
+	expectPanicError(t, fmt.Errorf("argument error: command \"bogus\" is not registered"), func() {
+		cl.AddExample("bogus", "mytool bogus", "")
+	})
+}
+
+func TestCommandGroups(t *testing.T) {
+	cl := NewCommandLine()
+
+	handler := func(values Values) error {
+		return nil
+	}
+	cl.RegisterCommand(handler, "users?Manages users", "[--create <string-name>]?Creates a user")
+	cl.RegisterCommand(handler, "query?Runs a query")
+	cl.RegisterCommand(handler, "roles?Manages roles")
+	cl.RegisterCommand(handler, "version?Prints the version")
+
+	cl.SetCommandGroup("users", "Administration")
+	cl.SetCommandGroup("query", "Query")
+	cl.SetCommandGroup("roles", "Administration")
+
+	output := captureStdout(t, func() {
+		cl.PrintCommands("", false)
+	})
+	expectString(
+		t,
+		"Administration Commands:\n\n"+
+			"  roles                Manages roles\n"+
+			"  users                Manages users\n"+
+			"    [--create <name>]  Creates a user\n\n"+
+			"Query Commands:\n\n"+
+			"  query                Runs a query\n\n"+
+			"Other Commands:\n\n"+
+			"  version              Prints the version\n\n",
+		output,
+	)
+
+	output = captureStdout(t, func() {
+		cl.PrintCommands("role", false)
+	})
+	expectString(t, "Matching Administration Commands:\n\n  roles  Manages roles\n\n", output)
+
+	expectPanicError(t, fmt.Errorf("argument error: command \"bogus\" is not registered"), func() {
+		cl.SetCommandGroup("bogus", "Query")
+	})
+}
+
+func TestHelpOrder(t *testing.T) {
+	cl := NewCommandLine()
+
+	handler := func(values Values) error {
+		return nil
+	}
+	cl.RegisterGlobalOption(handler, "--verbose?More output")
+	cl.RegisterGlobalOption(handler, "--config <path-config>?Config file")
+	cl.RegisterCommand(handler, "users?Manages users", "[--list]?Lists users", "[--add]?Adds a user")
+	cl.RegisterCommand(handler, "query?Runs a query")
+
+	output := captureStdout(t, func() {
+		cl.PrintCommands("", true)
+	})
+	expectString(
+		t,
+		"Global Options:\n\n"+
+			"  --config <config>  Config file\n"+
+			"  --verbose          More output\n\n"+
+			"All Commands:\n\n"+
+			"  query              Runs a query\n"+
+			"  users              Manages users\n"+
+			"    [--list]         Lists users\n"+
+			"    [--add]          Adds a user\n\n",
+		output,
+	)
+
+	cl.SetHelpOrder(RegistrationOrder)
+
+	output = captureStdout(t, func() {
+		cl.PrintCommands("", true)
+	})
+	expectString(
+		t,
+		"Global Options:\n\n"+
+			"  --verbose          More output\n"+
+			"  --config <config>  Config file\n\n"+
+			"All Commands:\n\n"+
+			"  users              Manages users\n"+
+			"    [--list]         Lists users\n"+
+			"    [--add]          Adds a user\n"+
+			"  query              Runs a query\n\n",
+		output,
+	)
+}
+
+type testTerminal struct {
+	width    int
+	password string
+	noVT     bool
+}
+
+func (tt *testTerminal) IsTerminal(fd int) bool {
+	return true
+}
+
+func (tt *testTerminal) GetSize(fd int) (int, int, error) {
+	return tt.width, 24, nil
+}
+
+func (tt *testTerminal) ReadPassword(fd int) ([]byte, error) {
+	return []byte(tt.password), nil
+}
+
+func (tt *testTerminal) EnableVirtualTerminal(fd int) bool {
+	return !tt.noVT
+}
+
+func TestHelpWidth(t *testing.T) {
+	cl := NewCommandLine()
+	cl.RegisterCommand(
+		func(values Values) error {
+			return nil
+		},
+		"format?Formats the storage device, erasing all of its contents",
+		"[--force]?Performs the format even if the storage has been formatted",
+	)
+
+	priorTerminal := xterm
+	defer func() { xterm = priorTerminal }()
+
+	// not a terminal
+	output := captureStdout(t, func() {
+		cl.PrintCommand("format")
+	})
+	expectString(
+		t,
+		"format       Formats the storage device, erasing all of its contents\n"+
+			"  [--force]  Performs the format even if the storage has been formatted\n",
+		output,
+	)
+
+	xterm = &testTerminal{width: 41}
+	output = captureStdout(t, func() {
+		cl.PrintCommand("format")
+	})
+	expectString(
+		t,
+		"format       Formats the storage device,\n"+
+			"             erasing all of its contents\n"+
+			"  [--force]  Performs the format even if\n"+
+			"             the storage has been\n"+
+			"             formatted\n",
+		output,
+	)
+
+	cl.SetHelpWidth(60)
+	output = captureStdout(t, func() {
+		cl.PrintCommand("format")
+	})
+	expectString(
+		t,
+		"format       Formats the storage device, erasing all of its\n"+
+			"             contents\n"+
+			"  [--force]  Performs the format even if the storage has\n"+
+			"             been formatted\n",
+		output,
+	)
+
+	// narrow terminals narrow the first column
+	cl.SetHelpWidth(0)
+	xterm = &testTerminal{width: 20}
+	expectValue(t, 40, cl.helpLineWidth())
+	expectValue(t, 13, helpRiverLimit(40))
+	expectValue(t, 30, helpRiverLimit(120))
+}
+
+func TestErrorKinds(t *testing.T) {
+	cl := NewCommandLine()
+
+	cl.RegisterCommand(
+		func(values Values) error {
+			return nil
+		},
+		"serve <int[1..65535]-port>",
+		"--host <string-host>",
+		"[--verbose]",
+		"[--name:<string-first>,<string-last>]",
+	)
+	cl.RegisterCommand(
+		func(values Values) error {
+			return nil
+		},
+		"status",
+		"[--a]",
+		"[--b]",
+	)
+	cl.RequireTogether("status", "--a", "--b")
+
+	expectKind := func(kind error, token string, spec string, err error) {
+		t.Helper()
+		expectBool(t, true, errors.Is(err, kind))
+
+		var cle *CommandLineError
+		expectBool(t, true, errors.As(err, &cle))
+		expectValue(t, kind, cle.Kind())
+		expectString(t, token, cle.Token())
+		expectString(t, spec, cle.Spec())
+	}
+
+	expectKind(ErrUnknownCommand, "serv", "", cl.Process([]string{"serv"}))
+	expectKind(ErrUnknownOption, "--bogus", "serve <port[1..65535]>", cl.Process([]string{"serve", "80", "--bogus"}))
+	expectKind(ErrMissingRequiredOption, "--host", "--host <host>", cl.Process([]string{"serve", "80"}))
+	expectKind(ErrMissingValue, "host", "--host <host>", cl.Process([]string{"serve", "80", "--host"}))
+	expectKind(ErrUnexpectedValue, "x", "[--verbose]", cl.Process([]string{"serve", "80", "--host", "h", "--verbose:x"}))
+	expectKind(ErrInvalidValue, "0", "serve <port[1..65535]>", cl.Process([]string{"serve", "0", "--host", "h"}))
+	expectKind(ErrConstraintViolation, "--a, --b", "", cl.Process([]string{"status", "--a"}))
+	expectKind(ErrMalformedLine, "\"", "", cl.ProcessLine(`status "`))
+
+	// kinds don't match each other, or errors made by NewCommandLineError
+	err := cl.Process([]string{"serv"})
+	expectBool(t, false, errors.Is(err, ErrUnknownOption))
+	expectBool(t, false, errors.Is(NewCommandLineError("Unrecognized command: serv"), ErrUnknownCommand))
+	expectValue(t, nil, NewCommandLineError("x").(*CommandLineError).Kind())
+
+	ncl := NewCommandLine()
+	ncl.RegisterCommand(func(values Values) error { return nil }, "run", "[--x]")
+	expectKind(ErrMissingCommand, "", "", ncl.Process([]string{}))
+}
+
+func TestParse(t *testing.T) {
+	cl := NewCommandLine()
+
+	calls := []string{}
+	cl.RegisterGlobalOption(
+		func(values Values) error {
+			calls = append(calls, fmt.Sprintf("log %d", values.Int("level")))
+			return nil
+		},
+		"--log <int-level>",
+	)
+	cl.RegisterCommand(
+		func(values Values) error {
+			calls = append(calls, "create "+values.String("name"))
+			return nil
+		},
+		"users|create <string-name>",
+		"[--admin]",
+	)
+
+	pc, err := cl.Parse([]string{"--log", "2", "users", "create", "bob", "--", "extra"})
+	expectError(t, nil, err)
+	expectValue(t, 0, len(calls))
+	expectString(t, "users create", pc.Name)
+	expectString(t, "bob", pc.Values.String("name"))
+	expectBool(t, false, pc.Values.Bool("--admin"))
+	expectString(t, "[extra]", fmt.Sprint(pc.Values.StringSlice(RestArgs)))
+	expectValue(t, 1, len(pc.GlobalOptions))
+	expectString(t, "--log", pc.GlobalOptions[0].Name)
+	expectValue(t, 2, pc.GlobalOptions[0].Values.Int("level"))
+
+	err = pc.Execute(context.Background())
+	expectError(t, nil, err)
+	expectString(t, "[log 2 create bob]", fmt.Sprint(calls))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = pc.Execute(ctx)
+	expectBool(t, true, errors.Is(err, context.Canceled))
+
+	pc, err = cl.ParseWithContext("pctx", []string{"users", "create", "ann"})
+	expectError(t, nil, err)
+	expectValue(t, "pctx", pc.Values[""])
+	expectValue(t, 0, len(pc.GlobalOptions))
+
+	_, err = cl.Parse([]string{"--log", "2", "users", "create"})
+	expectError(t, NewCommandLineError("Required value name is missing"), err)
+	expectString(t, "[log 2 create bob]", fmt.Sprint(calls))
+
+	// a help request is handled as Process handles it
+	var sb strings.Builder
+	cl.SetOutput(&sb)
+	cl.EnableAutoHelp()
+	pc, err = cl.Parse([]string{"users", "create", "--help"})
+	expectError(t, ErrHelpShown, err)
+	expectBool(t, true, pc == nil)
+	expectBool(t, true, strings.Contains(sb.String(), "users create <name>"))
+	expectString(t, "[log 2 create bob]", fmt.Sprint(calls))
+}
+
+func TestConcurrentUse(t *testing.T) {
+	cl := NewCommandLine()
+
+	cl.RegisterGlobalOption(
+		func(values Values) error {
+			return nil
+		},
+		"--log <int-level>?Log level",
+	)
+	cl.RegisterCommand(
+		func(values Values) error {
+			if values.Int("count") < 0 {
+				return errors.New("negative")
+			}
+			return nil
+		},
+		"run <int-count>?Runs the job",
+		"[--fast]?Runs faster",
+	)
+	cl.RegisterCommand(
+		func(values Values) error {
+			return nil
+		},
+		"status?Shows the status",
+	)
+
+	var sb strings.Builder
+	cl.SetOutput(&sb)
+
+	cl.PrintCommands("", true)
+	expected := sb.String()
+	sb.Reset()
+
+	const workers = 8
+	const iterations = 50
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*iterations)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				if err := cl.Process([]string{"--log", "1", "run", strconv.Itoa(i), "--fast"}); err != nil {
+					errs <- err
+				}
+				if _, err := cl.Parse([]string{"status"}); err != nil {
+					errs <- err
+				}
+				cl.PrintCommands("", true)
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		expectError(t, nil, err)
+	}
+
+	// each help listing is printed as a whole
+	expectString(t, strings.Repeat(expected, workers*iterations), sb.String())
+}
+
+func TestGlobalOptionPriority(t *testing.T) {
+	cl := NewCommandLine()
+
+	calls := []string{}
+	record := func(name string) CommandHandler {
+		return func(values Values) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+
+	cl.RegisterGlobalOption(record("--quiet"), "--quiet")
+	cl.RegisterGlobalOption(record("--color"), "--color")
+	cl.RegisterGlobalOptionWithPriority(record("--config"), "--config <path-config>", 100)
+	cl.RegisterGlobalOptionWithPriority(record("--log-level"), "--log-level <int-level>", 50)
+	cl.RegisterCommand(record("run"), "run")
+
+	err := cl.Process([]string{"--quiet", "--log-level", "2", "run", "--color", "--config", "x.cfg"})
+	expectError(t, nil, err)
+	expectString(t, "[--config --log-level --quiet --color run]", fmt.Sprint(calls))
+
+	pc, err := cl.Parse([]string{"--color", "--log-level", "2", "run"})
+	expectError(t, nil, err)
+	expectString(t, "--log-level", pc.GlobalOptions[0].Name)
+	expectString(t, "--color", pc.GlobalOptions[1].Name)
+}
+
+func TestComplete(t *testing.T) {
+	cl := NewCommandLine()
+
+	noop := func(values Values) error { return nil }
+	cl.RegisterCommand(noop, "users?Lists users")
+	cl.RegisterCommand(noop, "users|create <string-username>?Creates a user", "[--region <string-region>]", "[--admin]")
+	cl.RegisterCommand(noop, "users|delete <string-username>?Deletes a user")
+	cl.RegisterCommand(noop, "groups?Lists groups")
+	cl.RegisterGlobalOption(noop, "--env:<string-env>")
+
+	cl.RegisterCompleter("username", func(prefix string) []string {
+		completions := []string{}
+		for _, name := range []string{"alice", "bob", "barbara"} {
+			if strings.HasPrefix(name, prefix) {
+				completions = append(completions, name)
+			}
+		}
+		return completions
+	})
+	cl.RegisterCompleter("region", func(prefix string) []string {
+		return []string{"us-east", "us-west"}
+	})
+	cl.RegisterCompleter("env", func(prefix string) []string {
+		return []string{"prod"}
+	})
+
+	expectString(t, "[groups users]", fmt.Sprint(cl.Complete([]string{""})))
+	expectString(t, "[users]", fmt.Sprint(cl.Complete([]string{"u"})))
+	expectString(t, "[create delete]", fmt.Sprint(cl.Complete([]string{"users", ""})))
+	expectString(t, "[barbara bob]", fmt.Sprint(cl.Complete([]string{"users", "create", "b"})))
+	expectString(t, "[--admin --env --region]", fmt.Sprint(cl.Complete([]string{"users", "create", "bob", ""})))
+	expectString(t, "[us-east us-west]", fmt.Sprint(cl.Complete([]string{"users", "create", "bob", "--region", "us"})))
+	expectString(t, "[--admin --env]", fmt.Sprint(cl.Complete([]string{"users", "create", "bob", "--region", "us-east", ""})))
+	expectString(t, "[--env:prod]", fmt.Sprint(cl.Complete([]string{"--env:p"})))
+	expectString(t, "[alice]", fmt.Sprint(cl.Complete([]string{"--env:prod", "users", "delete", "a"})))
+	expectString(t, "[]", fmt.Sprint(cl.Complete([]string{"groups", "x"})))
+
+	expectPanicError(t, errors.New("argument error: a completer requires a value name and a function"), func() {
+		cl.RegisterCompleter("username", nil)
+	})
+}
+
+func TestJSONErrors(t *testing.T) {
+	cl := NewCommandLine()
+	cl.SetErrorFormat(JSONErrors)
+
+	var sb strings.Builder
+	cl.SetOutput(&sb)
+
+	cl.RegisterCommand(func(values Values) error { return nil }, "users", "--name <string-name>")
+
+	args := []string{"usrs"}
+	err := cl.Process(args)
+	cl.Help(err, "test", args)
+	expectString(t, `{"code":"unknown_command","message":"Unrecognized command: usrs; did you mean 'users'?","arg":"usrs","suggestions":["users"]}`+"\n", sb.String())
+
+	sb.Reset()
+	args = []string{"users", "--name"}
+	err = cl.Process(args)
+	cl.Help(err, "test", args)
+	expectString(t, `{"code":"missing_value","message":"Required value name is missing","arg":"name","spec":"--name <name>"}`+"\n", sb.String())
+
+	sb.Reset()
+	cl.Help(errors.New("disk full"), "test", args)
+	expectString(t, `{"code":"error","message":"disk full"}`+"\n", sb.String())
+
+	text, err := json.Marshal(NewCommandLineError("bad %s", "thing"))
+	expectError(t, nil, err)
+	expectString(t, `{"code":"error","message":"bad thing"}`, string(text))
+
+	// help without an error is still text
+	sb.Reset()
+	cl.Help(nil, "test", []string{})
+	expectBool(t, true, strings.HasPrefix(sb.String(), "Usage: test"))
+}
+
+type testUserCommands struct {
+	created string
+}
+
+func (tuc *testUserCommands) Commands() []CommandSpec {
+	return []CommandSpec{
+		{
+			Handler: func(values Values) error {
+				tuc.created = values["username"].(string)
+				return nil
+			},
+			Specs: []string{"users|create <string-username>?Creates a user"},
+		},
+		{
+			HandlerCtx: func(ctx context.Context, values Values) error {
+				return nil
+			},
+			Specs: []string{"users|list?Lists users", "[--all]"},
+		},
+	}
+}
+
+type testBadCommands struct{}
+
+func (tbc testBadCommands) Commands() []CommandSpec {
+	return []CommandSpec{{Specs: []string{"bad"}}}
+}
+
+func TestRegisterProvider(t *testing.T) {
+	cl := NewCommandLine()
+
+	users := &testUserCommands{}
+	cl.RegisterProvider(users)
+
+	err := cl.Process([]string{"users", "create", "bob"})
+	expectError(t, nil, err)
+	expectString(t, "bob", users.created)
+
+	err = cl.Process([]string{"users", "list", "--all"})
+	expectError(t, nil, err)
+
+	expectPanicError(t, errors.New("argument error: command spec [bad] requires one of Handler or HandlerCtx"), func() {
+		cl.RegisterProvider(testBadCommands{})
+	})
+	expectPanicError(t, errors.New("argument error: provider is required"), func() {
+		cl.RegisterProvider(nil)
+	})
+}
+
+func TestLoadCommandManifest(t *testing.T) {
+	cl := NewCommandLine()
+
+	created := ""
+	env := ""
+	cl.RegisterNamedHandler("createUser", func(values Values) error {
+		created = values["username"].(string)
+		return nil
+	})
+	cl.RegisterNamedHandler("setEnv", func(values Values) error {
+		env = values["env"].(string)
+		return nil
+	})
+
+	manifest := `{
+		"global_options": [
+			{"handler": "setEnv", "spec": "--env:<string-env>?Sets the environment"}
+		],
+		"commands": [
+			{"handler": "createUser", "specs": ["users|create <string-username>?Creates a user", "[--admin]?Grants admin rights"], "group": "User"}
+		]
+	}`
+
+	err := cl.LoadCommandManifest(strings.NewReader(manifest))
+	expectError(t, nil, err)
+
+	err = cl.Process([]string{"--env:prod", "users", "create", "bob", "--admin"})
+	expectError(t, nil, err)
+	expectString(t, "bob", created)
+	expectString(t, "prod", env)
+
+	desc := cl.Describe()
+	expectString(t, "Grants admin rights", desc.Commands[0].Options[0].Help)
+
+	err = cl.LoadCommandManifest(strings.NewReader(`{"commands": [{"handler": "missing", "specs": ["x"]}]}`))
+	expectError(t, errors.New("command manifest error: handler \"missing\" is not registered"), err)
+
+	err = cl.LoadCommandManifest(strings.NewReader(`{"commands": [{"handler": "createUser", "specs": ["y <string-"]}]}`))
+	expectErrorContainingText(t, "command manifest error: command line template syntax error!", err)
+
+	err = cl.LoadCommandManifest(strings.NewReader(`{"cmds": []}`))
+	expectErrorContainingText(t, "command manifest error: json: unknown field", err)
+}
+
+func TestLastInvocation(t *testing.T) {
+	cl := NewCommandLine()
+
+	expectValue(t, true, cl.LastInvocation() == nil)
+
+	cl.RegisterGlobalOption(func(values Values) error { return nil }, "--env:<string-env>")
+	cl.RegisterCommand(
+		func(values Values) error {
+			values["name"] = "changed"
+			return errors.New("handler failed")
+		},
+		"greet <string-name>",
+		"[--loud]",
+	)
+
+	err := cl.Process([]string{"greet", "bob", "--env:test"})
+	expectError(t, errors.New("handler failed"), err)
+
+	inv := cl.LastInvocation()
+	expectString(t, "greet", inv.Command)
+	expectValue(t, "bob", inv.Values["name"])
+	expectValue(t, false, inv.Values["--loud"])
+	expectValue(t, 1, len(inv.GlobalOptions))
+	expectString(t, "--env", inv.GlobalOptions[0].Name)
+	expectValue(t, "test", inv.GlobalOptions[0].Values["env"])
+
+	err = cl.Process([]string{"greet"})
+	expectError(t, NewCommandLineError("Required value name is missing"), err)
+	expectValue(t, true, cl.LastInvocation() == nil)
+}
+
+func TestMiddleware(t *testing.T) {
+	type testCtxKey string
+
+	cl := NewCommandLine()
+
+	calls := []string{}
+	cl.RegisterGlobalOption(func(values Values) error {
+		calls = append(calls, "--verbose")
+		return nil
+	}, "--verbose")
+	cl.RegisterCommand(func(values Values) error {
+		calls = append(calls, "run")
+		return nil
+	}, "run")
+
+	cl.Use(func(next CommandHandler) CommandHandler {
+		return func(values Values) error {
+			calls = append(calls, "outer")
+			return next(values)
+		}
+	})
+	cl.UseCtx(func(next CommandHandlerCtx) CommandHandlerCtx {
+		return func(ctx context.Context, values Values) error {
+			calls = append(calls, "inner")
+			err := next(ctx, values)
+			if err == nil && ctx.Value(testCtxKey("trace")) != nil {
+				calls = append(calls, "traced")
+			}
+			return err
+		}
+	})
+
+	err := cl.Process([]string{"--verbose", "run"})
+	expectError(t, nil, err)
+	expectString(t, "[--verbose outer inner run]", fmt.Sprint(calls))
+
+	calls = []string{}
+	cl.SetMiddlewareForGlobalOptions(true)
+	ctx := context.WithValue(context.Background(), testCtxKey("trace"), true)
+	err = cl.ProcessContext(ctx, []string{"--verbose", "run"})
+	expectError(t, nil, err)
+	expectString(t, "[outer inner --verbose traced outer inner run traced]", fmt.Sprint(calls))
+
+	// middleware can stop the handler
+	cl.Use(func(next CommandHandler) CommandHandler {
+		return func(values Values) error {
+			return errors.New("denied")
+		}
+	})
+	calls = []string{}
+	pc, err := cl.Parse([]string{"run"})
+	expectError(t, nil, err)
+	err = pc.Execute(context.Background())
+	expectError(t, errors.New("denied"), err)
+	expectString(t, "[outer inner]", fmt.Sprint(calls))
+}
+
+func TestRecoverPanics(t *testing.T) {
+	cl := NewCommandLine()
+
+	sentinel := errors.New("global exploded")
+	cl.RegisterGlobalOption(func(values Values) error {
+		panic(sentinel)
+	}, "--boom")
+	cl.RegisterCommand(func(values Values) error {
+		var m map[string]int
+		m["x"] = 1
+		return nil
+	}, "run")
+
+	expectPanic(t, func() {
+		cl.Process([]string{"run"})
+	})
+
+	cl.SetRecoverPanics(true)
+
+	err := cl.Process([]string{"run"})
+	var pe *PanicError
+	expectBool(t, true, errors.As(err, &pe))
+	expectString(t, "handler panic: assignment to entry in nil map", err.Error())
+	expectBool(t, true, strings.Contains(string(pe.Stack), "TestRecoverPanics"))
+
+	err = cl.Process([]string{"--boom", "run"})
+	expectString(t, "handler panic: global exploded", err.Error())
+	expectBool(t, true, errors.Is(err, sentinel))
+}
+
+func TestVerbosity(t *testing.T) {
+	cl := NewCommandLine()
+	cl.EnableVerbosity()
+
+	level := 99
+	cl.RegisterCommand(func(values Values) error {
+		level = cl.Verbosity()
+		return nil
+	}, "run")
+
+	tests := []struct {
+		args     []string
+		expected int
+	}{
+		{[]string{"run"}, 0},
+		{[]string{"-v", "run"}, 1},
+		{[]string{"-vv", "run"}, 2},
+		{[]string{"run", "-vvv"}, 3},
+		{[]string{"-v", "-v", "run"}, 2},
+		{[]string{"-vv", "--quiet", "run"}, -1},
+	}
+
+	for _, test := range tests {
+		err := cl.Process(test.args)
+		expectError(t, nil, err)
+		expectValue(t, test.expected, level)
+	}
+
+	pc, err := cl.Parse([]string{"-vv", "run"})
+	expectError(t, nil, err)
+	expectValue(t, -1, cl.Verbosity())
+	err = pc.Execute(context.Background())
+	expectError(t, nil, err)
+	expectValue(t, 2, level)
+
+	// turn the package printer's verbose output back off
+	cl.Process([]string{"run"})
+}
+
+func TestCountType(t *testing.T) {
+	cl := NewCommandLine()
+	cl.EnableShortFlagClustering()
+
+	var values Values
+	cl.RegisterCommand(
+		func(v Values) error {
+			values = v
+			return nil
+		},
+		"run",
+		"[-v|--verbose <count-verbose>]?Prints more detail",
+		"[-q]",
+	)
+
+	err := cl.Process([]string{"run"})
+	expectError(t, nil, err)
+	expectValue(t, 0, values["verbose"])
+	expectValue(t, false, values["-v"])
+
+	err = cl.Process([]string{"run", "-v", "--verbose", "-v"})
+	expectError(t, nil, err)
+	expectValue(t, 3, values["verbose"])
+	expectValue(t, true, values["-v"])
+
+	err = cl.Process([]string{"run", "-vvq"})
+	expectError(t, nil, err)
+	expectValue(t, 2, values["verbose"])
+	expectValue(t, true, values["-q"])
+
+	err = cl.Process([]string{"run", "-v:2"})
+	expectError(t, NewCommandLineError("Unexpected command argument: 2"), err)
+
+	expectString(t, "[-v|--verbose]...", cl.commands.values["run"].OptionSpecs.values["-v"].String())
+
+	expectPanicError(t, &SpecError{Spec: "count <count-n>", Column: 7, Expected: "count value as the only value of a single option"}, func() {
+		cl.RegisterCommand(func(v Values) error { return nil }, "count <count-n>")
+	})
+	expectPanicError(t, &SpecError{Spec: "[-x <count-n> <int-m>]", Column: 5, Expected: "count value as the only value of a single option"}, func() {
+		cl.RegisterCommand(func(v Values) error { return nil }, "other", "[-x <count-n> <int-m>]")
+	})
+
+	// a repeated global count runs its handler once with the total
+	counts := []int{}
+	cl.RegisterGlobalOption(func(v Values) error {
+		counts = append(counts, v.Int("n"))
+		return nil
+	}, "[-g:<count-n>]")
+
+	err = cl.Process([]string{"-g", "run", "-g", "-v", "-g"})
+	expectError(t, nil, err)
+	expectString(t, "[3]", fmt.Sprint(counts))
+	expectValue(t, 1, values["verbose"])
+
+	counts = nil
+	err = cl.Process([]string{"run", "-g"})
+	expectError(t, nil, err)
+	expectString(t, "[1]", fmt.Sprint(counts))
+}
+
+func TestNegatableFlags(t *testing.T) {
+	cl := NewCommandLine()
+
+	var values, globalValues Values
+	cl.RegisterGlobalOption(func(v Values) error {
+		globalValues = v
+		return nil
+	}, "--[no-]cache?Uses the cache")
+	cl.RegisterCommand(
+		func(v Values) error {
+			values = v
+			return nil
+		},
+		"build",
+		"[--[no-]color|-c]?Colors the output",
+	)
+
+	err := cl.Process([]string{"build"})
+	expectError(t, nil, err)
+	expectValue(t, false, values["--color"])
+	expectValue(t, false, values["--no-color"])
+
+	err = cl.Process([]string{"build", "-c"})
+	expectError(t, nil, err)
+	expectValue(t, true, values["--color"])
+	expectValue(t, false, values["--no-color"])
+
+	err = cl.Process([]string{"--no-cache", "build", "--color", "--no-color"})
+	expectError(t, nil, err)
+	expectValue(t, false, values["--color"])
+	expectValue(t, true, values["--no-color"])
+	expectValue(t, false, globalValues["--cache"])
+	expectValue(t, true, globalValues["--no-cache"])
+
+	expectString(t, "[--[no-]color|-c]", cl.commands.values["build"].OptionSpecs.values["--color"].String())
+	expectString(t, "--no-color", cl.Describe().Commands[0].Options[0].Negation)
+
+	expectPanicError(t, &SpecError{Spec: "[--[no-]name <string-x>]", Column: 14, Expected: "negatable flag without values"}, func() {
+		cl.RegisterCommand(func(v Values) error { return nil }, "other", "[--[no-]name <string-x>]")
+	})
+	expectPanicError(t, errors.New(basePanic+`unique argument "--no-cache"`), func() {
+		cl.RegisterCommand(func(v Values) error { return nil }, "another", "[--no-cache]")
+	})
+}
+
+func TestSecretValues(t *testing.T) {
+	cl := NewCommandLine()
+
+	password := ""
+	cl.RegisterCommand(
+		func(values Values) error {
+			password = values["password"].(string)
+			return nil
+		},
+		"login <string-user>",
+		"--password <secret/^[^ ]+$/-password>",
+	)
+
+	err := cl.Process([]string{"login", "bob", "--password", "hunter2"})
+	expectError(t, nil, err)
+	expectString(t, "hunter2", password)
+
+	inv := cl.LastInvocation()
+	expectValue(t, "********", inv.Values["password"])
+	expectValue(t, "bob", inv.Values["user"])
+
+	err = cl.Process([]string{"login", "bob", "--password", "has space"})
+	expectString(t, "Value password must match the pattern ^[^ ]+$", err.Error())
+	var cle *CommandLineError
+	expectBool(t, true, errors.As(err, &cle))
+	expectString(t, "********", cle.Token())
+
+	cl.AddValidator("password", func(value any) error {
+		return fmt.Errorf("%s is too short", value)
+	})
+	err = cl.Process([]string{"login", "bob", "--password", "abc"})
+	expectError(t, NewCommandLineError("******** is too short"), err)
+	expectBool(t, true, errors.Is(err, ErrInvalidValue))
+	delete(cl.validators, "password")
+
+	// prompting only happens when enabled
+	priorTerminal := xterm
+	defer func() { xterm = priorTerminal }()
+	xterm = &testTerminal{password: "s3cret"}
+
+	err = cl.Process([]string{"login", "bob", "--password"})
+	expectError(t, NewCommandLineError("Required value password is missing"), err)
+
+	cl.EnableSecretPrompts()
+	err = cl.Process([]string{"login", "bob", "--password"})
+	expectError(t, nil, err)
+	expectString(t, "s3cret", password)
+}
+
+type testConfirmProvider struct {
+	answer  bool
+	prompts []string
+}
+
+func (tcp *testConfirmProvider) Confirm(prompt string) (bool, error) {
+	tcp.prompts = append(tcp.prompts, prompt)
+	return tcp.answer, nil
+}
+
+func TestRequireConfirmation(t *testing.T) {
+	cl := NewCommandLine()
+
+	deleted := ""
+	cl.RegisterCommand(func(values Values) error {
+		deleted = values["username"].(string)
+		return nil
+	}, "users|delete <string-username>")
+
+	cl.RequireConfirmation("users|delete", "Delete user %s?")
+
+	tcp := &testConfirmProvider{}
+	cl.SetConfirmProvider(tcp)
+
+	err := cl.Process([]string{"users", "delete", "bob"})
+	expectError(t, NewCommandLineError("Canceled"), err)
+	expectBool(t, true, errors.Is(err, ErrNotConfirmed))
+	expectString(t, "", deleted)
+	expectString(t, "[Delete user bob?]", fmt.Sprint(tcp.prompts))
+
+	tcp.answer = true
+	err = cl.Process([]string{"users", "delete", "bob"})
+	expectError(t, nil, err)
+	expectString(t, "bob", deleted)
+
+	tcp.answer = false
+	tcp.prompts = nil
+	err = cl.Process([]string{"users", "delete", "alice", "--yes"})
+	expectError(t, nil, err)
+	expectString(t, "alice", deleted)
+	expectValue(t, 0, len(tcp.prompts))
+
+	err = cl.Process([]string{"users", "delete", "carol", "-y"})
+	expectError(t, nil, err)
+	expectString(t, "carol", deleted)
+
+	expectString(t, "100% sure?", formatPrompt("100%% sure?", nil))
+	expectString(t, "Remove a and b?", formatPrompt("Remove %s and %s?", []any{"a", "b", "c"}))
+}
+
+func TestRun(t *testing.T) {
+	cl := NewCommandLine()
+	cl.EnableAutoHelp()
+
+	var sb strings.Builder
+	cl.SetOutput(&sb)
+
+	cl.RegisterCommand(func(values Values) error { return nil }, "ok")
+	cl.RegisterCommand(func(values Values) error { return errors.New("disk full") }, "fail")
+	cl.RegisterCommand(func(values Values) error { return nil }, "add", "[--count <int-count>]")
+
+	expectValue(t, ExitOK, runArgs(cl, "test", []string{"ok"}))
+	expectValue(t, ExitOK, runArgs(cl, "test", []string{"--help"}))
+	expectValue(t, ExitUsage, runArgs(cl, "test", []string{"nope"}))
+	expectValue(t, ExitUsage, runArgs(cl, "test", []string{"add", "--count", "abc"}))
+	expectValue(t, ExitUsage, ExitCode(cl.Process([]string{"add", "--count", "abc"})))
+
+	sb.Reset()
+	expectValue(t, ExitFailure, runArgs(cl, "test", []string{"fail"}))
+	expectString(t, "\ndisk full\n\n", sb.String())
+
+	expectValue(t, ExitFailure, ExitCode(newKindError(ErrNotConfirmed, "", "", "Canceled")))
+	expectValue(t, ExitOK, ExitCode(ErrVersionShown))
+
+	priorExit := osExit
+	defer func() { osExit = priorExit }()
+	priorArgs := os.Args
+	defer func() { os.Args = priorArgs }()
+
+	code := -1
+	osExit = func(c int) { code = c }
+	os.Args = []string{"test", "nope"}
+	Run(cl, "test")
+	expectValue(t, ExitUsage, code)
+}
+
+func TestLocalization(t *testing.T) {
+	cl := NewCommandLine()
+	cl.RegisterCommand(func(values Values) error { return nil }, "users?users.help")
+
+	cl.SetTranslator(MessageCatalog{
+		"Unrecognized command: %s": "Commande inconnue : %s",
+		"; did you mean '%s'?":     " ; vouliez-vous dire '%s' ?",
+		"All Commands:":            "Toutes les commandes :",
+		"Description: %s":          "Description : %s",
+		"users.help":               "Liste les utilisateurs",
+	})
+
+	err := cl.Process([]string{"usrs"})
+	expectError(t, NewCommandLineError("Commande inconnue : usrs ; vouliez-vous dire 'users' ?"), err)
+
+	err = cl.Process([]string{"groups"})
+	expectError(t, NewCommandLineError("Commande inconnue : groups"), err)
+
+	cl.RegisterCommand(func(values Values) error { return nil }, "groups?Lists groups")
+	output := captureStdout(t, func() { cl.Help(nil, "app", []string{}) })
+	expectBool(t, true, strings.Contains(output, "Toutes les commandes :"))
+	expectBool(t, true, strings.Contains(output, "Liste les utilisateurs"))
+	expectBool(t, true, strings.Contains(output, "Lists groups"))
+
+	cl.SetTranslator(nil)
+	err = cl.Process([]string{"usrs"})
+	expectError(t, NewCommandLineError("Unrecognized command: usrs; did you mean 'users'?"), err)
+}
+
+func TestSlashOptions(t *testing.T) {
+	cl := NewCommandLine()
+
+	var seen Values
+	cl.RegisterCommand(
+		func(values Values) error {
+			seen = values
+			return nil
+		},
+		"copy <path-source>",
+		"[--force|-f]?Overwrite",
+		"[--mode:<string-mode>]?Copy mode",
+		"[--out <path-dest>]?Destination",
+	)
+
+	verbose := false
+	cl.RegisterGlobalOption(func(values Values) error {
+		verbose = true
+		return nil
+	}, "[--verbose]")
+
+	err := cl.Process([]string{"copy", "/tmp/a", "/force"})
+	expectError(t, NewCommandLineError("Unrecognized command argument: /force"), err)
+
+	cl.EnableSlashOptions()
+
+	err = cl.Process([]string{"copy", "/tmp/a", "/force", "/mode:fast", "/out:/tmp/b", "/verbose"})
+	expectError(t, nil, err)
+	expectString(t, "/tmp/a", seen.String("source"))
+	expectBool(t, true, seen.Bool("--force"))
+	expectString(t, "fast", seen.String("mode"))
+	expectString(t, "/tmp/b", seen.String("dest"))
+	expectBool(t, true, verbose)
+
+	err = cl.Process([]string{"copy", "/tmp/a", "/f"})
+	expectError(t, nil, err)
+	expectBool(t, true, seen.Bool("--force"))
+
+	err = cl.Process([]string{"copy", "/tmp/a", "/tmp/c"})
+	expectError(t, NewCommandLineError("Unrecognized command argument: /tmp/c"), err)
+}
+
+func TestOptionAbbreviations(t *testing.T) {
+	cl := NewCommandLine()
+
+	var seen Values
+	cl.RegisterCommand(
+		func(values Values) error {
+			seen = values
+			return nil
+		},
+		"build",
+		"[--verbose|--loud]?Verbose output",
+		"[--version:<string-ver>]?Version to build",
+		"[--output <string-out>]?Output file",
+		"[--[no-]color]?Colors the output",
+	)
+
+	dryRun := false
+	cl.RegisterGlobalOption(func(values Values) error {
+		dryRun = true
+		return nil
+	}, "[--dry-run]")
+
+	err := cl.Process([]string{"build", "--verb"})
+	expectError(t, NewCommandLineError("Unrecognized command argument: --verb; did you mean '--verbose'?"), err)
+
+	cl.EnableOptionAbbreviations()
+
+	err = cl.Process([]string{"--dry", "build", "--verb", "--out", "a.bin", "--vers:1.2", "--no-c"})
+	expectError(t, nil, err)
+	expectBool(t, true, seen.Bool("--verbose"))
+	expectString(t, "a.bin", seen.String("out"))
+	expectString(t, "1.2", seen.String("ver"))
+	expectBool(t, true, seen.Bool("--no-color"))
+	expectBool(t, true, dryRun)
+
+	err = cl.Process([]string{"build", "--output=b.bin"})
+	expectError(t, nil, err)
+	expectString(t, "b.bin", seen.String("out"))
+
+	err = cl.Process([]string{"build", "--ver"})
+	expectError(t, NewCommandLineError("Ambiguous option: --ver could be --verbose, --version"), err)
+	expectBool(t, true, errors.Is(err, ErrAmbiguousOption))
+
+	err = cl.Process([]string{"build", "--l"})
+	expectError(t, nil, err)
+	expectBool(t, true, seen.Bool("--verbose"))
+
+	// a global option is abbreviated only before the command
+	err = cl.Process([]string{"build", "--dry"})
+	expectError(t, NewCommandLineError("Unrecognized command argument: --dry; valid options: --verbose, --version, --output, --color"), err)
+
+	err = cl.Process([]string{"build", "--dry-run"})
+	expectError(t, nil, err)
+
+	cl = NewCommandLine()
+	cl.EnableOptionAbbreviations()
+
+	verbose := false
+	cl.RegisterGlobalOption(func(values Values) error { verbose = true; return nil }, "[--verbose]")
+	cl.RegisterCommand(func(values Values) error { seen = values; return nil }, "run", "[--ver]")
+
+	err = cl.Process([]string{"run", "--ver"})
+	expectError(t, nil, err)
+	expectBool(t, true, seen.Bool("--ver"))
+	expectBool(t, false, verbose)
+
+	err = cl.Process([]string{"--verb", "run"})
+	expectError(t, nil, err)
+	expectBool(t, false, seen.Bool("--ver"))
+	expectBool(t, true, verbose)
+
+	cl = NewCommandLine()
+	cl.EnableOptionAbbreviations()
+
+	verbose = false
+	cl.RegisterGlobalOption(func(values Values) error { verbose = true; return nil }, "[--verbose]")
+	cl.RegisterCommand(func(values Values) error { seen = values; return nil }, "~", "[--ver]")
+
+	err = cl.Process([]string{"--ver"})
+	expectError(t, nil, err)
+	expectBool(t, true, seen.Bool("--ver"))
+	expectBool(t, false, verbose)
+}
+
+func TestOptionGroups(t *testing.T) {
+	cl := NewCommandLine()
+
+	cl.RegisterCommand(
+		func(values Values) error { return nil },
+		"serve?Runs the server",
+		"[--port <int-port>]?Listening port",
+		"[--tls-cert <path-cert>]?Certificate file",
+		"[--log-level <string-level>]?Log level",
+		"[--tls-key <path-key>]?Key file",
+	)
+
+	cl.SetOptionGroup("serve", "--tls-cert", "TLS")
+	cl.SetOptionGroup("serve", "--log-level", "Logging")
+	cl.SetOptionGroup("serve", "--tls-key", "TLS")
+
+	output := captureStdout(t, func() {
+		err := cl.PrintCommand("serve")
+		expectError(t, nil, err)
+	})
+	expectString(
+		t,
+		"serve                      Runs the server\n"+
+			"  [--port <port>]          Listening port\n"+
+			"  TLS options:\n"+
+			"    [--tls-cert <cert>]    Certificate file\n"+
+			"    [--tls-key <key>]      Key file\n"+
+			"  Logging options:\n"+
+			"    [--log-level <level>]  Log level\n",
+		output,
+	)
+
+	expectString(t, "TLS", cl.Describe().Commands[0].Options[1].Group)
+
+	expectPanicError(t, fmt.Errorf("argument error: option \"--bogus\" is not registered for command \"serve\""), func() {
+		cl.SetOptionGroup("serve", "--bogus", "TLS")
+	})
+}
+
+func TestPowerShellCompletion(t *testing.T) {
+	cl := NewCommandLine()
+
+	handler := func(values Values) error { return nil }
+	cl.RegisterCommand(handler, "users|create <string-name>", "[--admin|-a]?Grants admin rights")
+	cl.RegisterGlobalOption(func(values Values) error { return nil }, "[--verbose|-v]")
+	cl.EnableCompletionCommand()
+
+	script := cl.PowerShellCompletion("o'app")
+	expectBool(t, true, strings.HasPrefix(script, "Register-ArgumentCompleter -Native -CommandName 'oapp' -ScriptBlock {\n"))
+	expectBool(t, true, strings.Contains(script, "& 'oapp' __complete -- @words"))
+
+	expected, err := cl.CompletionScript("powershell", "o'app")
+	expectError(t, nil, err)
+	expectString(t, expected, script)
+
+	output := captureStdout(t, func() {
+		err = cl.Process([]string{"__complete", "--", "users", "create", "bob", "-"})
+	})
+	expectBool(t, true, errors.Is(err, ErrCompletionShown))
+	expectString(t, "--admin\n--verbose\n-a\n-v\n", output)
+}
+
+func TestCompletionCommand(t *testing.T) {
+	cl := NewCommandLine()
+	cl.SetAppName("my-app")
+
+	handler := func(values Values) error { return nil }
+	cl.RegisterCommand(handler, "users|create <string-name>", "[--admin]")
+	cl.RegisterCommand(handler, "users|delete <string-name>")
+	cl.RegisterCommand(handler, "jobs")
+
+	err := cl.Process([]string{"completion", "bash"})
+	expectError(t, NewCommandLineError("Unrecognized command: completion"), err)
+
+	cl.EnableCompletionCommand()
+
+	output := captureStdout(t, func() {
+		err = cl.Process([]string{"completion", "bash"})
+	})
+	expectBool(t, true, errors.Is(err, ErrCompletionShown))
+	expectValue(t, ExitOK, ExitCode(err))
+	expectBool(t, true, strings.HasPrefix(output, "_my_app_complete() {\n"))
+	expectBool(t, true, strings.Contains(output, "complete -o default -F _my_app_complete 'my-app'\n"))
+
+	output = captureStdout(t, func() {
+		err = cl.Process([]string{"__complete", "--", "users", ""})
+	})
+	expectBool(t, true, errors.Is(err, ErrCompletionShown))
+	expectString(t, "create\ndelete\n", output)
+
+	output = captureStdout(t, func() {
+		err = cl.Process([]string{"__complete", "--", "users", "create", "bob", "--a"})
+	})
+	expectString(t, "--admin\n", output)
+
+	for _, shell := range []string{"zsh", "fish", "powershell"} {
+		script, err := cl.CompletionScript(shell, "my-app")
+		expectError(t, nil, err)
+		expectBool(t, true, strings.Contains(script, "'my-app' __complete -- "))
+	}
+
+	err = cl.Process([]string{"completion", "tcsh"})
+	expectError(t, NewCommandLineError("Unsupported shell: tcsh; expected one of bash, fish, powershell, zsh"), err)
+
+	err = cl.Process([]string{"completion"})
+	expectError(t, NewCommandLineError("A shell is required: bash, fish, powershell, zsh"), err)
+
+	output = captureStdout(t, func() {
+		cl.PrintCommands("", false)
+	})
+	expectBool(t, false, strings.Contains(output, "completion"))
+}
+
+func TestCommandsMetadata(t *testing.T) {
+	cl := NewCommandLine()
+
+	handler := func(values Values) error { return nil }
+	cl.RegisterCommand(handler, "users|create <string-name>?Creates a user", "[--role|-r <string-role>]?Role", "[--tag <string-tags>...]?Tags")
+	cl.RegisterCommand(handler, "jobs?Lists jobs", "[--limit:<int-n>]")
+	cl.SetCommandGroup("users create", "Administration")
+
+	commands := cl.Commands()
+	expectValue(t, 2, len(commands))
+
+	users := commands[0]
+	expectString(t, "users create", users.Name)
+	expectString(t, "Administration", users.Group)
+	expectString(t, "Creates a user", users.Help)
+	expectString(t, "name", users.Values[0].Name)
+	expectString(t, "string", users.Values[0].Type)
+	expectString(t, "--role", users.Options[0].Name)
+	expectString(t, "[-r]", fmt.Sprint(users.Options[0].Aliases))
+	expectBool(t, true, users.Options[0].Optional)
+	expectBool(t, true, users.Options[1].Values[0].Multi)
+
+	jobs := commands[1]
+	expectString(t, "int", jobs.Options[0].Values[0].Type)
+	expectValue(t, 0, jobs.Options[0].Values[0].Default)
+
+	users.Options[0].Aliases[0] = "-x"
+	expectString(t, "[-r]", fmt.Sprint(cl.Commands()[0].Options[0].Aliases))
+}
+
+func TestDefaultValues(t *testing.T) {
+	t.Setenv("CMDLINE_TEST_HOME", "/home/tester")
+	os.Unsetenv("CMDLINE_TEST_MISSING")
+
+	cl := NewCommandLine()
+
+	var seen Values
+	cl.RegisterCommand(
+		func(values Values) error {
+			seen = values
+			return nil
+		},
+		"backup <string-name>",
+		"[--backup-dir:<path-dir=${CMDLINE_TEST_HOME}/.mytool>]?Backup directory",
+		"[--label:<string-label=${name}-backup>]",
+		"[--retries:<int-retries=3>]",
+		"[--owner:<string-owner=${CMDLINE_TEST_MISSING}>]",
+		"[--level [<int-level=5>]]",
+	)
+
+	err := cl.Process([]string{"backup", "db", "--owner:root"})
+	expectError(t, nil, err)
+	expectString(t, "/home/tester/.mytool", seen.String("dir"))
+	expectString(t, "db-backup", seen.String("label"))
+	expectValue(t, 3, seen["retries"])
+	expectValue(t, 5, seen["level"])
+
+	err = cl.Process([]string{"backup", "db", "--owner:root", "--backup-dir:/tmp", "--retries:1", "--level"})
+	expectError(t, nil, err)
+	expectString(t, "/tmp", seen.String("dir"))
+	expectValue(t, 1, seen["retries"])
+	expectValue(t, 5, seen["level"])
+
+	err = cl.Process([]string{"backup", "db"})
+	expectError(t, NewCommandLineError("Default of owner refers to CMDLINE_TEST_MISSING, which is not set"), err)
+	expectBool(t, true, errors.Is(err, ErrMissingValue))
+
+	expectPanicError(t, &SpecError{Spec: "[--count:<int-n=many>]", Column: 17, Expected: "valid default value"}, func() {
+		cl.RegisterCommand(func(values Values) error { return nil }, "other", "[--count:<int-n=many>]")
+	})
+
+	expectPanicError(t, &SpecError{Spec: "[--tag <string-tags=a>...]", Column: 21, Expected: "default for a single value"}, func() {
+		cl.RegisterCommand(func(values Values) error { return nil }, "other", "[--tag <string-tags=a>...]")
+	})
+}
+
+func TestRepeatCountLimits(t *testing.T) {
+	cl := NewCommandLine()
+
+	var seen Values
+	cl.RegisterCommand(
+		func(values Values) error {
+			seen = values
+			return nil
+		},
+		"tag",
+		"*{1..3}[-t:<string-tags>]?Tags to apply",
+		"*{..2}[--note <string-note>]",
+	)
+
+	err := cl.Process([]string{"tag", "-t:a", "-t:b"})
+	expectError(t, nil, err)
+	expectString(t, "[a b]", fmt.Sprint(seen["tags"]))
+
+	err = cl.Process([]string{"tag"})
+	expectError(t, NewCommandLineError("Argument -t is given 0 time(s); expected {1..3}"), err)
+	expectBool(t, true, errors.Is(err, ErrRepeatCount))
+
+	err = cl.Process([]string{"tag", "-t:a", "-t:b", "-t:c", "-t:d"})
+	expectError(t, NewCommandLineError("Argument -t is given 4 time(s); expected {1..3}"), err)
+
+	err = cl.Process([]string{"tag", "-t:a", "--note", "x", "--note", "y", "--note", "z"})
+	expectError(t, NewCommandLineError("Argument --note is given 3 time(s); expected {..2}"), err)
+
+	info := cl.Commands()[0].Options[0]
+	expectString(t, "*{1..3}[-t:<string-tags>]?Tags to apply", info.Spec)
+	expectValue(t, 1, info.MinCount)
+	expectValue(t, 3, info.MaxCount)
+
+	expectPanicError(t, &SpecError{Spec: "*{3..1}[-x]", Column: 3, Expected: "repeat count min at or below max"}, func() {
+		cl.RegisterCommand(func(values Values) error { return nil }, "other", "*{3..1}[-x]")
+	})
+
+	expectPanicError(t, &SpecError{Spec: "*{0..1}[-x]", Column: 3, Expected: "positive repeat count"}, func() {
+		cl.RegisterCommand(func(values Values) error { return nil }, "other", "*{0..1}[-x]")
+	})
+}
+
+func TestArgumentHelp(t *testing.T) {
+	cl := NewCommandLine()
+
+	cl.RegisterCommand(
+		func(values Values) error { return nil },
+		"copy <string-src>... <string-dst>?Copies files",
+		"[--force]?Overwrites files",
+	)
+	cl.SetArgumentHelp("copy", "src", "Files to copy")
+	cl.SetArgumentHelp("copy", "dst", "Destination directory")
+
+	output := captureStdout(t, func() {
+		err := cl.PrintCommand("copy")
+		expectError(t, nil, err)
+	})
+	expectString(
+		t,
+		"copy <src>... <dst>  Copies files\n"+
+			"  Arguments:\n"+
+			"    <src>...         Files to copy\n"+
+			"    <dst>            Destination directory\n"+
+			"  [--force]          Overwrites files\n",
+		output,
+	)
+
+	expectString(t, "Files to copy", cl.Commands()[0].Values[0].Help)
+
+	expectPanicError(t, fmt.Errorf("argument error: value \"bogus\" is not a positional argument of command \"copy\""), func() {
+		cl.SetArgumentHelp("copy", "bogus", "Nothing")
+	})
+}
+
+func TestProcessReader(t *testing.T) {
+	cl := NewCommandLine()
+
+	streamed := []string{}
+	handled := ""
+	cl.RegisterCommand(
+		func(values Values) error {
+			handled = fmt.Sprintf("%v %d", values["--force"], len(streamed))
+			return nil
+		},
+		"rm [<string-files>...]",
+		"[--force]",
+	)
+	cl.RegisterCommand(func(values Values) error { return nil }, "sum [<int[0..9]-digits>...]")
+	cl.RegisterCommand(func(values Values) error { return nil }, "noop")
+
+	cl.StreamValues("rm", "files", func(value any) error {
+		streamed = append(streamed, value.(string))
+		return nil
+	})
+	cl.StreamValues("sum", "digits", func(value any) error { return nil })
+
+	err := cl.ProcessReader([]string{"rm", "a", "--force"}, strings.NewReader("b\r\n\nc d\n"))
+	expectError(t, nil, err)
+	expectString(t, "[a b c d]", fmt.Sprint(streamed))
+	expectString(t, "true 3", handled)
+
+	err = cl.ProcessReader([]string{"sum"}, strings.NewReader("1\n12\n"))
+	expectError(t, NewCommandLineError("Value digits must be in the range 0 to 9"), err)
+
+	err = cl.ProcessReader([]string{"noop"}, strings.NewReader("x\n"))
+	expectError(t, NewCommandLineError("Unexpected command argument: x"), err)
+
+	tokens := NewTokenizer(strings.NewReader("one\n\ntwo"))
+	expectBool(t, true, tokens.Next())
+	expectString(t, "one", tokens.Token())
+	expectBool(t, true, tokens.Next())
+	expectString(t, "two", tokens.Token())
+	expectBool(t, false, tokens.Next())
+	expectError(t, nil, tokens.Err())
+
+	expectPanicError(t, fmt.Errorf("argument error: value \"force\" is not registered for command \"rm\""), func() {
+		cl.StreamValues("rm", "force", func(value any) error { return nil })
+	})
+}
+
+func TestSpecCache(t *testing.T) {
+	cl := NewCommandLine()
+
+	handler := func(values Values) error { return nil }
+	cl.RegisterCommand(handler, "one", "[--verbose|-v]?Verbose output", "[--tag:<string-tag>]")
+	cl.RegisterCommand(handler, "two", "[--verbose|-v]?Verbose output", "[--tag:<string-tag>]")
+	expectValue(t, 4, len(cl.specCache))
+
+	cl.SetOptionGroup("one", "--tag", "Tags")
+	expectString(t, "", cl.commands.values["two"].OptionSpecs.values["--tag"].Group)
+
+	expectPanicError(t, fmt.Errorf("command line template syntax error! expected unique argument \"one\""), func() {
+		cl.RegisterCommand(handler, "one")
+	})
+	expectPanicError(t, fmt.Errorf("command line template syntax error! expected unique argument \"--verbose\""), func() {
+		cl.RegisterGlobalOption(handler, "--verbose")
+	})
+	expectPanicError(t, fmt.Errorf("command line template syntax error! expected unique argument \"-g\""), func() {
+		cl.RegisterGlobalOption(handler, "[--global|-g]")
+		cl.RegisterCommand(handler, "three", "[-g]")
+	})
+}
+
+func BenchmarkRegisterCommands(b *testing.B) {
+	handler := func(values Values) error { return nil }
+	for i := 0; i < b.N; i++ {
+		cl := NewCommandLine()
+		cl.RegisterGlobalOption(handler, "[--verbose|-v]?Verbose output")
+		for n := 0; n < 500; n++ {
+			cl.RegisterCommand(
+				handler,
+				fmt.Sprintf("cmd%d <string-name>?Command %d", n, n),
+				"[--force|-f]?Forces the operation",
+				"[--output:<path-file>]?Output file",
+				"[--retries:<int[0..9]-retries>]?Retry count",
+			)
+		}
+	}
+}
+
+func BenchmarkProcess(b *testing.B) {
+	handler := func(values Values) error { return nil }
+	cl := NewCommandLine()
+	for n := 0; n < 500; n++ {
+		cl.RegisterCommand(handler, fmt.Sprintf("cmd%d <string-name>", n), "[--force|-f]", "[--retries:<int-retries>]")
+	}
+
+	args := []string{"cmd250", "bob", "--force", "--retries:3"}
+	for i := 0; i < b.N; i++ {
+		if err := cl.Process(args); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestLazyCommands(t *testing.T) {
+	cl := NewCommandLine()
+
+	loaded := []string{}
+	deployed := ""
+	cl.RegisterLazyCommand("deploy", func() (*CommandSpecSet, CommandHandler) {
+		loaded = append(loaded, "deploy")
+		return &CommandSpecSet{Specs: []string{"deploy <string-env>?Deploys the app", "[--force]"}},
+			func(values Values) error {
+				deployed = values["env"].(string)
+				return nil
+			}
+	})
+	cl.RegisterLazyCommand("users|create", func() (*CommandSpecSet, CommandHandler) {
+		loaded = append(loaded, "users create")
+		return &CommandSpecSet{Specs: []string{"users|create <string-name>?Creates a user"}},
+			func(values Values) error { return nil }
+	})
+	cl.RegisterCommand(func(values Values) error { return nil }, "status?Shows the status")
+
+	err := cl.Process([]string{"deploy", "prod", "--force"})
+	expectError(t, nil, err)
+	expectString(t, "prod", deployed)
+	expectString(t, "[deploy]", fmt.Sprint(loaded))
+
+	err = cl.Process([]string{"deploy", "test"})
+	expectError(t, nil, err)
+	expectString(t, "[deploy]", fmt.Sprint(loaded))
+
+	err = cl.Process([]string{"usres", "create", "bob"})
+	expectError(t, NewCommandLineError("Unrecognized command: usres; did you mean 'users create'?"), err)
+	expectString(t, "[deploy]", fmt.Sprint(loaded))
+
+	// the words of a name must be consecutive
+	err = cl.Process([]string{"users", "status", "create"})
+	expectError(t, NewCommandLineError("Unrecognized command: users"), err)
+	expectString(t, "[deploy]", fmt.Sprint(loaded))
+
+	output := captureStdout(t, func() {
+		cl.PrintCommands("", false)
+	})
+	expectBool(t, true, strings.Contains(output, "Creates a user"))
+	expectString(t, "[deploy users create]", fmt.Sprint(loaded))
+
+	expectPanicError(t, fmt.Errorf("command line template syntax error! expected unique argument \"status\""), func() {
+		cl.RegisterLazyCommand("status", func() (*CommandSpecSet, CommandHandler) { return nil, nil })
+	})
+
+	cl.RegisterLazyCommand("broken", func() (*CommandSpecSet, CommandHandler) {
+		return &CommandSpecSet{Specs: []string{"other"}}, func(values Values) error { return nil }
+	})
+	expectPanicError(t, fmt.Errorf("argument error: lazy command \"broken\" loaded the spec \"other\""), func() {
+		cl.LoadLazyCommands()
+	})
+}
+
+func TestDryRun(t *testing.T) {
+	cl := NewCommandLine()
+
+	var sb strings.Builder
+	cl.SetOutput(&sb)
+
+	called := false
+	cl.RegisterGlobalOption(func(values Values) error { called = true; return nil }, "--region <string-name>")
+	cl.RegisterCommand(func(values Values) error { called = true; return nil }, "deploy <string-env>", "[--replicas <int-count>]")
+	cl.EnableDryRunOption()
+
+	err := cl.Process([]string{"deploy", "prod", "--replicas", "3", "--region", "east", "--dry-run"})
+	expectError(t, nil, err)
+	expectBool(t, false, called)
+	expectString(t, "Command: deploy\n  --replicas: true\n  count: 3\n  deploy: true\n  env: prod\nGlobal option: --region\n  --region: true\n  name: east\n", sb.String())
+
+	sb.Reset()
+	cl.SetDryRunFormat(JSONDryRun)
+	err = cl.Process([]string{"deploy", "prod", "--dry-run"})
+	expectError(t, nil, err)
+	expectBool(t, false, called)
+	expectString(t, `{"command":"deploy","values":{"--replicas":false,"count":0,"deploy":true,"env":"prod"}}`+"\n", sb.String())
+
+	sb.Reset()
+	err = cl.Process([]string{"deploy", "--dry-run"})
+	expectError(t, NewCommandLineError("Required value env is missing"), err)
+	expectString(t, "", sb.String())
+
+	err = cl.Process([]string{"deploy", "prod"})
+	expectError(t, nil, err)
+	expectBool(t, true, called)
+
+	called = false
+	cl.SetDryRun(true)
+	err = cl.Process([]string{"deploy", "prod"})
+	expectError(t, nil, err)
+	expectBool(t, false, called)
+
+	sb.Reset()
+	cl.SetDryRun(false)
+	cl.SetDryRunFormat(TextDryRun)
+	pc, err := cl.Parse([]string{"deploy", "prod", "--region", "west", "--dry-run"})
+	expectError(t, nil, err)
+	err = pc.Execute(context.Background())
+	expectError(t, nil, err)
+	expectBool(t, false, called)
+	expectString(t, "Command: deploy\n  --replicas: false (default)\n  count: 0 (default)\n  deploy: true\n  env: prod\nGlobal option: --region\n  --region: true\n  name: west\n", sb.String())
+}
+
+type testObserver struct {
+	started []string
+	events  []CommandEvent
+}
+
+func (o *testObserver) CommandStarted(command string) {
+	o.started = append(o.started, command)
+}
+
+func (o *testObserver) CommandFinished(event CommandEvent) {
+	o.events = append(o.events, event)
+}
+
+func TestObserver(t *testing.T) {
+	cl := NewCommandLine()
+
+	failure := errors.New("failed")
+	cl.RegisterCommand(func(values Values) error { time.Sleep(time.Millisecond); return nil }, "build")
+	cl.RegisterCommand(func(values Values) error { return failure }, "deploy")
+
+	o := &testObserver{}
+	cl.SetObserver(o)
+
+	err := cl.Process([]string{"build"})
+	expectError(t, nil, err)
+	err = cl.Process([]string{"deploy"})
+	expectError(t, failure, err)
+	err = cl.Process([]string{"bild"})
+	expectError(t, NewCommandLineError("Unrecognized command: bild; did you mean 'build'?"), err)
+
+	expectString(t, "[build deploy]", fmt.Sprint(o.started))
+	expectValue(t, 3, len(o.events))
+
+	expectString(t, "build", o.events[0].Command)
+	expectBool(t, true, o.events[0].Duration >= time.Millisecond)
+	expectError(t, nil, o.events[0].Err)
+	expectString(t, "", o.events[0].Code)
+	expectValue(t, ExitOK, o.events[0].ExitCode)
+
+	expectString(t, "deploy", o.events[1].Command)
+	expectError(t, failure, o.events[1].Err)
+	expectString(t, "error", o.events[1].Code)
+	expectValue(t, ExitFailure, o.events[1].ExitCode)
+
+	expectString(t, "", o.events[2].Command)
+	expectValue(t, time.Duration(0), o.events[2].Duration)
+	expectString(t, "unknown_command", o.events[2].Code)
+	expectValue(t, ExitUsage, o.events[2].ExitCode)
+
+	cl.SetObserver(nil)
+	err = cl.Process([]string{"build"})
+	expectError(t, nil, err)
+	expectValue(t, 3, len(o.events))
+}
+
+type testLogger struct {
+	lines []string
+}
+
+func (l *testLogger) Debug(msg string, args ...any) {
+	l.lines = append(l.lines, fmt.Sprintln(append([]any{msg}, args...)...))
+}
+
+func TestLogger(t *testing.T) {
+	cl := NewCommandLine()
+
+	failure := errors.New("failed")
+	cl.RegisterGlobalOption(func(values Values) error { return nil }, "--token <secret-token>")
+	cl.RegisterCommand(func(values Values) error { return failure }, "login <string-user>", "[--password <secret-password>]")
+
+	logger := &testLogger{}
+	cl.SetLogger(logger)
+
+	err := cl.Process([]string{"--token", "abc", "login", "ann", "--password", "xyz"})
+	expectError(t, failure, err)
+	expectString(t, "cmdline global option option --token values map[--token:true token:********]\n"+
+		"cmdline command args count 4\n"+
+		"cmdline command matched command login values map[--password:true login:true password:******** user:ann]\n"+
+		"cmdline handler failed command login error failed\n", strings.Join(logger.lines, ""))
+
+	logger.lines = nil
+	err = cl.Process([]string{"logout"})
+	expectError(t, NewCommandLineError("Unrecognized command: logout"), err)
+	expectString(t, "cmdline command args count 1\n"+
+		"cmdline parse failed error Unrecognized command: logout\n", strings.Join(logger.lines, ""))
+
+	logger.lines = nil
+	cl.SetLogger(nil)
+	err = cl.Process([]string{"login", "ann"})
+	expectError(t, failure, err)
+	expectValue(t, 0, len(logger.lines))
+}
+
+func TestExplain(t *testing.T) {
+	cl := NewCommandLine()
+	cl.EnableOptionAbbreviations()
+
+	cl.RegisterGlobalOption(func(values Values) error { return nil }, "--token <secret-token>")
+	cl.RegisterCommand(func(values Values) error { return nil }, "users|create <string-name>", "[--role <string-role=member>]", "[--verbose]")
+
+	steps, err := cl.Explain([]string{"users", "create", "ann", "--verb", "--token", "abc", "--", "x"})
+	expectError(t, nil, err)
+
+	lines := []string{}
+	for _, step := range steps {
+		lines = append(lines, step.String())
+	}
+	expectString(t, `"--token": global option --token
+"********": value --token
+"users": command users create
+"create": command users create
+"ann": value users create
+"--verbose": option --verbose
+default role = member
+"x": rest `+RestArgs, strings.Join(lines, "\n"))
+
+	steps, err = cl.Explain([]string{"users", "create", "ann", "--role"})
+	expectError(t, NewCommandLineError("Required value role is missing"), err)
+	expectValue(t, 4, len(steps))
+	expectValue(t, OptionStep, steps[3].Kind)
+	expectString(t, "--role", steps[3].Token)
+}
+
+func TestGlobalValues(t *testing.T) {
+	cl := NewCommandLine()
+	cl.EnableGlobalValues()
+
+	var got Values
+	cl.RegisterGlobalOption(func(values Values) error { return nil }, "--env <string-name=dev>")
+	cl.RegisterGlobalOption(func(values Values) error { return nil }, "--[no-]color")
+	cl.RegisterGlobalOption(func(values Values) error { return nil }, "--token <secret-token>")
+	cl.RegisterCommand(func(values Values) error { got = values; return nil }, "deploy")
+
+	err := cl.Process([]string{"deploy", "--env", "prod", "--no-color", "--token", "abc"})
+	expectError(t, nil, err)
+	expectValue(t, true, got["global.--env"])
+	expectString(t, "prod", got["global.name"].(string))
+	expectValue(t, false, got["global.--color"])
+	expectValue(t, true, got["global.--no-color"])
+	expectString(t, "abc", got["global.token"].(string))
+	expectString(t, secretMask, cl.LastInvocation().Values["global.token"].(string))
+
+	err = cl.Process([]string{"deploy"})
+	expectError(t, nil, err)
+	expectValue(t, false, got["global.--env"])
+	expectString(t, "dev", got["global.name"].(string))
+	expectValue(t, false, got["global.--color"])
+	expectValue(t, false, got["global.--no-color"])
+	expectString(t, "", got["global.token"].(string))
+}
+
+func TestProcessEx(t *testing.T) {
+	cl := NewCommandLine()
+
+	failure := errors.New("failed")
+	cl.RegisterGlobalOption(func(values Values) error { return nil }, "--env <string-name>")
+	cl.RegisterCommand(func(values Values) error { return failure }, "deploy <string-name>", "[--force]")
+
+	result, err := cl.ProcessEx([]string{"--env", "prod", "deploy", "web", "--force"})
+	expectError(t, failure, err)
+	expectString(t, "deploy", result.Command)
+	expectString(t, "web", result.CommandValues["name"].(string))
+	expectValue(t, true, result.CommandValues["--force"])
+	_, hasContext := result.CommandValues[""]
+	expectBool(t, false, hasContext)
+	expectValue(t, 1, len(result.GlobalValues))
+	expectString(t, "prod", result.GlobalValues["--env"]["name"].(string))
+	expectString(t, "[--env prod deploy web --force]", fmt.Sprint(result.RawArgs))
+
+	result, err = cl.ProcessEx([]string{"deploy"})
+	expectError(t, NewCommandLineError("Required value name is missing"), err)
+	expectBool(t, true, result == nil)
+}
+
+func TestProcessExConcurrentResults(t *testing.T) {
+	cl := NewCommandLine()
+	cl.SetResultRenderer(func(result any) error { return nil })
+
+	// both handlers are running before either returns
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	cl.RegisterResultCommand(func(values Values) (any, error) {
+		arrived.Done()
+		arrived.Wait()
+		return values["text"], nil
+	}, "echo <string-text>")
+
+	results := make([]any, 2)
+	var wg sync.WaitGroup
+	for i, text := range []string{"a", "b"} {
+		wg.Add(1)
+		go func(i int, text string) {
+			defer wg.Done()
+			result, err := cl.ProcessEx([]string{"echo", text})
+			expectError(t, nil, err)
+			results[i] = result.Result
+		}(i, text)
+	}
+	wg.Wait()
+
+	expectValue(t, "a", results[0])
+	expectValue(t, "b", results[1])
+}
+
+func TestValueKeyNames(t *testing.T) {
+	cl := NewCommandLine()
+
+	var globalValues, flagValues, commandValues Values
+	cl.RegisterGlobalOption(func(values Values) error { globalValues = values; return nil }, "--env <string-name>")
+	cl.RegisterGlobalOption(func(values Values) error { flagValues = values; return nil }, "-q")
+	cl.RegisterCommand(func(values Values) error { commandValues = values; return nil }, "deploy <string-target>", "[--region <string-region>]", "[-f]")
+
+	err := cl.Process([]string{"--env", "prod", "-q", "deploy", "web", "--region", "east", "-f"})
+	expectError(t, nil, err)
+
+	expectValue(t, true, globalValues["--env"])
+	expectString(t, "prod", globalValues["name"].(string))
+	expectValue(t, true, flagValues["-q"])
+	expectValue(t, true, commandValues["deploy"])
+	expectString(t, "web", commandValues["target"].(string))
+	expectValue(t, true, commandValues["--region"])
+	expectString(t, "east", commandValues["region"].(string))
+	expectValue(t, true, commandValues["-f"])
+}
+
+func TestPrintTable(t *testing.T) {
+	cl := NewCommandLine()
+
+	var sb strings.Builder
+	cl.SetOutput(&sb)
+
+	cl.PrintTable([]string{"Name", "Size"}, [][]string{{"a.txt", "10"}, {"notes.md", "2048"}})
+	expectString(t, "Name      Size\n--------  ----\na.txt     10\nnotes.md  2048\n", sb.String())
+
+	sb.Reset()
+	cl.SetHelpWidth(24)
+	cl.PrintTable([]string{"Key", "Description"}, [][]string{{"id", "The unique identifier of the record"}, {"n"}})
+	expectString(t, "Key  Description\n"+
+		"---  -------------------\n"+
+		"id   The unique\n"+
+		"     identifier of the\n"+
+		"     record\n"+
+		"n\n", sb.String())
+
+	sb.Reset()
+	cl.SetHelpWidth(12)
+	cl.PrintTable(nil, [][]string{{"x", "abcdefghijkl"}})
+	expectString(t, "x  abcdefghi\n   jkl\n", sb.String())
+}
+
+func TestProgress(t *testing.T) {
+	priorPrn := Prn
+	defer SetPrinter(priorPrn)
+	tp := toolprinter.NewTestPrinter()
+	SetPrinter(tp)
+
+	cl := NewCommandLine()
+	cl.SetHelpWidth(30)
+
+	p := cl.StartProgress("copy", 4)
+	expectString(t, "copy [                  ]   0%", tp.GetStatusText())
+
+	p.Advance(1)
+	expectString(t, "copy [##        ]  25% ETA 0:00", tp.GetStatusText())
+
+	p.Advance(5)
+	expectString(t, "copy [##################] 100%", tp.GetStatusText())
+	expectString(t, tp.GetStatusText(), p.String())
+
+	// taking work back stops at none done
+	p.Advance(-2)
+	expectString(t, "copy [#####     ]  50% ETA 0:00", tp.GetStatusText())
+	p.Advance(-5)
+	expectString(t, "copy [                  ]   0%", tp.GetStatusText())
+
+	p.Finish()
+	expectString(t, "", tp.GetStatusText())
+
+	expectString(t, "1:05", etaText(65*time.Second))
+	expectString(t, "2:00:05", etaText(2*time.Hour+5*time.Second))
+}
+
+func TestSpinner(t *testing.T) {
+	priorPrn := Prn
+	defer SetPrinter(priorPrn)
+	tp := toolprinter.NewTestPrinter()
+	SetPrinter(tp)
+
+	priorTerminal := xterm
+	defer func() { xterm = priorTerminal }()
+	xterm = &testTerminal{width: 80}
+
+	cl := NewCommandLine()
+	cl.SetSpinnerFrames(".", "o", "O")
+
+	s := cl.StartSpinner("waiting")
+	s.mu.Lock()
+	expectString(t, ". waiting", tp.GetStatusText())
+	s.mu.Unlock()
+
+	s.advance()
+	s.SetText("still waiting")
+	s.mu.Lock()
+	expectString(t, "o still waiting", tp.GetStatusText())
+	s.mu.Unlock()
+
+	s.Stop("done")
+	expectString(t, "", tp.GetStatusText())
+	expectString(t, "done\n", tp.String())
+
+	// without a terminal, only the result is printed
+	var sb strings.Builder
+	cl.SetOutput(&sb)
+	s = cl.StartSpinner("waiting")
+	s.Stop("finished")
+	expectString(t, "finished\n", sb.String())
+}
+
+func TestStreams(t *testing.T) {
+	cl := NewCommandLine()
+
+	var help strings.Builder
+	cl.SetOutput(&help)
+
+	output := captureStdout(t, func() {
+		cl.Dataln("row ", 1)
+	})
+	expectString(t, "", output)
+	expectString(t, "row 1\n", help.String())
+
+	var data, diagnostics strings.Builder
+	cl.SetStreams(&data, &diagnostics)
+	cl.Dataln("row ", 2)
+	cl.Errorln("warning: ", "skipped")
+	expectString(t, "row 2\n", data.String())
+	expectString(t, "warning: skipped\n", diagnostics.String())
+	expectString(t, "row 1\n", help.String())
+}
+
+func TestSafePrinter(t *testing.T) {
+	priorTerminal := xterm
+	defer func() { xterm = priorTerminal }()
+	xterm = &testTerminal{width: 12}
+
+	tp := toolprinter.NewTestPrinter()
+	sp := NewSafePrinter(tp)
+	var out strings.Builder
+	sp.out = &out
+
+	sp.StatusLine("a", "worker a: 10%")
+	sp.StatusLine("b", "worker b")
+	expectString(t, "worker a: 1\n"+
+		eraseLineAbove+"worker a: 1\nworker b\n", out.String())
+
+	out.Reset()
+	sp.Println("result")
+	expectString(t, "result\n", tp.String())
+	expectString(t, strings.Repeat(eraseLineAbove, 2)+"worker a: 1\nworker b\n", out.String())
+
+	out.Reset()
+	sp.StatusLine("a", "")
+	expectString(t, strings.Repeat(eraseLineAbove, 2)+"worker b\n", out.String())
+
+	// concurrent printing keeps each line whole
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sp.Println("line ", i)
+			sp.StatusLine(fmt.Sprint(i), "working")
+		}(i)
+	}
+	wg.Wait()
+	expectValue(t, 11, len(tp.GetLines()))
+
+	priorPrn := Prn
+	defer SetPrinter(priorPrn)
+	SetPrinter(sp)
+	out.Reset()
+	cl := NewCommandLine()
+	for i := 0; i < 10; i++ {
+		cl.StatusLine(fmt.Sprint(i), "")
+	}
+	cl.StatusLine("b", "")
+	expectValue(t, 0, sp.drawn)
+	expectValue(t, 0, len(sp.ids))
+	expectString(t, eraseLineAbove, out.String()[out.Len()-len(eraseLineAbove):])
+}
+
+type testPrompter struct {
+	answers []string
+	prompts []string
+}
+
+func (tp *testPrompter) Ask(prompt string) (string, error) {
+	tp.prompts = append(tp.prompts, prompt)
+	if len(tp.answers) == 0 {
+		return "", ErrNoInput
+	}
+	answer := tp.answers[0]
+	tp.answers = tp.answers[1:]
+	return answer, nil
+}
+
+func (tp *testPrompter) AskSecret(prompt string) (string, error) {
+	return tp.Ask(prompt)
+}
+
+func (tp *testPrompter) Confirm(prompt string) (bool, error) {
+	answer, err := tp.Ask(prompt)
+	return answer == "y", err
+}
+
+func TestPrompts(t *testing.T) {
+	p := &stdioPrompter{reader: bufio.NewReader(strings.NewReader("ann\n  YES \nno"))}
+	answer, err := p.Ask("Name? ")
+	expectError(t, nil, err)
+	expectString(t, "ann", answer)
+	confirmed, err := p.Confirm("Continue?")
+	expectError(t, nil, err)
+	expectBool(t, true, confirmed)
+	confirmed, err = p.Confirm("Continue?")
+	expectError(t, nil, err)
+	expectBool(t, false, confirmed)
+	_, err = p.Ask("Name? ")
+	expectError(t, ErrNoInput, err)
+	confirmed, err = p.Confirm("Continue?")
+	expectError(t, nil, err)
+	expectBool(t, false, confirmed)
+
+	// confirmations and secret prompts go through the prompter
+	cl := NewCommandLine()
+	cl.EnableSecretPrompts()
+	var password string
+	cl.RegisterCommand(func(values Values) error { password = values["password"].(string); return nil }, "login <secret-password>")
+	cl.RequireConfirmation("login", "Log in?")
+
+	prompter := &testPrompter{answers: []string{"s3cret", "y"}}
+	cl.SetPrompter(prompter)
+	err = cl.Process([]string{"login"})
+	expectError(t, nil, err)
+	expectString(t, "s3cret", password)
+	expectString(t, "[password:  Log in?]", fmt.Sprint(prompter.prompts))
+
+	answer, err = cl.Ask("Anything? ")
+	expectError(t, ErrNoInput, err)
+	expectString(t, "", answer)
+}
+
+func TestColors(t *testing.T) {
+	cl := NewCommandLine()
+
+	var out, diagnostics strings.Builder
+	cl.SetOutput(&out)
+	cl.SetStreams(nil, &diagnostics)
+
+	// writers aren't terminals, so there's no color by default
+	cl.Success("saved")
+	cl.Warn("slow")
+	cl.ErrorMsg("failed")
+	expectString(t, "saved\n", out.String())
+	expectString(t, "slow\nfailed\n", diagnostics.String())
+
+	out.Reset()
+	diagnostics.Reset()
+	cl.SetColorMode(ColorAlways)
+	cl.Success("saved")
+	cl.Warn("slow")
+	cl.ErrorMsg("failed")
+	expectString(t, "\x1b[32msaved\x1b[0m\n", out.String())
+	expectString(t, "\x1b[33mslow\x1b[0m\n\x1b[31mfailed\x1b[0m\n", diagnostics.String())
+
+	priorTerminal := xterm
+	defer func() { xterm = priorTerminal }()
+	xterm = &testTerminal{width: 80}
+
+	cl.SetColorMode(ColorAuto)
+	t.Setenv("TERM", "xterm")
+	t.Setenv("NO_COLOR", "")
+	expectBool(t, true, cl.useColor(os.Stdout))
+	t.Setenv("NO_COLOR", "1")
+	expectBool(t, false, cl.useColor(os.Stdout))
+	cl.SetColorMode(ColorNever)
+	t.Setenv("NO_COLOR", "")
+	expectBool(t, false, cl.useColor(os.Stdout))
+}
+
+func TestOutputFormat(t *testing.T) {
+	cl := NewCommandLine()
+
+	var sb strings.Builder
+	cl.SetOutput(&sb)
+
+	type user struct {
+		Name  string `json:"name"`
+		Admin bool   `json:"admin,omitempty"`
+		note  string
+	}
+	users := []user{{Name: "ann", Admin: true}, {Name: "bob", note: "x"}}
+
+	var emitErr error
+	cl.RegisterCommand(func(values Values) error { emitErr = cl.Emit(users); return nil }, "users")
+	cl.SetOutputFormatter("csv", func(w io.Writer, v any) error {
+		for _, u := range v.([]user) {
+			fmt.Fprintf(w, "%s,%v\n", u.Name, u.Admin)
+		}
+		return nil
+	})
+	cl.EnableOutputFormat("table", "json", "csv")
+
+	err := cl.Process([]string{"users"})
+	expectError(t, nil, err)
+	expectError(t, nil, emitErr)
+	expectString(t, "table", cl.OutputFormat())
+	expectString(t, "name  admin\n----  -----\nann   true\nbob   false\n", sb.String())
+
+	sb.Reset()
+	err = cl.Process([]string{"-o", "json", "users"})
+	expectError(t, nil, err)
+	expectString(t, "json", cl.OutputFormat())
+	expectString(t, "[\n  {\n    \"name\": \"ann\",\n    \"admin\": true\n  },\n  {\n    \"name\": \"bob\"\n  }\n]\n", sb.String())
+
+	sb.Reset()
+	err = cl.Process([]string{"--output:csv", "users"})
+	expectError(t, nil, err)
+	expectString(t, "ann,true\nbob,false\n", sb.String())
+
+	sb.Reset()
+	err = cl.Process([]string{"users"})
+	expectError(t, nil, err)
+	expectString(t, "table", cl.OutputFormat())
+
+	err = cl.Process([]string{"-o", "yaml", "users"})
+	expectError(t, NewCommandLineError("Output format must be one of: table, json, csv"), err)
+
+	expectPanicError(t, fmt.Errorf("argument error: no formatter for the output format \"yaml\""), func() {
+		NewCommandLine().EnableOutputFormat("yaml")
+	})
+
+	sb.Reset()
+	err = cl.Emit(map[string]int{"b": 2, "a": 1})
+	expectError(t, nil, err)
+	expectString(t, "Name  Value\n----  -----\na     1\nb     2\n", sb.String())
+}
+
+func TestResultCommands(t *testing.T) {
+	cl := NewCommandLine()
+
+	var sb strings.Builder
+	cl.SetOutput(&sb)
+
+	type user struct {
+		Name string `json:"name"`
+	}
+
+	cl.RegisterResultCommand(func(values Values) (any, error) {
+		return []user{{Name: "ann"}, {Name: "bob"}}, nil
+	}, "users")
+	cl.RegisterResultCommand(func(values Values) (any, error) {
+		return nil, errors.New("failed")
+	}, "fail")
+	cl.RegisterResultCommandCtx(func(ctx context.Context, values Values) (any, error) {
+		return nil, nil
+	}, "nothing")
+	cl.SetOutputFormatter("names", NewTemplateFormatter("{{range .}}{{.Name}};{{end}}"))
+	cl.EnableOutputFormat("json", "table", "names")
+
+	err := cl.Process([]string{"users"})
+	expectError(t, nil, err)
+	expectString(t, "[\n  {\n    \"name\": \"ann\"\n  },\n  {\n    \"name\": \"bob\"\n  }\n]\n", sb.String())
+	expectValue(t, 2, len(cl.LastResult().([]user)))
+
+	sb.Reset()
+	err = cl.Process([]string{"-o", "table", "users"})
+	expectError(t, nil, err)
+	expectString(t, "name\n----\nann\nbob\n", sb.String())
+
+	sb.Reset()
+	err = cl.Process([]string{"-o", "names", "users"})
+	expectError(t, nil, err)
+	expectString(t, "ann;bob;\n", sb.String())
+
+	sb.Reset()
+	err = cl.Process([]string{"fail"})
+	expectError(t, errors.New("failed"), err)
+	expectString(t, "", sb.String())
+	expectBool(t, true, cl.LastResult() == nil)
+
+	result, err := cl.ProcessEx([]string{"users"})
+	expectError(t, nil, err)
+	expectValue(t, "bob", result.Result.([]user)[1].Name)
+
+	result, err = cl.ProcessEx([]string{"nothing"})
+	expectError(t, nil, err)
+	expectBool(t, true, result.Result == nil)
+
+	// a custom renderer replaces Emit
+	var rendered any
+	cl.SetResultRenderer(func(result any) error { rendered = result; return nil })
+	sb.Reset()
+	err = cl.Process([]string{"users"})
+	expectError(t, nil, err)
+	expectString(t, "", sb.String())
+	expectValue(t, "ann", rendered.([]user)[0].Name)
+
+	cl.SetResultRenderer(func(result any) error { return errors.New("render failed") })
+	err = cl.Process([]string{"users"})
+	expectError(t, errors.New("render failed"), err)
+
+	expectPanicError(t, fmt.Errorf("argument error: output template: template: output:1: unclosed action"), func() {
+		NewTemplateFormatter("{{.Name")
+	})
+}
+
+func TestCommandChain(t *testing.T) {
+	cl := NewCommandLine()
+
+	ran := []string{}
+	env := ""
+	cl.RegisterGlobalOption(func(values Values) error { env = values["env"].(string); return nil }, "--env <string-env>")
+	cl.RegisterCommand(func(values Values) error {
+		ran = append(ran, fmt.Sprintf("create %s %s", values["user"], env))
+		return nil
+	}, "users+create <string-user>")
+	cl.RegisterCommand(func(values Values) error {
+		ran = append(ran, fmt.Sprintf("grant %s %s %v", values["user"], values["role"], values[RestArgs]))
+		return nil
+	}, "users+grant <string-user> <string-role>")
+	cl.RegisterCommand(func(values Values) error { return errors.New("failed") }, "fail")
+
+	// without a separator, ';' is an unexpected arg
+	err := cl.Process([]string{"users", "create", "ann", ";", "users", "grant", "ann", "admin"})
+	expectBool(t, true, err != nil)
+	expectValue(t, 0, len(ran))
+
+	cl.SetCommandSeparator(";")
+	err = cl.Process([]string{"--env", "prod", "users", "create", "ann", ";", "users", "grant", "ann", "admin", ";"})
+	expectError(t, nil, err)
+	expectString(t, "[create ann prod grant ann admin <nil>]", fmt.Sprint(ran))
+	expectString(t, "users grant", cl.LastInvocation().Command)
+
+	// the args after "--" go to the last command
+	ran = []string{}
+	err = cl.Process([]string{"users", "create", "bob", ";", "users", "grant", "bob", "dev", "--", "x"})
+	expectError(t, nil, err)
+	expectString(t, "[create bob prod grant bob dev [x]]", fmt.Sprint(ran))
+
+	// an invalid command stops all of them
+	ran = []string{}
+	err = cl.Process([]string{"users", "create", "cat", ";", "users", "grant", "cat"})
+	expectError(t, NewCommandLineError("Required value role is missing"), err)
+	expectValue(t, 0, len(ran))
+
+	// a failing command stops the rest
+	o := &testObserver{}
+	cl.SetObserver(o)
+	err = cl.Process([]string{"users", "create", "dan", ";", "fail", ";", "users", "create", "eve"})
+	expectError(t, errors.New("failed"), err)
+	expectString(t, "[create dan prod]", fmt.Sprint(ran))
+	expectString(t, "[users create fail]", fmt.Sprint(o.started))
+	expectValue(t, 2, len(o.events))
+	expectError(t, errors.New("failed"), o.events[1].Err)
+
+	cl.SetCommandSeparator("")
+	err = cl.Process([]string{"users", "create", "fay", ";"})
+	expectBool(t, true, err != nil)
+
+	expectPanicError(t, fmt.Errorf("argument error: the command separator can't be the \"--\" terminator"), func() {
+		cl.SetCommandSeparator("--")
+	})
+}
+
+func TestAliases(t *testing.T) {
+	cl := NewCommandLine()
+
+	var ran string
+	cl.RegisterGlobalOption(func(values Values) error { return nil }, "--env <string-env>")
+	cl.RegisterCommand(func(values Values) error {
+		ran = fmt.Sprintf("users list=%v admins=%v", values["--list"], values["--admins"])
+		return nil
+	}, "users", "[--list]", "[--admins]")
+	cl.RegisterCommand(func(values Values) error { ran = "status"; return nil }, "status")
+
+	cl.DefineAlias("ls", "users --list")
+	cl.DefineAlias("status", "users")
+
+	err := cl.Process([]string{"ls"})
+	expectError(t, nil, err)
+	expectString(t, "users list=true admins=false", ran)
+
+	// args after the alias follow the expansion, and global options still apply
+	err = cl.Process([]string{"--env", "prod", "ls", "--admins"})
+	expectError(t, nil, err)
+	expectString(t, "users list=true admins=true", ran)
+
+	// a command takes precedence over an alias
+	err = cl.Process([]string{"status"})
+	expectError(t, nil, err)
+	expectString(t, "status", ran)
+
+	steps, err := cl.Explain([]string{"ls"})
+	expectError(t, nil, err)
+	expectString(t, `"ls": alias users --list`, steps[0].String())
+	expectString(t, `"users": command users`, steps[1].String())
+
+	err = cl.LoadAliases(strings.NewReader(`{"admins": "users --list --admins", "all": "'users' --list"}`))
+	expectError(t, nil, err)
+	err = cl.Process([]string{"admins"})
+	expectError(t, nil, err)
+	expectString(t, "users list=true admins=true", ran)
+
+	err = cl.LoadAliases(strings.NewReader(`{"bad": ""}`))
+	expectString(t, "alias file error: argument error: alias \"bad\" has no expansion", err.Error())
+	err = cl.LoadAliases(strings.NewReader(`["ls"]`))
+	expectBool(t, true, strings.HasPrefix(err.Error(), "alias file error: json:"))
+
+	expectPanicError(t, fmt.Errorf("argument error: alias name \"--ls\" must be a single word"), func() {
+		cl.DefineAlias("--ls", "users")
+	})
+	expectPanicError(t, fmt.Errorf("argument error: alias \"ls\": Unterminated \" quote in command line"), func() {
+		cl.DefineAlias("ls", `users "`)
+	})
+}
+
+func TestBindGoFlags(t *testing.T) {
+	cl := NewCommandLine()
+
+	var sb strings.Builder
+	cl.SetOutput(&sb)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	logDir := fs.String("log_dir", "", "If non-empty, write log files in this `dir`")
+	toStderr := fs.Bool("logtostderr", true, "log to standard error")
+	timeout := fs.Duration("timeout", time.Second, "the request timeout")
+	level := fs.Int("v", 0, "log level")
+	trace := fs.Bool("trace", false, "trace calls")
+	testV := fs.Bool("test.v", false, "verbose test output")
+
+	cl.RegisterGlobalOption(func(values Values) error { return nil }, "--trace?The app's own trace option")
+	cl.RegisterCommand(func(values Values) error { return nil }, "run")
+	cl.BindGoFlags(fs)
+
+	err := cl.Process([]string{"--log_dir", "/tmp/logs", "-logtostderr=false", "-timeout=5s", "-v", "2", "--trace", "run"})
+	expectError(t, nil, err)
+	expectString(t, "/tmp/logs", *logDir)
+	expectBool(t, false, *toStderr)
+	expectValue(t, 5*time.Second, *timeout)
+	expectValue(t, 2, *level)
+	expectBool(t, false, *trace)
+
+	err = cl.Process([]string{"--logtostderr", "run"})
+	expectError(t, nil, err)
+	expectBool(t, true, *toStderr)
+
+	err = cl.Process([]string{"-timeout", "soon", "run"})
+	expectString(t, "Invalid value soon for -timeout: parse error", err.Error())
+	expectBool(t, true, errors.Is(err, ErrInvalidValue))
+
+	// a flag that isn't a valid option name is skipped
+	err = cl.Process([]string{"-test.v", "run"})
+	expectError(t, NewCommandLineError("Unrecognized command: -test.v"), err)
+	expectBool(t, false, *testV)
+
+	cl.PrintCommands("", true)
+	expectBool(t, true, strings.Contains(sb.String(), "--log_dir|-log_dir <dir>"))
+	expectBool(t, true, strings.Contains(sb.String(), "log to standard error (default true)"))
+	expectBool(t, false, strings.Contains(sb.String(), "test.v"))
+
+	expectPanicError(t, fmt.Errorf("argument error: BindGoFlags requires a flag set"), func() {
+		cl.BindGoFlags(nil)
+	})
+}
+
+func TestValueSources(t *testing.T) {
+	t.Setenv("CMDLINE_TEST_HOME", "/home/ann")
+
+	cl := NewCommandLine()
+
+	var sb strings.Builder
+	cl.SetOutput(&sb)
+
+	var got map[string]ValueSource
+	var kept []Values
+	names := []string{"token", "port", "dir", "--tls", "serve", "global.env", "global.--env", "missing"}
+	cl.RegisterGlobalOption(func(values Values) error { return nil }, "--env <string-env=dev>")
+	cl.RegisterCommand(
+		func(values Values) error {
+			got = map[string]ValueSource{}
+			for _, name := range names {
+				got[name] = values.Source(name)
+			}
+			kept = append(kept, values)
+			return nil
+		},
+		"serve <secret-token>",
+		"[--port <int-port=8080>]",
+		"[--home <string-dir=${CMDLINE_TEST_HOME}>]",
+		"[--tls]",
+	)
+	cl.EnableGlobalValues()
+	cl.EnableSecretPrompts()
+	cl.EnableValueSources()
+	cl.SetPrompter(&testPrompter{answers: []string{"s3cret"}})
+
+	err := cl.Process([]string{"--env", "prod", "serve", "--port", "80"})
+	expectError(t, nil, err)
+	expectValue(t, SourcePrompt, got["token"])
+	expectValue(t, SourceCommandLine, got["port"])
+	expectValue(t, SourceEnvironment, got["dir"])
+	expectValue(t, SourceDefault, got["--tls"])
+	expectValue(t, SourceCommandLine, got["serve"])
+	expectValue(t, SourceCommandLine, got["global.env"])
+	expectValue(t, ValueSource(""), got["missing"])
+
+	err = cl.Process([]string{"serve", "abc"})
+	expectError(t, nil, err)
+	expectValue(t, SourceCommandLine, got["token"])
+	expectValue(t, SourceDefault, got["port"])
+	expectValue(t, SourceDefault, got["global.env"])
+	expectValue(t, SourceDefault, got["global.--env"])
+
+	// the values keep their sources after Process returns
+	expectValue(t, SourceCommandLine, kept[0].Source("port"))
+	expectValue(t, SourceDefault, kept[1].Source("port"))
+	expectValue(t, SourceDefault, copyValues(kept[1]).Source("port"))
+
+	inv := cl.LastInvocation()
+	expectValue(t, SourceDefault, inv.Source("port"))
+	expectValue(t, SourceDefault, inv.Values.Source("port"))
+
+	pc, err := cl.Parse([]string{"serve", "abc", "--port", "80"})
+	expectError(t, nil, err)
+	expectValue(t, SourceCommandLine, pc.Source("port"))
+	expectValue(t, SourceDefault, pc.Values.Source("--tls"))
+	expectValue(t, SourceDefault, inv.Source("port"))
+
+	cl.SetDryRun(true)
+	err = cl.Process([]string{"serve", "abc", "--tls"})
+	expectError(t, nil, err)
+	expectString(t, "Command: serve\n"+
+		"  --home: false (default)\n"+
+		"  --port: false (default)\n"+
+		"  --tls: true\n"+
+		"  dir: /home/ann (environment)\n"+
+		"  global.--env: false (default)\n"+
+		"  global.env: dev (default)\n"+
+		"  port: 8080 (default)\n"+
+		"  serve: true\n"+
+		"  token: ********\n", sb.String())
+}
+
+func TestValueSourcesNotKept(t *testing.T) {
+	cl := NewCommandLine()
+
+	var sb strings.Builder
+	cl.SetOutput(&sb)
+
+	var kept Values
+	cl.RegisterGlobalOption(func(values Values) error { return nil }, "--env <string-env=dev>")
+	cl.RegisterCommand(
+		func(values Values) error {
+			kept = values
+			return nil
+		},
+		"run",
+		"[--x:<int-x=5>]",
+	)
+
+	err := cl.Process([]string{"run"})
+	expectError(t, nil, err)
+	expectValue(t, "map[:<nil> --x:false run:true x:5]", fmt.Sprint(kept))
+	expectValue(t, ValueSource(""), kept.Source("x"))
+
+	inv := cl.LastInvocation()
+	expectValue(t, SourceDefault, inv.Source("x"))
+	expectValue(t, SourceDefault, inv.Source("--x"))
+	expectValue(t, ValueSource(""), inv.Source("missing"))
+
+	err = cl.Process([]string{"--env", "prod", "run", "--x:1"})
+	expectError(t, nil, err)
+	expectValue(t, SourceDefault, inv.Source("x"))
+	expectValue(t, SourceCommandLine, cl.LastInvocation().Source("x"))
+	expectValue(t, SourceCommandLine, cl.LastInvocation().GlobalOptions[0].Source("env"))
+
+	// the sources are owned by the parsed command, however long it is kept
+	pc, err := cl.Parse([]string{"run"})
+	expectError(t, nil, err)
+	runtime.GC()
+	expectValue(t, SourceDefault, pc.Source("x"))
+
+	cl.SetDryRun(true)
+	err = cl.Process([]string{"run"})
+	expectError(t, nil, err)
+	expectString(t, "Command: run\n"+
+		"  --x: false (default)\n"+
+		"  run: true\n"+
+		"  x: 5 (default)\n", sb.String())
+}
+
+func TestClone(t *testing.T) {
+	base := NewCommandLine()
+
+	ran := ""
+	base.RegisterGlobalOption(func(values Values) error { return nil }, "--env <string-env>")
+	base.RegisterCommand(func(values Values) error { ran = fmt.Sprint("users ", values["count"]); return nil }, "users", "[--count <int-count=1>]")
+	base.DefineAlias("u", "users")
+
+	variant := base.Clone()
+	variant.RegisterCommand(func(values Values) error { ran = "experiment"; return nil }, "experiment")
+	variant.AddValidator("count", func(value any) error {
+		if value.(int) > 5 {
+			return errors.New("too many")
+		}
+		return nil
+	})
+	variant.AddExample("users", "users --count 2", "Lists two users")
+	variant.DefineAlias("x", "experiment")
+
+	err := variant.Process([]string{"--env", "dev", "u", "--count", "3"})
+	expectError(t, nil, err)
+	expectString(t, "users 3", ran)
+	err = variant.Process([]string{"x"})
+	expectError(t, nil, err)
+	expectString(t, "experiment", ran)
+	err = variant.Process([]string{"users", "--count", "9"})
+	expectError(t, errors.New("too many"), err)
+
+	// the original is unchanged
+	err = base.Process([]string{"experiment"})
+	expectBool(t, true, errors.Is(err, ErrUnknownCommand))
+	err = base.Process([]string{"users", "--count", "9"})
+	expectError(t, nil, err)
+	expectString(t, "users 9", ran)
+	expectValue(t, 0, len(base.lookupCommand("users").Examples))
+	expectValue(t, 1, len(variant.lookupCommand("users").Examples))
+
+	// a command spec of the clone is bound to the clone
+	expectBool(t, true, variant.lookupCommand("users").PrimaryArgSpec.CmdLine == variant)
+
+	// a streamed value stays streamed
+	streamed := []string{}
+	base.RegisterCommand(func(values Values) error { return nil }, "load *<string-file>")
+	base.StreamValues("load", "file", func(value any) error { streamed = append(streamed, value.(string)); return nil })
+	err = base.Clone().Process([]string{"load", "a", "b"})
+	expectError(t, nil, err)
+	expectString(t, "[a b]", fmt.Sprint(streamed))
+}
+
+func TestCommandEnabled(t *testing.T) {
+	cl := NewCommandLine()
+
+	var sb strings.Builder
+	cl.SetOutput(&sb)
+
+	beta := false
+	ran := ""
+	cl.RegisterCommand(func(values Values) error { ran = "build"; return nil }, "build?Builds")
+	cl.RegisterCommand(func(values Values) error { ran = "builder"; return nil }, "builder?Beta builder")
+	cl.SetCommandEnabled("builder", func() bool { return beta })
+
+	err := cl.Process([]string{"builder"})
+	expectError(t, NewCommandLineError("Unrecognized command: builder"), err)
+	expectBool(t, true, errors.Is(err, ErrUnknownCommand))
+	expectString(t, "", ran)
+
+	cl.PrintCommands("", false)
+	expectBool(t, false, strings.Contains(sb.String(), "builder"))
+	expectString(t, "[build]", fmt.Sprint(cl.Complete([]string{"bu"})))
+	expectValue(t, 1, len(cl.Describe().Commands))
+	_, err = cl.Synopsis("builder")
+	expectError(t, errors.New("command \"builder\" not found"), err)
+
+	// the predicate is asked each time
+	beta = true
+	err = cl.Process([]string{"builder"})
+	expectError(t, nil, err)
+	expectString(t, "builder", ran)
+	expectString(t, "[build builder]", fmt.Sprint(cl.Complete([]string{"bu"})))
+
+	sb.Reset()
+	cl.PrintCommands("", false)
+	expectBool(t, true, strings.Contains(sb.String(), "Beta builder"))
+
+	beta = false
+	cl.SetCommandEnabled("builder", nil)
+	err = cl.Process([]string{"builder"})
+	expectError(t, nil, err)
+
+	expectPanicError(t, fmt.Errorf("argument error: command \"deploy\" is not registered"), func() {
+		cl.SetCommandEnabled("deploy", func() bool { return true })
+	})
+
+	cl = NewCommandLine()
+	ran = ""
+	cl.RegisterCommand(func(values Values) error { ran = "~"; return nil }, "~ [<string-name>]")
+	cl.SetCommandEnabled("", func() bool { return false })
+
+	err = cl.Process([]string{"hello"})
+	expectError(t, NewCommandLineError("Unrecognized command: hello"), err)
+	expectBool(t, true, errors.Is(err, ErrUnknownCommand))
+	err = cl.Process([]string{})
+	expectError(t, NewCommandLineError("A command is required"), err)
+	expectString(t, "", ran)
+
+	cl.SetCommandEnabled("", nil)
+	err = cl.Process([]string{"hello"})
+	expectError(t, nil, err)
+	expectString(t, "~", ran)
+}
+
+func TestDetailedHelp(t *testing.T) {
+	cl := NewCommandLine()
+
+	var sb strings.Builder
+	cl.SetOutput(&sb)
+	cl.EnableAutoHelp()
+
+	cl.RegisterGlobalOption(func(values Values) error { return nil }, "--env <string-name=dev>?The environment")
+	cl.RegisterCommand(func(values Values) error { return nil }, "deploy <string/[a-z]+/-app>?Deploys an app", "[--replicas <int[1..9]-count=2>]?The replica count", "[--force]?Skips checks")
+	cl.RegisterCommand(func(values Values) error { return nil }, "status?Shows status")
+
+	err := cl.Process([]string{"deploy", "--help"})
+	expectError(t, ErrHelpShown, err)
+	expectString(t, "deploy <app>                  Deploys an app\n"+
+		"  [--replicas <count[1..9]>]  The replica count\n"+
+		"  [--force]                   Skips checks\n", sb.String())
+
+	sb.Reset()
+	err = cl.Process([]string{"deploy", "--help", "--full"})
+	expectError(t, ErrHelpShown, err)
+	expectString(t, "deploy <app>                  Deploys an app\n"+
+		"  app: string, pattern [a-z]+\n"+
+		"  [--replicas <count[1..9]>]  The replica count\n"+
+		"    count: int, range 1..9, default 2\n"+
+		"  [--force]                   Skips checks\n", sb.String())
+
+	sb.Reset()
+	cl.PrintCommandsDetailed("status")
+	expectString(t, "Matching Commands:\n\n  status  Shows status\n\n", sb.String())
+
+	sb.Reset()
+	cl.PrintCommandsDetailed("env")
+	expectBool(t, true, strings.Contains(sb.String(), "    name: string, default dev\n"))
+
+	// --full is a normal option when the app registers it
+	cl.RegisterCommand(func(values Values) error { return nil }, "build", "[--full]")
+	err = cl.Process([]string{"build", "--full"})
+	expectError(t, nil, err)
+}
+
+func TestSynopsis(t *testing.T) {
+	cl := NewCommandLine()
+
+	var sb strings.Builder
+	cl.SetOutput(&sb)
+
+	cl.RegisterCommand(func(values Values) error { return nil }, "users?Manages users", "[--create <string-name>]?Adds a user", "[--delete <string-user>]?Removes a user", "[--list]?Lists users", "[-v|--verbose]")
+	cl.RegisterCommand(func(values Values) error { return nil }, "copy <string-src> [<string-dest>]?Copies a file", "*--tag:<string-tag>", "--mode <string-mode>")
+	cl.RegisterCommand(func(values Values) error { return nil }, "sync?Syncs", "[--pull]", "[--push]", "[--[no-]prune]")
+	cl.Conflicts("users", "--create", "--delete", "--list")
+	cl.Conflicts("sync", "--pull", "--push")
+	cl.RequireAtLeastOneOf("sync", "--pull", "--push")
+
+	text, err := cl.Synopsis("users")
+	expectError(t, nil, err)
+	expectString(t, "users [--create <name> | --delete <user> | --list] [-v]", text)
+
+	text, err = cl.Synopsis("copy")
+	expectError(t, nil, err)
+	expectString(t, "copy <src> [<dest>] --tag:<tag>... --mode <mode>", text)
+
+	text, err = cl.Synopsis("sync")
+	expectError(t, nil, err)
+	expectString(t, "sync [--pull | --push] [--[no-]prune]", text)
+
+	_, err = cl.Synopsis("missing")
+	expectString(t, "command \"missing\" not found", err.Error())
+
+	// shown at the top of the command help once enabled
+	err = cl.PrintCommand("sync")
+	expectError(t, nil, err)
+	expectBool(t, false, strings.HasPrefix(sb.String(), "Usage:"))
+
+	sb.Reset()
+	cl.EnableSynopsis()
+	err = cl.PrintCommand("sync")
+	expectError(t, nil, err)
+	expectBool(t, true, strings.HasPrefix(sb.String(), "Usage: sync [--pull | --push] [--[no-]prune]\n\nsync"))
+}
+
+func TestUnknownOptionSuggestion(t *testing.T) {
+	cl := NewCommandLine()
+
+	cl.RegisterCommand(func(values Values) error { return nil }, "install <string-pkg>", "[--force]", "[-q|--quiet]", "[--[no-]cache]")
+	cl.RegisterCommand(func(values Values) error { return nil }, "clean")
+
+	err := cl.Process([]string{"install", "app", "--forse"})
+	expectError(t, NewCommandLineError("Unrecognized command argument: --forse; did you mean '--force'?"), err)
+	expectBool(t, true, errors.Is(err, ErrUnknownOption))
+
+	var cle *CommandLineError
+	expectBool(t, true, errors.As(err, &cle))
+	expectString(t, "--force", cle.Suggestion())
+	expectValue(t, 0, len(cle.Alternatives()))
+
+	err = cl.Process([]string{"install", "app", "--no-cahce"})
+	expectError(t, NewCommandLineError("Unrecognized command argument: --no-cahce; did you mean '--no-cache'?"), err)
+
+	// nothing close lists the options
+	err = cl.Process([]string{"install", "app", "--recursive"})
+	expectError(t, NewCommandLineError("Unrecognized command argument: --recursive; valid options: --force, -q, --cache"), err)
+	expectBool(t, true, errors.As(err, &cle))
+	expectString(t, "--force -q --cache", strings.Join(cle.Alternatives(), " "))
+	expectString(t, "--force -q --cache", strings.Join(NewErrorReport(err).Suggestions, " "))
+
+	// an extra value or a command without options has no hint
+	err = cl.Process([]string{"install", "app", "extra"})
+	expectError(t, NewCommandLineError("Unrecognized command argument: extra"), err)
+	err = cl.Process([]string{"clean", "--all"})
+	expectError(t, NewCommandLineError("Unrecognized command argument: --all"), err)
+
+	cl.SetTranslator(MessageCatalog{
+		"Unrecognized command argument: %s": "Argument inconnu : %s",
+		"; valid options: %s":               " ; options valides : %s",
+	})
+	err = cl.Process([]string{"install", "app", "--recursive"})
+	expectError(t, NewCommandLineError("Argument inconnu : --recursive ; options valides : --force, -q, --cache"), err)
+}
+
+func TestUnknownArgPolicy(t *testing.T) {
+	cl := NewCommandLine()
+
+	var got Values
+	cl.RegisterCommand(func(values Values) error {
+		got = values
+		return nil
+	}, "run <string-tool>", "[--verbose]")
+
+	err := cl.Process([]string{"run", "gcc", "-O2", "--verbose"})
+	expectError(t, NewCommandLineError("Unrecognized command argument: -O2; valid options: --verbose"), err)
+
+	cl.SetUnknownArgPolicy(UnknownArgsCollect)
+	err = cl.Process([]string{"run", "gcc", "-O2", "--verbose", "main.c", "--std=c11"})
+	expectError(t, nil, err)
+	expectString(t, "gcc", got["tool"].(string))
+	expectBool(t, true, got["--verbose"].(bool))
+	expectString(t, "-O2 main.c --std=c11", strings.Join(got[UnknownArgsKey].([]string), " "))
+
+	err = cl.Process([]string{"run", "gcc"})
+	expectError(t, nil, err)
+	expectValue(t, 0, len(got[UnknownArgsKey].([]string)))
+
+	cl.SetUnknownArgPolicy(UnknownArgsIgnore)
+	err = cl.Process([]string{"run", "gcc", "-O2", "--verbose"})
+	expectError(t, nil, err)
+	expectBool(t, true, got["--verbose"].(bool))
+	_, exists := got[UnknownArgsKey]
+	expectBool(t, false, exists)
+}
+
+func TestOrderedOptions(t *testing.T) {
+	cl := NewCommandLine()
+
+	debug := false
+	cl.RegisterGlobalOption(func(values Values) error {
+		debug = true
+		return nil
+	}, "[--debug]")
+
+	var got Values
+	cl.RegisterCommand(func(values Values) error {
+		got = values
+		return nil
+	}, "exec <string-tool> [<int-level>]", "[-v|--verbose]", "[--env <string-name>]")
+
+	// by default, options follow the values, and global options go anywhere
+	err := cl.Process([]string{"exec", "gcc", "--verbose", "--debug"})
+	expectError(t, nil, err)
+	expectBool(t, true, got["-v"].(bool))
+	expectBool(t, true, debug)
+
+	err = cl.Process([]string{"exec", "--verbose", "gcc"})
+	expectError(t, NewCommandLineError("Required value tool is missing"), err)
+
+	cl.SetOptionParsing(OrderedOptions)
+
+	debug = false
+	err = cl.Process([]string{"--debug", "exec", "-v", "--env=prod", "gcc", "-5"})
+	expectError(t, nil, err)
+	expectBool(t, true, debug)
+	expectBool(t, true, got["-v"].(bool))
+	expectString(t, "prod", got["name"].(string))
+	expectString(t, "gcc", got["tool"].(string))
+	expectValue(t, -5, got["level"].(int))
+
+	// the first value ends option parsing
+	debug = false
+	err = cl.Process([]string{"exec", "gcc", "--verbose", "--debug"})
+	expectError(t, NewCommandLineError("Unrecognized command argument: --verbose"), err)
+
+	cl.SetUnknownArgPolicy(UnknownArgsCollect)
+	err = cl.Process([]string{"exec", "gcc", "--verbose", "--debug"})
+	expectError(t, nil, err)
+	expectBool(t, false, debug)
+	expectBool(t, false, got["-v"].(bool))
+	expectString(t, "--verbose --debug", strings.Join(got[UnknownArgsKey].([]string), " "))
+}
+
+func TestDuplicateOptionPolicy(t *testing.T) {
+	cl := NewCommandLine()
+
+	envs := []string{}
+	cl.RegisterGlobalOption(func(values Values) error {
+		envs = append(envs, values["env"].(string))
+		return nil
+	}, "[--env <string-env>]")
+
+	var got Values
+	cl.RegisterCommand(func(values Values) error {
+		got = values
+		return nil
+	}, "build", "[--out <string-file>]", "*[--tag <string-tag>]", "[--[no-]cache]")
+
+	// the last occurrence wins by default, in colon, space and equals forms
+	err := cl.Process([]string{"build", "--out:a", "--out", "b", "--out=c", "--tag", "x", "--tag", "y"})
+	expectError(t, nil, err)
+	expectString(t, "c", got["file"].(string))
+	expectString(t, "x y", strings.Join(got["tag"].([]string), " "))
+
+	err = cl.Process([]string{"--env", "dev", "build", "--env=prod"})
+	expectError(t, nil, err)
+	expectString(t, "dev prod", strings.Join(envs, " "))
+
+	cl.SetDuplicateOptionPolicy(DuplicatesFirst)
+	envs = []string{}
+	err = cl.Process([]string{"--env:dev", "build", "--out=a", "--out", "b", "--out:c", "--cache", "--no-cache", "--env", "prod"})
+	expectError(t, nil, err)
+	expectString(t, "a", got["file"].(string))
+	expectBool(t, true, got["--cache"].(bool))
+	expectString(t, "dev", strings.Join(envs, " "))
+
+	cl.SetDuplicateOptionPolicy(DuplicatesError)
+	for _, args := range [][]string{
+		{"build", "--out:a", "--out:b"},
+		{"build", "--out", "a", "--out", "b"},
+		{"build", "--out=a", "--out=b"},
+	} {
+		err = cl.Process(args)
+		expectError(t, NewCommandLineError("Argument --out is given more than once"), err)
+		expectBool(t, true, errors.Is(err, ErrDuplicateOption))
+	}
+
+	err = cl.Process([]string{"build", "--cache", "--no-cache"})
+	expectError(t, NewCommandLineError("Argument --cache is given more than once"), err)
+
+	err = cl.Process([]string{"--env", "dev", "build", "--env", "prod"})
+	expectError(t, NewCommandLineError("Argument --env is given more than once"), err)
+
+	// repeatable options aren't duplicates
+	err = cl.Process([]string{"build", "--tag", "x", "--tag", "y"})
+	expectError(t, nil, err)
+}
+
+func TestDuplicateOptionPolicyCounts(t *testing.T) {
+	cl := NewCommandLine()
+
+	var got Values
+	cl.RegisterCommand(func(values Values) error {
+		got = values
+		return nil
+	}, "build", "[-d <count-debug>]")
+	cl.EnableVerbosity()
+	cl.SetDuplicateOptionPolicy(DuplicatesError)
+
+	// counts and the verbosity options add up their repeats
+	err := cl.Process([]string{"-v", "-v", "build", "-d", "-d"})
+	expectError(t, nil, err)
+	expectValue(t, 2, cl.Verbosity())
+	expectValue(t, 2, got["debug"])
+
+	err = cl.Process([]string{"-vv", "-v", "-vv", "build"})
+	expectError(t, nil, err)
+	expectValue(t, 5, cl.Verbosity())
+
+	err = cl.Process([]string{"--quiet", "--quiet", "build"})
+	expectError(t, NewCommandLineError("Argument --quiet is given more than once"), err)
+}
+
+func TestNormalizers(t *testing.T) {
+	cl := NewCommandLine()
+
+	var got Values
+	cl.RegisterCommand(func(values Values) error {
+		got = values
+		return nil
+	}, "invite <string:trim:lower-email>", "[--role <string:upper-role=${ROLE}>]", "*[--tag <string-tag>]", "[--count <int-count>]")
+
+	cl.AddNormalizer("tag", strings.ToLower)
+	cl.AddNormalizer("tag", func(text string) string { return strings.TrimPrefix(text, "#") })
+	cl.AddNormalizer("count", strings.TrimSpace)
+	cl.AddValidator("email", func(value any) error {
+		if value.(string) != strings.ToLower(value.(string)) {
+			return errors.New("not normalized")
+		}
+		return nil
+	})
+
+	t.Setenv("ROLE", "viewer")
+	err := cl.Process([]string{"invite", "  Ann@Example.COM ", "--tag", "#Beta", "--tag", "Ops", "--count", " 3 "})
+	expectError(t, nil, err)
+	expectString(t, "ann@example.com", got["email"].(string))
+	expectString(t, "VIEWER", got["role"].(string))
+	expectString(t, "beta ops", strings.Join(got["tag"].([]string), " "))
+	expectValue(t, 3, got["count"].(int))
+
+	err = cl.Process([]string{"invite", "ann@example.com", "--role", "admin"})
+	expectError(t, nil, err)
+	expectString(t, "ADMIN", got["role"].(string))
+
+	expectPanicError(t, &SpecError{Spec: "greet <string:title-name>", Column: 15, Expected: "normalizer trim, lower or upper"}, func() {
+		cl.RegisterCommand(func(values Values) error { return nil }, "greet <string:title-name>")
+	})
+	expectPanicError(t, errors.New("argument error: a normalizer requires a value name and a function"), func() {
+		cl.AddNormalizer("name", nil)
+	})
+}
+
+func TestRegisterOptionType(t *testing.T) {
+	cl := NewCommandLine()
+
+	parseHex := func(text string) (any, error) {
+		n, err := strconv.ParseUint(strings.TrimPrefix(text, "0x"), 16, 32)
+		return uint32(n), err
+	}
+	cl.RegisterOptionType("hex", parseHex, uint32(0))
+	cl.SetMaxFileSize(1024)
+
+	var got Values
+	cl.RegisterCommand(func(values Values) error {
+		got = values
+		return nil
+	}, "paint <hex-color>", "[--alpha <hex-alpha=0xff>]", "*[--mask <hex-mask>]", "[--count <int-count>]")
+
+	err := cl.Process([]string{"paint", "0x00ff00", "--mask", "1", "--mask", "f0", "--count", "2"})
+	expectError(t, nil, err)
+	expectValue(t, uint32(0xff00), got["color"].(uint32))
+	expectValue(t, uint32(0xff), got["alpha"].(uint32))
+	expectValue(t, 2, len(got["mask"].([]uint32)))
+	expectValue(t, uint32(0xf0), got["mask"].([]uint32)[1])
+	expectValue(t, 2, got["count"].(int))
+
+	err = cl.Process([]string{"paint", "zz"})
+	expectError(t, NewCommandLineError("Invalid hex value zz: strconv.ParseUint: parsing \"zz\": invalid syntax"), err)
+	expectBool(t, true, errors.Is(err, ErrInvalidValue))
+
+	expectPanicError(t, errors.New("argument error: option type \"hex\" is already defined"), func() {
+		cl.RegisterOptionType("hex", parseHex, uint32(0))
+	})
+	expectPanicError(t, errors.New("argument error: option type \"int\" is already defined"), func() {
+		cl.RegisterOptionType("int", parseHex, uint32(0))
+	})
+	expectPanicError(t, errors.New("argument error: an option type requires a name and a parse function"), func() {
+		cl.RegisterOptionType("oct", nil, 0)
+	})
+}
+
+func TestUUIDAndSemVerTypes(t *testing.T) {
+	cl := NewCommandLine()
+
+	var got Values
+	cl.RegisterCommand(func(values Values) error {
+		got = values
+		return nil
+	}, "deploy <uuid-id>", "[--version <semver[1.0.0..]-version>]", "*[--also <uuid-others>]")
+
+	err := cl.Process([]string{"deploy", "123E4567-E89B-12D3-A456-426614174000", "--version", "1.4.2-rc.1+build.5", "--also", "00000000-0000-0000-0000-000000000000"})
+	expectError(t, nil, err)
+	expectString(t, "123e4567-e89b-12d3-a456-426614174000", got["id"].(string))
+	expectValue(t, SemVer{Major: 1, Minor: 4, Patch: 2, Prerelease: "rc.1", Build: "build.5"}, got["version"].(SemVer))
+	expectString(t, "1.4.2-rc.1+build.5", got["version"].(SemVer).String())
+	expectValue(t, 1, len(got["others"].([]string)))
+
+	for _, id := range []string{"123e4567e89b12d3a456426614174000", "123e4567-e89b-12d3-a456-42661417400g", "{123e4567-e89b-12d3-a456-426614174000}"} {
+		err = cl.Process([]string{"deploy", id})
+		expectError(t, NewCommandLineError("Invalid UUID: %s", id), err)
+		expectBool(t, true, errors.Is(err, ErrInvalidValue))
+	}
+
+	for _, version := range []string{"1.2", "v1.2.3", "01.2.3", "1.2.3-01", "1.2.3-", "1.2.3+a..b"} {
+		err = cl.Process([]string{"deploy", "123e4567-e89b-12d3-a456-426614174000", "--version", version})
+		expectError(t, NewCommandLineError("Invalid semantic version: %s", version), err)
+	}
+
+	err = cl.Process([]string{"deploy", "123e4567-e89b-12d3-a456-426614174000", "--version", "1.0.0-beta"})
+	expectError(t, NewCommandLineError("Value version must be at least 1.0.0"), err)
+
+	// semver precedence
+	order := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0"}
+	for i := 1; i < len(order); i++ {
+		a, _ := ParseSemVer(order[i-1])
+		b, _ := ParseSemVer(order[i])
+		expectValue(t, -1, a.Compare(b))
+		expectValue(t, 1, b.Compare(a))
+	}
+	a, _ := ParseSemVer("1.0.0+x")
+	b, _ := ParseSemVer("1.0.0+y")
+	expectValue(t, 0, a.Compare(b))
+
+	text, err := json.Marshal(a)
+	expectError(t, nil, err)
+	expectString(t, `"1.0.0+x"`, string(text))
+}
+
+func TestCommandNameChars(t *testing.T) {
+	cl := NewCommandLine()
+
+	var ran string
+	var key string
+	cl.RegisterCommand(func(values Values) error { ran = "migrate"; return nil }, "db-migrate")
+	cl.RegisterCommand(func(values Values) error { ran = "set"; key = values["key"].(string); return nil }, "config.set <string-key>")
+	cl.RegisterCommand(func(values Values) error { ran = "up"; return nil }, "db.schema+up")
+
+	expectError(t, nil, cl.Process([]string{"db-migrate"}))
+	expectString(t, "migrate", ran)
+
+	expectError(t, nil, cl.Process([]string{"config.set", "color"}))
+	expectString(t, "set", ran)
+	expectString(t, "color", key)
+
+	expectError(t, nil, cl.Process([]string{"db.schema", "up"}))
+	expectString(t, "up", ran)
+
+	// the characters can't start or end a name, and options keep their own rules
+	expectPanicError(t, &SpecError{Spec: "config.", Column: 1, Expected: "a valid argument token"}, func() {
+		cl.RegisterCommand(func(values Values) error { return nil }, "config.")
+	})
+	expectPanicError(t, &SpecError{Spec: "[--a.b]", Column: 2, Expected: "a valid argument token"}, func() {
+		cl.RegisterCommand(func(values Values) error { return nil }, "other", "[--a.b]")
+	})
+
+	cl = NewCommandLine()
+	cl.RegisterCommand(func(values Values) error { return nil }, "config.get")
+	cl.SetCommandNameChars("-")
+	expectPanicError(t, &SpecError{Spec: "config.get", Column: 1, Expected: "a valid argument token"}, func() {
+		cl.RegisterCommand(func(values Values) error { return nil }, "config.get")
+	})
+	cl.RegisterCommand(func(values Values) error { return nil }, "db-migrate")
+
+	cl.SetCommandNameChars("-@")
+	cl.RegisterCommand(func(values Values) error { return nil }, "user@host")
+
+	expectPanicError(t, errors.New(`argument error: command name characters "-:" include spec syntax`), func() {
+		cl.SetCommandNameChars("-:")
+	})
+}
+
+func TestUnicodeHelpLayout(t *testing.T) {
+	expectValue(t, 3, displayWidth("abc"))
+	expectValue(t, 6, displayWidth("日本語"))
+	expectValue(t, 1, displayWidth("é"))
+	expectValue(t, 4, displayWidth("ｱｲｳ€"))
+	expectString(t, "日本", truncateToWidth("日本語", 5))
+	expectString(t, "日本 ", padToWidth("日本", 5))
+
+	cl := NewCommandLine()
+	var sb strings.Builder
+	cl.SetOutput(&sb)
+	cl.SetHelpWidth(30)
+	cl.RegisterCommand(
+		func(values Values) error {
+			return nil
+		},
+		"format?ストレージ デバイスを フォーマット して 全ての 内容を 消去します",
+	)
+
+	// wide characters take two columns, so the help wraps at half as many of them
+	cl.PrintCommand("format")
+	expectString(t, "format  ストレージ デバイスを\n"+
+		"        フォーマット して\n"+
+		"        全ての 内容を\n"+
+		"        消去します\n", sb.String())
+
+	sb.Reset()
+	cl.SetHelpWidth(14)
+	cl.PrintTable([]string{"名前", "説明"}, [][]string{{"ファイル", "とても長い説明文です"}})
+	expectString(t, "名前    説明\n"+
+		"------  ------\n"+
+		"ファイ  とても\n"+
+		"ル      長い説\n"+
+		"        明文で\n"+
+		"        す\n", sb.String())
+}
+
+func TestTerminalPrinter(t *testing.T) {
+	priorTerminal := xterm
+	defer func() { xterm = priorTerminal }()
+
+	xterm = &testTerminal{width: 20}
+	tp := NewTerminalPrinter()
+	output := captureStdout(t, func() {
+		tp.Status("copying")
+		tp.Status("copy")
+		tp.Println("done")
+		tp.Clear()
+	})
+	expectString(t, "\rcopying\x1b[K\rcopy\x1b[K\r\x1b[Kdone\n\rcopy\x1b[K\r\x1b[K", output)
+
+	// without ANSI sequences, the prior status is overwritten with spaces
+	xterm = &testTerminal{width: 20, noVT: true}
+	tp = NewTerminalPrinter()
+	output = captureStdout(t, func() {
+		tp.Status("copying")
+		tp.Status("copy")
+		tp.Println("done")
+		tp.Clear()
+	})
+	expectString(t, "\rcopying\r       \rcopy\r    \rdone\n\rcopy\r    \r", output)
+
+	// the count and date range texts are toolprinter's, and a status is cut to the width
+	xterm = &testTerminal{width: 20}
+	tp = NewTerminalPrinter()
+	output = captureStdout(t, func() {
+		tp.SetCounterMax(2, "files")
+		tp.Count()
+		tp.Count()
+		tp.Status("a status wider than the terminal")
+	})
+	expectString(t, "\rfiles 1 of 2 50%\x1b[K\rfiles 2 of 2 100%\x1b[K\ra status wider than\x1b[K", output)
+
+	xterm = &testTerminal{width: 40}
+	tp = NewTerminalPrinter()
+	day := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	output = captureStdout(t, func() {
+		tp.DateRangeStatus(day, day, "backup")
+		tp.BeginPrint("a")
+		tp.ContinuePrint("b")
+		tp.EndPrintIfStarted()
+	})
+	expectString(t, "\rbackup for 2024-01-02 03:04:05 UTC\x1b[K\r\x1b[Kab\n\rbackup for 2024-01-02 03:04:05 UTC\x1b[K", output)
+}
+
+func TestQuietStatus(t *testing.T) {
+	priorPrn := Prn
+	defer SetPrinter(priorPrn)
+	tp := toolprinter.NewTestPrinter()
+	SetPrinter(tp)
+
+	cl := NewCommandLine()
+	cl.SetQuiet(true)
+
+	cl.StatusLine("a", "working")
+	p := cl.StartProgress("copy", 4)
+	p.Advance(1)
+	cl.StartSpinner("waiting").Stop("")
+	expectString(t, "", tp.GetStatusText())
+
+	cl.PrintTable(nil, [][]string{{"done"}})
+	expectString(t, "done\n", tp.String())
+
+	cl.SetQuiet(false)
+	cl.StatusLine("a", "working")
+	expectString(t, "working", tp.GetStatusText())
+
+	// a safe printer stops its status output, including its status lines, until enabled
+	priorTerminal := xterm
+	defer func() { xterm = priorTerminal }()
+	xterm = &testTerminal{width: 20}
+
+	tp = toolprinter.NewTestPrinter()
+	sp := NewSafePrinter(tp)
+	var out strings.Builder
+	sp.out = &out
+
+	sp.StatusLine("a", "work")
+	sp.Status("busy")
+	expectString(t, "work\n", out.String())
+	expectString(t, "busy", tp.GetStatusText())
+
+	out.Reset()
+	sp.Disable()
+	expectString(t, eraseLineAbove, out.String())
+	expectString(t, "", tp.GetStatusText())
+
+	out.Reset()
+	sp.Status("still busy")
+	sp.StatusLine("a", "more work")
+	sp.Println("log line")
+	expectString(t, "", out.String())
+	expectString(t, "", tp.GetStatusText())
+	expectString(t, "log line\n", tp.String())
+
+	sp.Enable()
+	expectString(t, "more work\n", out.String())
+}
@@ -0,0 +1,98 @@
+// Package cmdlinetest runs command lines against a cmdline.CommandLine in tests,
+// capturing what the handlers print and the values they receive.
+package cmdlinetest
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/jimsnab/go-cmdline"
+)
+
+// the outcome of running a command line
+type Result struct {
+	// what was printed to stdout while the command line ran
+	Stdout string
+	// the error that Process returned
+	Err error
+	// the resolved command key, such as "users create"; empty when the command line is
+	// invalid, and the last command of a command chain
+	Command string
+	// a copy of the values given to the command handler; nil when the command line is invalid
+	Values cmdline.Values
+	// a copy of the values given to each global option handler, by option name
+	GlobalValues map[string]cmdline.Values
+	// the result returned by a command result handler, or nil
+	Data any
+}
+
+// splits the command line like a shell and runs it; see RunArgs
+func Run(cl *cmdline.CommandLine, commandLine string) *Result {
+	args, err := cmdline.SplitArgs(commandLine)
+	if err != nil {
+		return &Result{Err: err}
+	}
+	return RunArgs(cl, args)
+}
+
+// runs the args with Process, so a dry run or a command chain runs as it would for
+// the user; stdout is redirected while the args run, so tests that use RunArgs
+// shouldn't run in parallel
+func RunArgs(cl *cmdline.CommandLine, args []string) *Result {
+	result := Result{}
+
+	result.Stdout = captureStdout(func() {
+		pr, err := cl.ProcessEx(args)
+		result.Err = err
+		if pr == nil {
+			return
+		}
+
+		result.Command = pr.Command
+		result.Values = copyValues(pr.CommandValues)
+		result.GlobalValues = map[string]cmdline.Values{}
+		for name, values := range pr.GlobalValues {
+			result.GlobalValues[name] = copyValues(values)
+		}
+		result.Data = pr.Result
+	})
+
+	return &result
+}
+
+func copyValues(values cmdline.Values) cmdline.Values {
+	snapshot := make(cmdline.Values, len(values))
+	for k, v := range values {
+		snapshot[k] = v
+	}
+	return snapshot
+}
+
+func captureStdout(fn func()) string {
+	orgStdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		fn()
+		return ""
+	}
+
+	os.Stdout = w
+	output := make(chan string, 1)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		output <- buf.String()
+	}()
+
+	defer func() {
+		w.Close()
+		os.Stdout = orgStdout
+	}()
+
+	fn()
+
+	w.Close()
+	os.Stdout = orgStdout
+	return <-output
+}
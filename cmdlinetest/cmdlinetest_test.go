@@ -0,0 +1,113 @@
+package cmdlinetest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jimsnab/go-cmdline"
+	"github.com/jimsnab/go-testutils"
+)
+
+var (
+	expectError  = testutils.ExpectError
+	expectString = testutils.ExpectString
+	expectValue  = testutils.ExpectValue
+)
+
+func TestRun(t *testing.T) {
+	cl := cmdline.NewCommandLine()
+
+	cl.RegisterGlobalOption(func(values cmdline.Values) error { return nil }, "--env:<string-env>")
+	cl.RegisterCommand(
+		func(values cmdline.Values) error {
+			fmt.Println("creating", values["username"])
+			values["username"] = "changed"
+			return nil
+		},
+		"users|create <string-username>",
+		"[--admin]",
+	)
+	cl.RegisterCommand(
+		func(values cmdline.Values) error {
+			return errors.New("failed")
+		},
+		"fail",
+	)
+
+	result := Run(cl, `--env:prod users create "bob smith"`)
+	expectError(t, nil, result.Err)
+	expectString(t, "creating bob smith\n", result.Stdout)
+	expectString(t, "users create", result.Command)
+	expectValue(t, "bob smith", result.Values["username"])
+	expectValue(t, false, result.Values["--admin"])
+	expectValue(t, "prod", result.GlobalValues["--env"]["env"])
+
+	result = Run(cl, "fail")
+	expectError(t, errors.New("failed"), result.Err)
+
+	result = Run(cl, "users delete")
+	expectString(t, "Unrecognized command: users; did you mean 'users create'?", result.Err.Error())
+	expectValue(t, true, result.Values == nil)
+
+	result = Run(cl, `users create "bob`)
+	expectString(t, "Unterminated \" quote in command line", result.Err.Error())
+
+	cl.RegisterResultCommand(
+		func(values cmdline.Values) (any, error) {
+			return []string{"ann", "bob"}, nil
+		},
+		"users|list",
+	)
+	result = Run(cl, "users list")
+	expectError(t, nil, result.Err)
+	expectString(t, "ann\nbob\n", result.Stdout)
+	expectValue(t, "bob", result.Data.([]string)[1])
+}
+
+func TestRunDryRun(t *testing.T) {
+	cl := cmdline.NewCommandLine()
+
+	cl.RegisterCommand(
+		func(values cmdline.Values) error {
+			fmt.Println("a ran")
+			return nil
+		},
+		"a",
+	)
+	cl.EnableDryRunOption()
+
+	result := Run(cl, "a --dry-run")
+	expectError(t, nil, result.Err)
+	expectString(t, "Command: a\n  a: true\n", result.Stdout)
+
+	result = Run(cl, "a")
+	expectError(t, nil, result.Err)
+	expectString(t, "a ran\n", result.Stdout)
+}
+
+func TestRunChain(t *testing.T) {
+	cl := cmdline.NewCommandLine()
+
+	cl.RegisterCommand(
+		func(values cmdline.Values) error {
+			fmt.Println("created", values["name"])
+			return nil
+		},
+		"users|create <string-name>",
+	)
+	cl.RegisterCommand(
+		func(values cmdline.Values) error {
+			fmt.Println("granted", values["role"])
+			return nil
+		},
+		"users|grant <string-name> <string-role>",
+	)
+	cl.SetCommandSeparator(";")
+
+	result := Run(cl, `users create ann ";" users grant ann admin`)
+	expectError(t, nil, result.Err)
+	expectString(t, "created ann\ngranted admin\n", result.Stdout)
+	expectString(t, "users grant", result.Command)
+	expectValue(t, "admin", result.Values["role"])
+}
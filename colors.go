@@ -0,0 +1,83 @@
+package cmdline
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// when Success, Warn and ErrorMsg print in color
+type ColorMode int
+
+const (
+	// colors output to a terminal, unless the NO_COLOR environment variable is set
+	// or TERM is dumb; this is the default
+	ColorAuto ColorMode = iota
+	// always colors the output
+	ColorAlways
+	// never colors the output
+	ColorNever
+)
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+)
+
+// sets when Success, Warn and ErrorMsg print in color
+func (cl *CommandLine) SetColorMode(mode ColorMode) {
+	cl.colorMode = mode
+}
+
+// prints a line reporting success, in green, with the help printer
+func (cl *CommandLine) Success(text ...any) {
+	var f *os.File
+	if cl.output == nil {
+		f = os.Stdout
+	}
+	cl.printer().Println(cl.colored(f, ansiGreen, fmt.Sprint(text...)))
+}
+
+// prints a warning line, in yellow, with the diagnostics
+func (cl *CommandLine) Warn(text ...any) {
+	cl.printDiagnostic(ansiYellow, fmt.Sprint(text...))
+}
+
+// prints an error line, in red, with the diagnostics
+func (cl *CommandLine) ErrorMsg(text ...any) {
+	cl.printDiagnostic(ansiRed, fmt.Sprint(text...))
+}
+
+func (cl *CommandLine) printDiagnostic(color string, text string) {
+	var w io.Writer = os.Stderr
+	if cl.diagnosticStream != nil {
+		w = cl.diagnosticStream
+	}
+	f, _ := w.(*os.File)
+	cl.printStream(w, cl.colored(f, color, text))
+}
+
+// wraps text in the color when the output, f, should be colored; f is nil when
+// the output isn't a file
+func (cl *CommandLine) colored(f *os.File, color string, text string) string {
+	if !cl.useColor(f) {
+		return text
+	}
+	return color + text + ansiReset
+}
+
+func (cl *CommandLine) useColor(f *os.File) bool {
+	switch cl.colorMode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" || f == nil {
+		return false
+	}
+	return xterm.IsTerminal(int(f.Fd())) && xterm.EnableVirtualTerminal(int(f.Fd()))
+}
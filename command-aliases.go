@@ -0,0 +1,90 @@
+package cmdline
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jimsnab/go-simpleutils"
+)
+
+// defines a shortcut that expands into a command and its args, such as "ls" for
+// "users --list", split like a shell; like a git alias, it applies in the command
+// position, a registered command of the same name takes precedence, and the args
+// that follow the alias are appended to the expansion
+func (cl *CommandLine) DefineAlias(name, expansion string) {
+	if len(name) == 0 || strings.ContainsAny(name, " \t") || strings.HasPrefix(name, "-") {
+		panic(fmt.Errorf("argument error: alias name \"%s\" must be a single word", name))
+	}
+
+	args, err := SplitArgs(expansion)
+	if err != nil {
+		panic(fmt.Errorf("argument error: alias \"%s\": %v", name, err))
+	}
+	if len(args) == 0 {
+		panic(fmt.Errorf("argument error: alias \"%s\" has no expansion", name))
+	}
+
+	if cl.aliases == nil {
+		cl.aliases = map[string][]string{}
+	}
+	cl.aliases[name] = args
+}
+
+// defines the aliases of a JSON object that maps each alias name to its expansion,
+// such as a user's config file; a malformed file or alias is returned as an error
+func (cl *CommandLine) LoadAliases(r io.Reader) (err error) {
+	var aliases map[string]string
+	if err = json.NewDecoder(r).Decode(&aliases); err != nil {
+		return fmt.Errorf("alias file error: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = fmt.Errorf("alias file error: %w", e)
+			} else {
+				err = fmt.Errorf("alias file error: %v", r)
+			}
+		}
+	}()
+
+	for _, name := range simpleutils.SortedKeys(aliases) {
+		cl.DefineAlias(name, aliases[name])
+	}
+	return nil
+}
+
+// replaces an alias in the command position with its expansion
+func (cl *CommandLine) expandAlias(globalArgs *parsedGlobalArgs) *parsedGlobalArgs {
+	if len(cl.aliases) == 0 || len(globalArgs.commandArgs) == 0 || cl.unnamedCmd != nil {
+		return globalArgs
+	}
+
+	name := globalArgs.commandArgs[0]
+	expansion, exists := cl.aliases[name]
+	if !exists || cl.isCommandWord(name) {
+		return globalArgs
+	}
+
+	globalArgs.trace.add(AliasStep, name, strings.Join(expansion, " "))
+
+	expanded := *globalArgs
+	expanded.commandArgs = append(append([]string{}, expansion...), globalArgs.commandArgs[1:]...)
+	cl.loadNamedLazyCommands(expanded.commandArgs)
+	return &expanded
+}
+
+// determines if a word starts a registered command
+func (cl *CommandLine) isCommandWord(word string) bool {
+	for _, key := range cl.commands.order {
+		if !cl.commands.values[key].enabled() {
+			continue
+		}
+		if key == word || strings.HasPrefix(key, word+" ") {
+			return true
+		}
+	}
+	return false
+}
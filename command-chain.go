@@ -0,0 +1,47 @@
+package cmdline
+
+import (
+	"fmt"
+)
+
+// sets an arg that separates commands, such as ";" or "and", so one command line runs
+// several commands in turn, as in `users create ann ; users grant ann admin`; the
+// global options run once, before the first command, and apply to all of them. All
+// of the commands are parsed before any runs, and a failing command stops the rest.
+// An empty separator, the default, disables chaining.
+func (cl *CommandLine) SetCommandSeparator(separator string) {
+	if separator == "--" {
+		panic(fmt.Errorf("argument error: the command separator can't be the \"--\" terminator"))
+	}
+	cl.commandSeparator = separator
+}
+
+// splits the command args at the command separator; the args after the "--"
+// terminator go to the last command, and empty commands are skipped
+func (cl *CommandLine) splitCommandChain(globalArgs *parsedGlobalArgs) []*parsedGlobalArgs {
+	if cl.commandSeparator == "" {
+		return []*parsedGlobalArgs{globalArgs}
+	}
+
+	chain := []*parsedGlobalArgs{}
+	start := 0
+	for i := 0; i <= len(globalArgs.commandArgs); i++ {
+		if i < len(globalArgs.commandArgs) && globalArgs.commandArgs[i] != cl.commandSeparator {
+			continue
+		}
+
+		if i > start {
+			link := *globalArgs
+			link.commandArgs = globalArgs.commandArgs[start:i]
+			link.restArgs = nil
+			chain = append(chain, &link)
+		}
+		start = i + 1
+	}
+
+	if len(chain) == 0 {
+		return []*parsedGlobalArgs{globalArgs}
+	}
+	chain[len(chain)-1].restArgs = globalArgs.restArgs
+	return chain
+}
@@ -0,0 +1,24 @@
+package cmdline
+
+// makes a command available only while enabled returns true, such as for a feature
+// flag, platform or license; it is asked each time a command line is processed or
+// help is printed, and a disabled command is hidden from help, completion and
+// Describe, and is rejected as an unrecognized command. nil enables the command
+// again.
+func (cl *CommandLine) SetCommandEnabled(cmdName string, enabled func() bool) {
+	cmd := cl.lookupCommand(cmdName)
+	cmd.Enabled = enabled
+}
+
+func (cmd *command) enabled() bool {
+	return cmd.Enabled == nil || cmd.Enabled()
+}
+
+// finds a registered command that is enabled
+func (cl *CommandLine) enabledCommand(key string) (*command, bool) {
+	cmd, exists := cl.commands.values[key]
+	if !exists || !cmd.enabled() {
+		return nil, false
+	}
+	return cmd, true
+}
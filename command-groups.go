@@ -0,0 +1,59 @@
+package cmdline
+
+type commandSection struct {
+	heading  string
+	commands []*command
+}
+
+// assigns a command to a named group, so help lists it under "<group> Commands:"
+func (cl *CommandLine) SetCommandGroup(cmdName string, group string) {
+	cmd := cl.lookupCommand(cmdName)
+	cmd.Group = group
+
+	for _, existing := range cl.commandGroups {
+		if existing == group {
+			return
+		}
+	}
+	cl.commandGroups = append(cl.commandGroups, group)
+}
+
+// splits the commands into help sections, by group in the order the groups were
+// first assigned, followed by the ungrouped commands
+func (cl *CommandLine) commandSections(heading string, partial bool, commands []*command) []commandSection {
+	grouped := false
+	for _, cmd := range commands {
+		if len(cmd.Group) > 0 {
+			grouped = true
+			break
+		}
+	}
+	if !grouped {
+		return []commandSection{{heading: heading, commands: commands}}
+	}
+
+	prefix := ""
+	if partial {
+		prefix = "Matching "
+	}
+
+	sections := []commandSection{}
+	groups := append(append([]string{}, cl.commandGroups...), "")
+	for _, group := range groups {
+		section := commandSection{heading: cl.tr(prefix+"%s Commands:", group)}
+		if len(group) == 0 {
+			section.heading = cl.tr(prefix + "Other Commands:")
+		}
+
+		for _, cmd := range commands {
+			if cmd.Group == group {
+				section.commands = append(section.commands, cmd)
+			}
+		}
+
+		if len(section.commands) > 0 {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
@@ -0,0 +1,34 @@
+package cmdline
+
+import (
+	"fmt"
+	"strings"
+)
+
+// the characters allowed within a command name, besides letters, digits and
+// underscores, such as db-migrate or config.set
+const DefaultCommandNameChars = "-."
+
+// characters that have a meaning in a spec, so can't be part of a command name
+const specSyntaxChars = " :<>[]{}|+?*~,=/"
+
+// sets the characters allowed within a command name, besides letters, digits and
+// underscores; a name can't start or end with one of them. Call before registering
+// commands.
+func (cl *CommandLine) SetCommandNameChars(chars string) {
+	if strings.ContainsAny(chars, specSyntaxChars) {
+		panic(fmt.Errorf("argument error: command name characters \"%s\" include spec syntax", chars))
+	}
+	cl.commandNameChars = chars
+
+	// a cached spec was checked with the former characters
+	cl.specCache = nil
+}
+
+// the characters allowed within a name of the spec
+func (cl *CommandLine) nameMiddleChars(primaryArg bool) string {
+	if primaryArg {
+		return " " + cl.commandNameChars
+	}
+	return "- "
+}
@@ -0,0 +1,79 @@
+package cmdline
+
+import (
+	"context"
+)
+
+// a command handler that returns its result as data, for the result renderer to
+// present, rather than printing it
+type CommandResultHandler func(values Values) (any, error)
+type CommandResultHandlerCtx func(ctx context.Context, values Values) (any, error)
+
+// presents the result of a command result handler
+type ResultRenderer func(result any) error
+
+// registers a command handler that returns a result; unless the handler fails or
+// the result is nil, the result is rendered by the result renderer
+func (cl *CommandLine) RegisterResultCommand(handler CommandResultHandler, specList ...string) {
+	cl.RegisterResultCommandCtx(func(ctx context.Context, values Values) (any, error) {
+		return handler(values)
+	}, specList...)
+}
+
+// registers a command handler that returns a result and receives the context given
+// to ProcessContext
+func (cl *CommandLine) RegisterResultCommandCtx(handler CommandResultHandlerCtx, specList ...string) {
+	cl.RegisterCommandCtx(cl.resultHandler(handler), specList...)
+}
+
+// sets how command results are presented; nil restores the default, which prints
+// the result with Emit in the output format selected by --output, if enabled
+func (cl *CommandLine) SetResultRenderer(renderer ResultRenderer) {
+	cl.resultRenderer = renderer
+}
+
+// provides the result of the most recent command result handler, or nil if the
+// most recent command didn't return one
+func (cl *CommandLine) LastResult() any {
+	cl.lastMu.Lock()
+	defer cl.lastMu.Unlock()
+	return cl.lastResult
+}
+
+func (cl *CommandLine) recordResult(result any) {
+	cl.lastMu.Lock()
+	defer cl.lastMu.Unlock()
+	cl.lastResult = result
+}
+
+// the context key of the command being run, which a result handler gives its result
+type runningCommandKey struct{}
+
+// provides a context for running the command, through which its result handler
+// gives the command its result
+func withRunningCommand(ctx context.Context, cmdToRun *commandToRun) context.Context {
+	return context.WithValue(ctx, runningCommandKey{}, cmdToRun)
+}
+
+// adapts a result handler to a command handler that renders the result
+func (cl *CommandLine) resultHandler(handler CommandResultHandlerCtx) CommandHandlerCtx {
+	return func(ctx context.Context, values Values) error {
+		result, err := handler(ctx, values)
+		if err != nil {
+			return err
+		}
+
+		cl.recordResult(result)
+		if cmdToRun, _ := ctx.Value(runningCommandKey{}).(*commandToRun); cmdToRun != nil {
+			cmdToRun.result = result
+		}
+		if result == nil {
+			return nil
+		}
+
+		if cl.resultRenderer != nil {
+			return cl.resultRenderer(result)
+		}
+		return cl.Emit(result)
+	}
+}
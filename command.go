@@ -1,21 +1,40 @@
 package cmdline
 
 import (
+	"context"
 	"fmt"
 )
 
 const basePanic = "command line template syntax error! expected "
 
+// the Values key holding the args that follow the "--" terminator, untouched
+const RestArgs = "--"
+
 type Values map[string]any
 type CommandHandler func(values Values) error
+type CommandHandlerCtx func(ctx context.Context, values Values) error
 
 type command struct {
-	Handler        CommandHandler
+	Handler        CommandHandlerCtx
 	PrimaryArgSpec *argSpec
 	OptionSpecs    *orderedArgSpecMap
+	Constraints    []*optionConstraint
+	Examples       []*commandExample
+	Group          string
+	OptionGroups   []string
+	Confirmation   string
+	Streamed       *argValueSpec
+	Enabled        func() bool // nil when the command is always enabled
+}
+
+// adapts a handler that doesn't use the context
+func ctxHandler(handler CommandHandler) CommandHandlerCtx {
+	return func(ctx context.Context, values Values) error {
+		return handler(values)
+	}
 }
 
-func (cl *CommandLine) newCommand(handler CommandHandler, specList ...string) *command {
+func (cl *CommandLine) newCommand(handler CommandHandlerCtx, specList ...string) *command {
 	cmd := command{}
 
 	cmd.Handler = handler
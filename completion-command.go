@@ -0,0 +1,125 @@
+package cmdline
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// returned by Process after a completion command prints its output
+var ErrCompletionShown = errors.New("completion shown")
+
+// the protocol command that completion scripts call with the words typed so far
+const completeCommandName = "__complete"
+
+// the scripts call "<app> __complete -- <words>", with the word being typed last,
+// and offer each line of output; %[1]s is the app name, %[2]s a shell identifier
+// derived from it
+var completionScripts = map[string]string{
+	"bash": `_%[2]s_complete() {
+    local IFS=$'\n'
+    COMPREPLY=($('%[1]s' __complete -- "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _%[2]s_complete '%[1]s'
+`,
+	"zsh": `#compdef '%[1]s'
+_%[2]s_complete() {
+    local -a completions
+    completions=("${(@f)$('%[1]s' __complete -- "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    compadd -a completions
+}
+compdef _%[2]s_complete '%[1]s'
+`,
+	"fish": `function __%[2]s_complete
+    set -l words (commandline -opc) (commandline -ct)
+    '%[1]s' __complete -- $words[2..-1] 2>/dev/null
+end
+complete -c '%[1]s' -f -a '(__%[2]s_complete)'
+`,
+	"powershell": `Register-ArgumentCompleter -Native -CommandName '%[1]s' -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -eq '') { $words += '' }
+    & '%[1]s' __complete -- @words 2>$null | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`,
+}
+
+var shellIdentifierPattern = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// makes Process handle two hidden commands: "completion <shell>", which prints the
+// completion script for bash, zsh, fish or powershell, and "__complete", which the
+// scripts call to get the suggestions of Complete; Process returns
+// ErrCompletionShown after either one
+func (cl *CommandLine) EnableCompletionCommand() {
+	cl.completionCommand = true
+}
+
+// provides a completion script for bash, zsh, fish or powershell that calls back
+// into the app's "__complete" command, which EnableCompletionCommand provides
+func (cl *CommandLine) CompletionScript(shell string, appName string) (string, error) {
+	script, exists := completionScripts[shell]
+	if !exists {
+		return "", newKindError(ErrInvalidValue, shell, "completion <shell>", "Unsupported shell: %s; expected one of %s", shell, strings.Join(completionShells(), ", "))
+	}
+
+	appName = strings.ReplaceAll(appName, "'", "")
+	return fmt.Sprintf(script, appName, shellIdentifierPattern.ReplaceAllString(appName, "_")), nil
+}
+
+// provides the PowerShell completion script, based on Register-ArgumentCompleter; it
+// is the script of CompletionScript, so the app needs EnableCompletionCommand to
+// answer it. Load it with ". script.ps1" or add it to the PowerShell profile.
+func (cl *CommandLine) PowerShellCompletion(appName string) string {
+	script, _ := cl.CompletionScript("powershell", appName)
+	return script
+}
+
+func completionShells() []string {
+	shells := make([]string, 0, len(completionScripts))
+	for shell := range completionScripts {
+		shells = append(shells, shell)
+	}
+	sort.Strings(shells)
+	return shells
+}
+
+// runs a completion command, if the args are one; a command registered by the app
+// takes precedence
+func (cl *CommandLine) runCompletionCommand(args []string, restArgs []string) (bool, error) {
+	if !cl.completionCommand || len(args) == 0 {
+		return false, nil
+	}
+	if _, exists := cl.commands.values[args[0]]; exists {
+		return false, nil
+	}
+
+	switch args[0] {
+	case "completion":
+		if len(args) < 2 {
+			return true, newKindError(ErrMissingValue, "", "completion <shell>", "A shell is required: %s", strings.Join(completionShells(), ", "))
+		}
+
+		script, err := cl.CompletionScript(args[1], cl.getAppName())
+		if err != nil {
+			return true, err
+		}
+		cl.printer().Println(strings.TrimSuffix(script, "\n"))
+
+	case completeCommandName:
+		words := append(append([]string{}, args[1:]...), restArgs...)
+		for _, completion := range cl.Complete(words) {
+			cl.printer().Println(completion)
+		}
+
+	default:
+		return false, nil
+	}
+
+	return true, ErrCompletionShown
+}
@@ -0,0 +1,272 @@
+package cmdline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// suggests values that begin with prefix, such as user names for <string-username>
+type ValueCompleter func(prefix string) []string
+
+// registers a completer for every value spec with the given name, such as
+// "username" in <string-username>; Complete calls it to suggest dynamic values
+func (cl *CommandLine) RegisterCompleter(valueName string, completer ValueCompleter) {
+	if len(valueName) == 0 || completer == nil {
+		panic(fmt.Errorf("argument error: a completer requires a value name and a function"))
+	}
+
+	if cl.completers == nil {
+		cl.completers = map[string]ValueCompleter{}
+	}
+	cl.completers[valueName] = completer
+}
+
+// suggests completions for the last of args, the word being typed, which can be
+// empty; the args before it are the words already on the command line
+func (cl *CommandLine) Complete(args []string) []string {
+	cl.LoadLazyCommands()
+	words := args
+	partial := ""
+	if len(args) > 0 {
+		words = args[:len(args)-1]
+		partial = args[len(args)-1]
+	}
+
+	//
+	// Set aside the global options, which can appear anywhere.
+	//
+
+	cmdWords := []string{}
+	endsInGlobal := false
+	var pending *argValueSpec
+	for i := 0; i < len(words); i++ {
+		globalArgSwitch, globalArgValue := cl.splitColon(words[i])
+
+		globalOpt, exists := cl.globalOptions.lookup(globalArgSwitch)
+		if exists {
+			var argsUsed int
+			argsUsed, pending = pendingValue(globalOpt.argSpec, globalArgValue, words[i+1:])
+			i += argsUsed
+			endsInGlobal = true
+		} else {
+			cmdWords = append(cmdWords, words[i])
+			pending = nil
+			endsInGlobal = false
+		}
+	}
+
+	if value, completions, ok := cl.completeAttachedValue(nil, partial); ok {
+		return prefixCompletions(value, completions)
+	}
+
+	completions := []string{}
+	if pending != nil && !strings.HasPrefix(partial, "-") {
+		completions = append(completions, cl.completeValue(pending, partial)...)
+		if !pending.Optional {
+			return sortCompletions(completions)
+		}
+	}
+
+	//
+	// Find the command, preferring the deepest subcommand.
+	//
+
+	var cmd *command
+	tokensUsed := 0
+	if cl.unnamedCmd != nil {
+		cmd, _ = cl.enabledCommand("~")
+	} else {
+		for n := 1; n <= len(cmdWords); n++ {
+			if strings.HasPrefix(cmdWords[n-1], "-") {
+				break
+			}
+
+			subcmd, exists := cl.enabledCommand(strings.Join(cmdWords[:n], " "))
+			if exists {
+				cmd = subcmd
+				tokensUsed = n
+			}
+		}
+	}
+
+	// the words can be a whole command or the start of a subcommand path
+	if cl.unnamedCmd == nil && (cmd == nil || tokensUsed == len(cmdWords)) && !strings.HasPrefix(partial, "-") {
+		completions = append(completions, cl.completeCommandName(cmdWords, partial)...)
+	}
+
+	if cmd == nil {
+		if len(cmdWords) > 0 {
+			cmd, _ = cl.enabledCommand("~")
+		}
+		if cmd == nil {
+			if strings.HasPrefix(partial, "-") {
+				completions = append(completions, cl.completeGlobalOptionName(partial)...)
+			}
+			return sortCompletions(completions)
+		}
+	}
+
+	//
+	// Walk the command's values and options to see what the partial word can be.
+	//
+
+	remaining := cmdWords[tokensUsed:]
+	argsUsed := 0
+	if cmd.PrimaryArgSpec.ValuesDelim == ' ' {
+		argsUsed, pending = pendingValue(cmd.PrimaryArgSpec, nil, remaining)
+	}
+
+	specified := map[string]bool{}
+	for i := argsUsed; i < len(remaining); i++ {
+		optionArgSwitch, optionArgValue := cl.splitColon(remaining[i])
+
+		optionSpec, exists := cmd.OptionSpecs.lookup(optionArgSwitch)
+		if !exists {
+			pending = nil
+			continue
+		}
+
+		specified[optionSpec.Key] = true
+		var used int
+		used, pending = pendingValue(optionSpec, optionArgValue, remaining[i+1:])
+		i += used
+	}
+
+	if endsInGlobal {
+		// the word before the partial one belongs to a global option
+		pending = nil
+	}
+
+	if value, valueCompletions, ok := cl.completeAttachedValue(cmd, partial); ok {
+		return prefixCompletions(value, valueCompletions)
+	}
+
+	if pending != nil && !strings.HasPrefix(partial, "-") {
+		completions = append(completions, cl.completeValue(pending, partial)...)
+		if !pending.Optional {
+			return sortCompletions(completions)
+		}
+	}
+
+	// options are suggested for a dash, or when nothing else fits
+	if strings.HasPrefix(partial, "-") || (len(partial) == 0 && len(completions) == 0) {
+		for _, name := range cmd.OptionSpecs.order {
+			optionSpec := cmd.OptionSpecs.values[name]
+			if specified[optionSpec.Key] && !optionSpec.MultiValue {
+				continue
+			}
+			completions = append(completions, completeNames(optionSpec, partial)...)
+		}
+		completions = append(completions, cl.completeGlobalOptionName(partial)...)
+	}
+
+	return sortCompletions(completions)
+}
+
+// determines how many of args are values of the option, and the value spec that
+// the next arg would fill, if any
+func pendingValue(as *argSpec, colonValue *string, args []string) (argsUsed int, pending *argValueSpec) {
+	if colonValue != nil || as.ValuesDelim != ' ' || len(as.ValueSpecs) == 0 || as.isCount() {
+		return
+	}
+
+	for i, valueSpec := range as.ValueSpecs {
+		if argsUsed >= len(args) {
+			return argsUsed, valueSpec
+		}
+		if !valueSpec.isValueArg(args[argsUsed]) {
+			return
+		}
+		argsUsed++
+
+		if valueSpec.Multi {
+			for argsUsed < len(args) && valueSpec.isValueArg(args[argsUsed]) {
+				argsUsed++
+			}
+			if argsUsed >= len(args) && i == len(as.ValueSpecs)-1 {
+				return argsUsed, valueSpec
+			}
+		}
+	}
+	return
+}
+
+// completes the value of a word like --env:pr or --env=pr, for a command's options
+// or the global options when cmd is nil
+func (cl *CommandLine) completeAttachedValue(cmd *command, partial string) (string, []string, bool) {
+	delimiter := strings.IndexAny(partial, ":=")
+	if delimiter < 0 {
+		return "", nil, false
+	}
+
+	var as *argSpec
+	var exists bool
+	if cmd == nil {
+		as, exists = cl.lookupGlobalArgSpec(partial[:delimiter])
+	} else {
+		as, exists = cmd.OptionSpecs.lookup(partial[:delimiter])
+	}
+	if !exists || len(as.ValueSpecs) == 0 {
+		return "", nil, false
+	}
+
+	return partial[:delimiter+1], cl.completeValue(as.ValueSpecs[0], partial[delimiter+1:]), true
+}
+
+func (cl *CommandLine) completeValue(spec *argValueSpec, prefix string) []string {
+	completer := cl.completers[spec.OptionName]
+	if completer == nil {
+		return nil
+	}
+	return completer(prefix)
+}
+
+func (cl *CommandLine) completeCommandName(cmdWords []string, partial string) (completions []string) {
+	seen := map[string]bool{}
+	for _, name := range cl.commands.order {
+		tokens := strings.Split(name, " ")
+		if len(tokens) <= len(cmdWords) || name == "~" || !cl.commands.values[name].enabled() {
+			continue
+		}
+		if strings.Join(tokens[:len(cmdWords)], " ") != strings.Join(cmdWords, " ") {
+			continue
+		}
+
+		token := tokens[len(cmdWords)]
+		if strings.HasPrefix(token, partial) && !seen[token] {
+			seen[token] = true
+			completions = append(completions, token)
+		}
+	}
+	return
+}
+
+func (cl *CommandLine) completeGlobalOptionName(partial string) (completions []string) {
+	for _, name := range cl.globalOptions.order {
+		completions = append(completions, completeNames(cl.globalOptions.values[name].argSpec, partial)...)
+	}
+	return
+}
+
+func completeNames(as *argSpec, partial string) (completions []string) {
+	for _, name := range append([]string{as.Key}, as.allAliases()...) {
+		if strings.HasPrefix(name, partial) {
+			completions = append(completions, name)
+		}
+	}
+	return
+}
+
+func prefixCompletions(prefix string, completions []string) []string {
+	prefixed := make([]string, 0, len(completions))
+	for _, completion := range completions {
+		prefixed = append(prefixed, prefix+completion)
+	}
+	return prefixed
+}
+
+func sortCompletions(completions []string) []string {
+	sort.Strings(completions)
+	return completions
+}
@@ -0,0 +1,67 @@
+package cmdline
+
+import (
+	"fmt"
+	"strings"
+)
+
+// asks the user to confirm a command; tests can provide their own answers
+type ConfirmProvider interface {
+	Confirm(prompt string) (bool, error)
+}
+
+// makes Process ask for a y/N confirmation before running the command's handler;
+// the prompt is formatted with the command's positional values, as in
+// "Delete user %s?", and a --yes|-y option is added to skip the question
+func (cl *CommandLine) RequireConfirmation(cmdName string, prompt string) {
+	cmd := cl.lookupCommand(cmdName)
+	cmd.Confirmation = prompt
+
+	_, exists := cmd.OptionSpecs.lookup("--yes")
+	if !exists {
+		spec := cl.newArgSpec("[--yes|-y]?Skips the confirmation", false)
+		cl.checkAddedOptionNames(cmd, spec)
+		cmd.OptionSpecs.add(spec.Key, spec)
+	}
+}
+
+// replaces the stdin confirmation, such as for tests; nil restores it
+func (cl *CommandLine) SetConfirmProvider(provider ConfirmProvider) {
+	cl.confirmProvider = provider
+}
+
+// asks for the confirmation the command requires, if any
+func (cl *CommandLine) confirm(cmd *command, values Values) error {
+	if len(cmd.Confirmation) == 0 {
+		return nil
+	}
+	if yes, _ := values["--yes"].(bool); yes {
+		return nil
+	}
+
+	args := []any{}
+	for _, valueSpec := range cmd.PrimaryArgSpec.ValueSpecs {
+		args = append(args, values[valueSpec.OptionName])
+	}
+
+	confirmed, err := cl.Confirm(formatPrompt(cmd.Confirmation, args))
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return newKindError(ErrNotConfirmed, "", cmd.PrimaryArgSpec.String(), "Canceled")
+	}
+	return nil
+}
+
+// formats the prompt with as many of the args as it has verbs
+func formatPrompt(prompt string, args []any) string {
+	verbs := strings.Count(prompt, "%") - 2*strings.Count(prompt, "%%")
+	if verbs > len(args) {
+		verbs = len(args)
+	}
+	if verbs <= 0 {
+		return strings.ReplaceAll(prompt, "%%", "%")
+	}
+	return fmt.Sprintf(prompt, args[:verbs]...)
+}
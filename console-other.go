@@ -0,0 +1,8 @@
+//go:build !windows
+
+package cmdline
+
+// terminals other than the Windows console process ANSI sequences
+func enableVirtualTerminal(fd int) bool {
+	return true
+}
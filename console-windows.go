@@ -0,0 +1,22 @@
+//go:build windows
+
+package cmdline
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// turns on the console's processing of ANSI sequences, reporting if they work; an
+// older console, or output that isn't a console, can't process them
+func enableVirtualTerminal(fd int) bool {
+	handle := windows.Handle(fd)
+
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return false
+	}
+	if mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 {
+		return true
+	}
+	return windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil
+}
@@ -0,0 +1,132 @@
+package cmdline
+
+import (
+	"fmt"
+	"strings"
+)
+
+type constraintKind int
+
+const (
+	constraintAtLeastOne constraintKind = iota
+	constraintTogether
+	constraintRequires
+	constraintConflicts
+)
+
+type optionConstraint struct {
+	kind    constraintKind
+	options []string
+}
+
+// requires at least one of the options to be specified with the command
+func (cl *CommandLine) RequireAtLeastOneOf(cmdName string, options ...string) {
+	cl.addConstraint(constraintAtLeastOne, cmdName, options)
+}
+
+// requires the options to be specified together with the command, or not at all
+func (cl *CommandLine) RequireTogether(cmdName string, options ...string) {
+	cl.addConstraint(constraintTogether, cmdName, options)
+}
+
+// requires the other options whenever the option is specified with the command
+func (cl *CommandLine) Requires(cmdName string, option string, requiredOptions ...string) {
+	cl.addConstraint(constraintRequires, cmdName, append([]string{option}, requiredOptions...))
+}
+
+// allows at most one of the options to be specified with the command
+func (cl *CommandLine) Conflicts(cmdName string, options ...string) {
+	cl.addConstraint(constraintConflicts, cmdName, options)
+}
+
+func (cl *CommandLine) lookupCommand(cmdName string) *command {
+	if len(cmdName) == 0 {
+		cmdName = "~"
+	}
+
+	cmd, exists := cl.commands.values[specKey(cmdName, true)]
+	if !exists {
+		panic(fmt.Errorf("argument error: command \"%s\" is not registered", cmdName))
+	}
+	return cmd
+}
+
+func (cl *CommandLine) addConstraint(kind constraintKind, cmdName string, options []string) {
+	cmd := cl.lookupCommand(cmdName)
+
+	if len(options) < 2 {
+		panic(fmt.Errorf("argument error: a constraint requires at least two options"))
+	}
+
+	keys := make([]string, 0, len(options))
+	for _, option := range options {
+		optionSpec, exists := cmd.OptionSpecs.lookup(option)
+		if !exists {
+			panic(fmt.Errorf("argument error: option \"%s\" is not registered for command \"%s\"", option, cmdName))
+		}
+		keys = append(keys, optionSpec.Key)
+	}
+
+	cmd.Constraints = append(cmd.Constraints, &optionConstraint{kind: kind, options: keys})
+}
+
+func (oc *optionConstraint) validate(specified map[string]bool) error {
+	count := 0
+	for _, option := range oc.options {
+		if specified[option] {
+			count++
+		}
+	}
+
+	switch oc.kind {
+	case constraintAtLeastOne:
+		if count == 0 {
+			return newKindError(ErrConstraintViolation, strings.Join(oc.options, ", "), "", "At least one of %s is required", strings.Join(oc.options, ", "))
+		}
+	case constraintTogether:
+		if count > 0 && count < len(oc.options) {
+			return newKindError(ErrConstraintViolation, strings.Join(oc.options, ", "), "", "Arguments %s must be specified together", strings.Join(oc.options, ", "))
+		}
+	case constraintRequires:
+		if specified[oc.options[0]] {
+			for _, option := range oc.options[1:] {
+				if !specified[option] {
+					return newKindError(ErrConstraintViolation, oc.options[0], "", "Argument %s requires %s", oc.options[0], option)
+				}
+			}
+		}
+	case constraintConflicts:
+		if count > 1 {
+			conflicting := []string{}
+			for _, option := range oc.options {
+				if specified[option] {
+					conflicting = append(conflicting, option)
+				}
+			}
+			return newKindError(ErrConstraintViolation, strings.Join(conflicting, ", "), "", "Arguments %s cannot be used together", strings.Join(conflicting, ", "))
+		}
+	}
+
+	return nil
+}
+
+func (cl *CommandLine) constraintHelpText(oc *optionConstraint) string {
+	switch oc.kind {
+	case constraintAtLeastOne:
+		return cl.tr("Requires at least one of: %s", strings.Join(oc.options, ", "))
+	case constraintTogether:
+		return cl.tr("Use together: %s", strings.Join(oc.options, ", "))
+	case constraintRequires:
+		return cl.tr("%s requires: %s", oc.options[0], strings.Join(oc.options[1:], ", "))
+	case constraintConflicts:
+		return cl.tr("Use only one of: %s", strings.Join(oc.options, ", "))
+	default:
+		return ""
+	}
+}
+
+func (hp *helpPrinter) helpPrintConstraints(indent int, cmd *command) {
+	for _, constraint := range cmd.Constraints {
+		hp.helpPrintln(strings.Repeat("  ", indent) + hp.constraintHelpText(constraint))
+	}
+}
@@ -0,0 +1,114 @@
+package cmdline
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// converts the text of a value of a registered type
+type ValueParser func(text string) (any, error)
+
+type registeredType struct {
+	name         string
+	parse        ValueParser
+	defaultValue any
+}
+
+// extends the option types of a command line with registered types; a registered
+// type has a negative index, -1 for the first, so it can't collide with the
+// indexes of the types it extends
+type registeredOptionTypes struct {
+	base  OptionTypes
+	types []*registeredType
+	names map[string]int
+}
+
+// adds a value type, such as <hex-color>, converted by the parse function; the
+// default value is the value of an optional value that isn't given, and its type
+// is the element type of a repeated value. A type must be registered before the
+// specs that use it.
+func (cl *CommandLine) RegisterOptionType(name string, parse ValueParser, defaultValue any) {
+	if len(name) == 0 || parse == nil {
+		panic(fmt.Errorf("argument error: an option type requires a name and a parse function"))
+	}
+
+	rot, ok := cl.optionTypes.(*registeredOptionTypes)
+	if !ok {
+		rot = &registeredOptionTypes{base: cl.optionTypes, names: map[string]int{}}
+		cl.optionTypes = rot
+	}
+
+	if _, exists := rot.names[name]; exists || rot.baseHasType(name) {
+		panic(fmt.Errorf("argument error: option type \"%s\" is already defined", name))
+	}
+
+	rot.names[name] = len(rot.types)
+	rot.types = append(rot.types, &registeredType{name: name, parse: parse, defaultValue: defaultValue})
+}
+
+// checks the base types, which panic for a name they don't have
+func (rot *registeredOptionTypes) baseHasType(name string) (exists bool) {
+	defer func() {
+		if recover() != nil {
+			exists = false
+		}
+	}()
+	return rot.base.StringToAttributes(name, "") != nil
+}
+
+func (rot *registeredOptionTypes) registered(typeIndex int) *registeredType {
+	if typeIndex >= 0 {
+		return nil
+	}
+	return rot.types[-1-typeIndex]
+}
+
+func (rot *registeredOptionTypes) StringToAttributes(typeName string, spec string) *OptionTypeAttributes {
+	if i, exists := rot.names[typeName]; exists {
+		return &OptionTypeAttributes{Index: -1 - i, DefaultValue: rot.types[i].defaultValue}
+	}
+	return rot.base.StringToAttributes(typeName, spec)
+}
+
+func (rot *registeredOptionTypes) MakeValue(typeIndex int, inputValue string) (any, error) {
+	rt := rot.registered(typeIndex)
+	if rt == nil {
+		return rot.base.MakeValue(typeIndex, inputValue)
+	}
+
+	value, err := rt.parse(inputValue)
+	if err != nil {
+		return nil, newKindError(ErrInvalidValue, inputValue, "", "Invalid %s value %s: %s", rt.name, inputValue, err.Error())
+	}
+	return value, nil
+}
+
+func (rot *registeredOptionTypes) NewList(typeIndex int) (any, error) {
+	rt := rot.registered(typeIndex)
+	if rt == nil {
+		return rot.base.NewList(typeIndex)
+	}
+
+	if rt.defaultValue == nil {
+		return []any{}, nil
+	}
+	return reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(rt.defaultValue)), 0, 0).Interface(), nil
+}
+
+func (rot *registeredOptionTypes) AppendList(typeIndex int, list any, inputValue string) (any, error) {
+	if rot.registered(typeIndex) == nil {
+		return rot.base.AppendList(typeIndex, list, inputValue)
+	}
+
+	value, err := rot.MakeValue(typeIndex, inputValue)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := reflect.ValueOf(list)
+	ev := reflect.ValueOf(value)
+	if !ev.IsValid() || !ev.Type().AssignableTo(rv.Type().Elem()) {
+		return nil, fmt.Errorf("value %v doesn't match the default value type %s", value, rv.Type().Elem())
+	}
+	return reflect.Append(rv, ev).Interface(), nil
+}
@@ -0,0 +1,50 @@
+package cmdline
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// a reference to another value or an environment variable in a default
+var defaultReferencePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+func (avs *argValueSpec) interpolated() bool {
+	return defaultReferencePattern.MatchString(avs.DefaultText)
+}
+
+// provides the default of a value and its source; a reference like ${HOME} is
+// replaced by the value of that name, if it was parsed, or else by the environment
+// variable, which makes the source the environment
+func (as *argSpec) defaultValue(spec *argValueSpec, values map[string]any) (any, ValueSource, error) {
+	if !spec.interpolated() {
+		return spec.DefaultValue, SourceDefault, nil
+	}
+
+	source := SourceDefault
+	missing := ""
+	text := defaultReferencePattern.ReplaceAllStringFunc(spec.DefaultText, func(reference string) string {
+		name := reference[2 : len(reference)-1]
+		if value, exists := values[name]; exists {
+			return fmt.Sprint(value)
+		}
+		if value, exists := os.LookupEnv(name); exists {
+			source = SourceEnvironment
+			return value
+		}
+		if missing == "" {
+			missing = name
+		}
+		return ""
+	})
+
+	if missing != "" {
+		return nil, "", newKindError(ErrMissingValue, missing, as.String(), "Default of %s refers to %s, which is not set", spec.OptionName, missing)
+	}
+
+	value, err := as.CmdLine.optionTypes.MakeValue(spec.ArgIndex, as.CmdLine.normalizeValue(spec, text))
+	if err != nil {
+		return nil, "", as.conversionError(spec, text, err)
+	}
+	return value, source, nil
+}
@@ -0,0 +1,126 @@
+package cmdline
+
+import "regexp"
+
+// structured model of the command line definition, suitable for marshaling to JSON
+type Description struct {
+	GlobalOptions []OptionDescription  `json:"global_options,omitempty"`
+	Commands      []CommandDescription `json:"commands,omitempty"`
+}
+
+// the read-only metadata of a command, its values and its options
+type CommandInfo = CommandDescription
+
+type CommandDescription struct {
+	Name    string              `json:"name"`
+	Spec    string              `json:"spec"`
+	Unnamed bool                `json:"unnamed,omitempty"`
+	Group   string              `json:"group,omitempty"`
+	Help    string              `json:"help,omitempty"`
+	Values  []ValueDescription  `json:"values,omitempty"`
+	Options []OptionDescription `json:"options,omitempty"`
+}
+
+type OptionDescription struct {
+	Name     string             `json:"name"`
+	Aliases  []string           `json:"aliases,omitempty"`
+	Negation string             `json:"negation,omitempty"`
+	Group    string             `json:"group,omitempty"`
+	Spec     string             `json:"spec"`
+	Optional bool               `json:"optional,omitempty"`
+	Multi    bool               `json:"multi,omitempty"`
+	MinCount int                `json:"min_count,omitempty"`
+	MaxCount int                `json:"max_count,omitempty"`
+	Help     string             `json:"help,omitempty"`
+	Values   []ValueDescription `json:"values,omitempty"`
+}
+
+type ValueDescription struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Optional bool   `json:"optional,omitempty"`
+	Multi    bool   `json:"multi,omitempty"`
+	Range    string `json:"range,omitempty"`
+	Pattern  string `json:"pattern,omitempty"`
+	Default  any    `json:"default"`
+	Help     string `json:"help,omitempty"`
+}
+
+// provides a structured model of the registered commands and options, in registration order
+func (cl *CommandLine) Describe() *Description {
+	cl.LoadLazyCommands()
+	desc := Description{}
+
+	for _, name := range cl.globalOptions.order {
+		desc.GlobalOptions = append(desc.GlobalOptions, describeOption(cl.globalOptions.values[name].argSpec))
+	}
+
+	for _, name := range cl.commands.order {
+		cmd := cl.commands.values[name]
+		if !cmd.enabled() {
+			continue
+		}
+
+		cd := CommandDescription{
+			Name:    cmd.PrimaryArgSpec.Key,
+			Spec:    cmd.PrimaryArgSpec.canonicalSpec(),
+			Unnamed: cmd.PrimaryArgSpec.Unnamed,
+			Group:   cmd.Group,
+			Help:    cmd.PrimaryArgSpec.HelpText,
+			Values:  describeValues(cmd.PrimaryArgSpec),
+		}
+
+		for _, optionName := range cmd.OptionSpecs.order {
+			cd.Options = append(cd.Options, describeOption(cmd.OptionSpecs.values[optionName]))
+		}
+
+		desc.Commands = append(desc.Commands, cd)
+	}
+
+	return &desc
+}
+
+// provides the metadata of the registered commands, in registration order; the
+// result is a copy, so changing it doesn't affect the command line
+func (cl *CommandLine) Commands() []CommandInfo {
+	return cl.Describe().Commands
+}
+
+func describeOption(as *argSpec) OptionDescription {
+	return OptionDescription{
+		Name:     as.Key,
+		Aliases:  append([]string(nil), as.Aliases...),
+		Negation: as.Negation,
+		Group:    as.Group,
+		Spec:     as.canonicalSpec(),
+		Optional: as.Optional,
+		Multi:    as.MultiValue,
+		MinCount: as.MinRepeat,
+		MaxCount: as.MaxRepeat,
+		Help:     as.HelpText,
+		Values:   describeValues(as),
+	}
+}
+
+func describeValues(as *argSpec) (values []ValueDescription) {
+	for _, valueSpec := range as.ValueSpecs {
+		values = append(values, ValueDescription{
+			Name:     valueSpec.OptionName,
+			Type:     valueSpec.TypeName,
+			Optional: valueSpec.Optional,
+			Multi:    valueSpec.Multi,
+			Range:    valueSpec.RangeText,
+			Pattern:  patternText(valueSpec.Pattern),
+			Default:  valueSpec.DefaultValue,
+			Help:     valueSpec.HelpText,
+		})
+	}
+	return
+}
+
+func patternText(pattern *regexp.Regexp) string {
+	if pattern == nil {
+		return ""
+	}
+	return pattern.String()
+}
@@ -0,0 +1,71 @@
+package cmdline
+
+import "strings"
+
+// the switch that, given with --help, shows detailed help
+const fullHelpSwitch = "--full"
+
+// prints the help of the commands that match the filter, like PrintCommands with
+// the global options, adding the type, range, pattern and default of each value
+func (cl *CommandLine) PrintCommandsDetailed(filter string) {
+	cl.LoadLazyCommands()
+	hp := cl.newHelpPrinter()
+	hp.detailed = true
+	hp.printCommandsWorker(filter, true)
+	hp.helpRender()
+}
+
+// removes --full from the args of a help request, reporting if it was given;
+// it is left alone when the app registered it
+func (cl *CommandLine) fullHelpArgs(args []string) (bool, []string) {
+	if _, exists := cl.globalOptions.lookup(fullHelpSwitch); exists {
+		return false, args
+	}
+	if primary := cl.PrimaryCommand(args); primary != "" {
+		if _, exists := cl.commands.values[primary].OptionSpecs.lookup(fullHelpSwitch); exists {
+			return false, args
+		}
+	}
+
+	full := false
+	remaining := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == fullHelpSwitch {
+			full = true
+		} else {
+			remaining = append(remaining, arg)
+		}
+	}
+	if !full {
+		return false, args
+	}
+	return true, remaining
+}
+
+func (cl *CommandLine) showHelp(detailed bool, appName string, args []string) {
+	cl.LoadLazyCommands()
+	hp := cl.newHelpPrinter()
+	hp.detailed = detailed
+	hp.help(nil, appName, args)
+}
+
+// in detailed help, prints a line describing each value of the spec
+func (hp *helpPrinter) helpPrintDetails(indent int, as *argSpec) {
+	if !hp.detailed {
+		return
+	}
+
+	for _, valueSpec := range as.ValueSpecs {
+		parts := []string{valueSpec.TypeName}
+		if valueSpec.RangeText != "" {
+			parts = append(parts, hp.tr("range %s", valueSpec.RangeText))
+		}
+		if valueSpec.Pattern != nil {
+			parts = append(parts, hp.tr("pattern %s", valueSpec.Pattern.String()))
+		}
+		if valueSpec.DefaultText != "" {
+			parts = append(parts, hp.tr("default %s", valueSpec.DefaultText))
+		}
+		hp.helpPrintCols(indent+1, "", hp.tr("%s: %s", valueSpec.OptionName, strings.Join(parts, ", ")))
+	}
+}
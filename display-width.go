@@ -0,0 +1,126 @@
+package cmdline
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// the East Asian wide and fullwidth ranges, which take two terminal columns
+var wideRanges = [][2]rune{
+	{0x1100, 0x115F},   // hangul jamo
+	{0x231A, 0x231B},   // watch, hourglass
+	{0x2329, 0x232A},   // angle brackets
+	{0x23E9, 0x23EC},   // media controls
+	{0x23F0, 0x23F0},   // alarm clock
+	{0x23F3, 0x23F3},   // hourglass
+	{0x25FD, 0x25FE},   // small squares
+	{0x2614, 0x2615},   // umbrella, hot beverage
+	{0x2648, 0x2653},   // zodiac
+	{0x267F, 0x267F},   // wheelchair
+	{0x2693, 0x2693},   // anchor
+	{0x26A1, 0x26A1},   // high voltage
+	{0x26AA, 0x26AB},   // circles
+	{0x26BD, 0x26BE},   // balls
+	{0x26C4, 0x26C5},   // snowman, sun
+	{0x26CE, 0x26CE},   // ophiuchus
+	{0x26D4, 0x26D4},   // no entry
+	{0x26EA, 0x26EA},   // church
+	{0x26F2, 0x26F3},   // fountain, golf
+	{0x26F5, 0x26F5},   // sailboat
+	{0x26FA, 0x26FA},   // tent
+	{0x26FD, 0x26FD},   // fuel pump
+	{0x2705, 0x2705},   // check mark
+	{0x270A, 0x270B},   // fists
+	{0x2728, 0x2728},   // sparkles
+	{0x274C, 0x274C},   // cross mark
+	{0x274E, 0x274E},   // cross mark
+	{0x2753, 0x2755},   // question marks
+	{0x2757, 0x2757},   // exclamation mark
+	{0x2795, 0x2797},   // math signs
+	{0x27B0, 0x27B0},   // curly loop
+	{0x27BF, 0x27BF},   // double curly loop
+	{0x2B1B, 0x2B1C},   // large squares
+	{0x2B50, 0x2B50},   // star
+	{0x2B55, 0x2B55},   // circle
+	{0x2E80, 0x303E},   // cjk radicals, symbols and punctuation
+	{0x3041, 0x33FF},   // kana, bopomofo, cjk compatibility
+	{0x3400, 0x4DBF},   // cjk extension a
+	{0x4E00, 0x9FFF},   // cjk unified ideographs
+	{0xA000, 0xA4CF},   // yi
+	{0xA960, 0xA97F},   // hangul jamo extended a
+	{0xAC00, 0xD7A3},   // hangul syllables
+	{0xF900, 0xFAFF},   // cjk compatibility ideographs
+	{0xFE10, 0xFE19},   // vertical forms
+	{0xFE30, 0xFE6F},   // cjk compatibility forms, small forms
+	{0xFF00, 0xFF60},   // fullwidth forms
+	{0xFFE0, 0xFFE6},   // fullwidth signs
+	{0x16FE0, 0x16FE4}, // ideographic symbols
+	{0x17000, 0x18CFF}, // tangut, khitan
+	{0x1B000, 0x1B2FF}, // kana supplement, nushu
+	{0x1F004, 0x1F004}, // mahjong tile
+	{0x1F0CF, 0x1F0CF}, // playing card
+	{0x1F18E, 0x1F18E}, // ab button
+	{0x1F191, 0x1F19A}, // squared words
+	{0x1F200, 0x1F2FF}, // enclosed ideographic supplement
+	{0x1F300, 0x1F64F}, // pictographs, emoticons
+	{0x1F680, 0x1F6FF}, // transport and map
+	{0x1F7E0, 0x1F7EB}, // colored shapes
+	{0x1F90C, 0x1F9FF}, // supplemental pictographs
+	{0x1FA70, 0x1FAFF}, // pictographs extended a
+	{0x20000, 0x2FFFD}, // cjk extensions b through f
+	{0x30000, 0x3FFFD}, // cjk extension g
+}
+
+// the terminal columns taken by a rune: none for a combining mark or a control,
+// two for an East Asian wide character, otherwise one
+func runeWidth(r rune) int {
+	if r < 0x20 || (r >= 0x7F && r < 0xA0) {
+		return 0
+	}
+	if r < 0x1100 {
+		if unicode.In(r, unicode.Mn, unicode.Me) || r == 0xAD {
+			return 0
+		}
+		return 1
+	}
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+
+	i := sort.Search(len(wideRanges), func(i int) bool { return wideRanges[i][1] >= r })
+	if i < len(wideRanges) && r >= wideRanges[i][0] {
+		return 2
+	}
+	return 1
+}
+
+// the terminal columns taken by text
+func displayWidth(text string) int {
+	width := 0
+	for _, r := range text {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// the longest start of text that fits in width columns
+func truncateToWidth(text string, width int) string {
+	used := 0
+	for i, r := range text {
+		used += runeWidth(r)
+		if used > width {
+			return text[:i]
+		}
+	}
+	return text
+}
+
+// pads text with spaces to width columns
+func padToWidth(text string, width int) string {
+	padding := width - displayWidth(text)
+	if padding <= 0 {
+		return text
+	}
+	return text + strings.Repeat(" ", padding)
+}
@@ -0,0 +1,117 @@
+package cmdline
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jimsnab/go-simpleutils"
+)
+
+// the global option registered by EnableDryRunOption
+const dryRunOption = "--dry-run"
+
+// how a dry run prints the resolved command line
+type DryRunFormat int
+
+const (
+	// prints the command, its values and the global options as readable text; this is the default
+	TextDryRun DryRunFormat = iota
+	// prints the Invocation as a single line JSON object
+	JSONDryRun
+)
+
+// makes Process print the resolved command line instead of calling any handlers
+func (cl *CommandLine) SetDryRun(enabled bool) {
+	cl.dryRun = enabled
+}
+
+// sets how a dry run prints the resolved command line
+func (cl *CommandLine) SetDryRunFormat(format DryRunFormat) {
+	cl.dryRunFormat = format
+}
+
+// registers a --dry-run global option, which makes Process print the resolved
+// command line instead of calling any handlers
+func (cl *CommandLine) EnableDryRunOption() {
+	_, exists := cl.globalOptions.values[dryRunOption]
+	if !exists {
+		cl.RegisterGlobalOption(func(values Values) error { return nil }, dryRunOption+"?Prints the resolved command line without running it")
+	}
+	cl.dryRunEnabled = true
+}
+
+// determines if the global options given request a dry run
+func (cl *CommandLine) isDryRun(globalOptions []string) bool {
+	if cl.dryRun {
+		return true
+	}
+	if cl.dryRunEnabled {
+		for _, name := range globalOptions {
+			if name == dryRunOption {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// prints the resolved command line in the dry run format
+func (cl *CommandLine) printDryRun(inv *Invocation) error {
+	report := Invocation{Command: inv.Command, Values: withoutSources(inv.Values), sources: inv.sources}
+	for _, opt := range inv.GlobalOptions {
+		if opt.Name != dryRunOption {
+			report.GlobalOptions = append(report.GlobalOptions, ParsedGlobalOption{Name: opt.Name, Values: withoutSources(opt.Values), sources: opt.sources})
+		}
+	}
+
+	prn := cl.printer()
+
+	if cl.dryRunFormat == JSONDryRun {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(&report); err != nil {
+			return err
+		}
+		prn.Println(string(bytes.TrimSuffix(buf.Bytes(), []byte("\n"))))
+		return nil
+	}
+
+	prn.Println(cl.tr("Command: %s", report.Command))
+	cl.printDryRunValues(report.Values, report.sources)
+	for _, opt := range report.GlobalOptions {
+		prn.Println(cl.tr("Global option: %s", opt.Name))
+		cl.printDryRunValues(opt.Values, opt.sources)
+	}
+	return nil
+}
+
+// prints each value, noting the source of one that wasn't given on the command line
+func (cl *CommandLine) printDryRunValues(values Values, sources map[string]ValueSource) {
+	for _, key := range simpleutils.SortedKeys(values) {
+		line := fmt.Sprintf("  %s: %v", key, values[key])
+		if source := valueSource(values, sources, key); source != SourceCommandLine {
+			line += fmt.Sprintf(" (%s)", source)
+		}
+		cl.printer().Println(line)
+	}
+}
+
+// drops the processing context, which isn't part of the command line
+func withoutContext(values Values) Values {
+	result := make(Values, len(values))
+	for k, v := range values {
+		if k != "" {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// drops the processing context and the value sources, which are reported apart
+func withoutSources(values Values) Values {
+	result := withoutContext(values)
+	delete(result, ValueSourcesKey)
+	return result
+}
@@ -0,0 +1,38 @@
+package cmdline
+
+// what Process does when an option that isn't repeatable is given more than once
+type DuplicateOptionPolicy int
+
+const (
+	// uses the last occurrence; a global option's handler runs for each occurrence,
+	// so the last one wins; this is the default
+	DuplicatesLast DuplicateOptionPolicy = iota
+	// uses the first occurrence, skipping the rest
+	DuplicatesFirst
+	// fails with ErrDuplicateOption
+	DuplicatesError
+)
+
+// sets what Process does when an option that isn't repeatable, i.e., doesn't start
+// with '*' and isn't a count, is given more than once, in any form, such as
+// --out:a --out b --out=c; the -v, -vv and -vvv options of EnableVerbosity count
+// their repeats, so they are repeatable
+func (cl *CommandLine) SetDuplicateOptionPolicy(policy DuplicateOptionPolicy) {
+	cl.duplicateOptionPolicy = policy
+}
+
+// checks another occurrence of an option, reporting if it is to be skipped
+func (cl *CommandLine) duplicateOption(as *argSpec, occurrences int, token string) (skip bool, err error) {
+	if occurrences == 0 || as.MultiValue || as.isCount() || cl.isVerbosityOption(as) {
+		return false, nil
+	}
+
+	switch cl.duplicateOptionPolicy {
+	case DuplicatesFirst:
+		return true, nil
+	case DuplicatesError:
+		return false, newKindError(ErrDuplicateOption, token, as.String(), "Argument %s is given more than once", as.Key)
+	default:
+		return false, nil
+	}
+}
@@ -0,0 +1,76 @@
+package cmdline
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// how Help reports a Process error
+type ErrorFormat int
+
+const (
+	// prints help text for the command line that failed; this is the default
+	TextErrors ErrorFormat = iota
+	// prints the error as a single line JSON object, for programs that run the tool
+	JSONErrors
+)
+
+// the JSON object printed for an error in the JSONErrors format
+type ErrorReport struct {
+	Code        string   `json:"code"`
+	Message     string   `json:"message"`
+	Arg         string   `json:"arg,omitempty"`
+	Spec        string   `json:"spec,omitempty"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// sets how Help reports a Process error
+func (cl *CommandLine) SetErrorFormat(format ErrorFormat) {
+	cl.errorFormat = format
+}
+
+// describes an error in the form printed by the JSONErrors format; the code is
+// derived from the error kind, such as "unknown_command", or is "error"
+func NewErrorReport(err error) *ErrorReport {
+	report := ErrorReport{Code: "error", Message: err.Error()}
+
+	var cle *CommandLineError
+	if errors.As(err, &cle) {
+		if cle.kind != nil {
+			report.Code = strings.ReplaceAll(cle.kind.Error(), " ", "_")
+		}
+		report.Arg = cle.token
+		report.Spec = cle.spec
+		if cle.suggestion != "" {
+			report.Suggestions = []string{cle.suggestion}
+		} else {
+			report.Suggestions = cle.alternatives
+		}
+	}
+
+	return &report
+}
+
+// provides the error in the JSON form of an ErrorReport
+func (e *CommandLineError) MarshalJSON() ([]byte, error) {
+	return NewErrorReport(e).marshal()
+}
+
+// encodes the report without escaping the <> of specs
+func (r *ErrorReport) marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(r); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func (hp *helpPrinter) helpPrintJSONError(err error) {
+	text, _ := NewErrorReport(err).marshal()
+	hp.helpPrintln(string(text))
+	hp.helpRender()
+}
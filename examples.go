@@ -0,0 +1,28 @@
+package cmdline
+
+import "strings"
+
+type commandExample struct {
+	commandLine string
+	description string
+}
+
+// adds an example invocation to a command's help; "" names the unnamed command
+func (cl *CommandLine) AddExample(cmdName string, commandLine string, description string) {
+	cmd := cl.lookupCommand(cmdName)
+	cmd.Examples = append(cmd.Examples, &commandExample{commandLine: commandLine, description: description})
+}
+
+func (hp *helpPrinter) helpPrintExamples(indent int, cmd *command) {
+	if len(cmd.Examples) == 0 {
+		return
+	}
+
+	hp.helpPrintln(strings.Repeat("  ", indent) + hp.tr("Examples:"))
+	for _, example := range cmd.Examples {
+		hp.helpPrintln(strings.Repeat("  ", indent+1) + example.commandLine)
+		if len(example.description) > 0 {
+			hp.helpPrintln(strings.Repeat("  ", indent+2) + example.description)
+		}
+	}
+}
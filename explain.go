@@ -0,0 +1,122 @@
+package cmdline
+
+import "fmt"
+
+// how a token of the command line was classified
+type ParseStepKind string
+
+const (
+	GlobalOptionStep ParseStepKind = "global option"
+	CommandStep      ParseStepKind = "command"
+	OptionStep       ParseStepKind = "option"
+	ValueStep        ParseStepKind = "value"
+	DefaultStep      ParseStepKind = "default"
+	RestStep         ParseStepKind = "rest"
+	AliasStep        ParseStepKind = "alias"
+)
+
+// one decision made while parsing a command line
+type ParseStep struct {
+	// the arg, after abbreviations and the like are expanded; "" for a default or
+	// for the unnamed command
+	Token string
+	Kind  ParseStepKind
+	// the key of the option or command the token belongs to, or the name of the
+	// defaulted value
+	Name string
+	// the value given for a default
+	Value any
+}
+
+func (ps ParseStep) String() string {
+	if ps.Kind == DefaultStep {
+		return fmt.Sprintf("%s %s = %v", ps.Kind, ps.Name, ps.Value)
+	}
+	return fmt.Sprintf("%q: %s %s", ps.Token, ps.Kind, ps.Name)
+}
+
+// records the steps of a parse for Explain; a nil trace records nothing
+type parseTrace struct {
+	steps []ParseStep
+}
+
+// resolves the command line like Parse, returning how each token was classified;
+// the global options come first, then the command, its values and options, and
+// then the values that were defaulted; on error, the steps made so far are returned
+func (cl *CommandLine) Explain(args []string) ([]ParseStep, error) {
+	trace := &parseTrace{}
+
+	globalArgs, err := cl.parseGlobalArgs(args, trace)
+	if err == nil {
+		_, err = cl.parseCommandArgs(nil, globalArgs)
+	}
+
+	return trace.steps, cl.localizeError(err)
+}
+
+func (tr *parseTrace) add(kind ParseStepKind, token, name string) {
+	if tr != nil {
+		tr.steps = append(tr.steps, ParseStep{Token: token, Kind: kind, Name: name})
+	}
+}
+
+// records an option or command token, masking a secret given after a colon
+func (tr *parseTrace) addOption(kind ParseStepKind, as *argSpec, name string, colonValue *string) {
+	if tr == nil {
+		return
+	}
+
+	token := name
+	if colonValue != nil {
+		if as.hasSecret() {
+			token += ":" + secretMask
+		} else {
+			token += ":" + *colonValue
+		}
+	}
+	tr.add(kind, token, as.Key)
+}
+
+// records the value tokens of an option or command, masking secrets
+func (tr *parseTrace) addValues(as *argSpec, values []string) {
+	if tr == nil {
+		return
+	}
+
+	secret := as.hasSecret()
+	for _, value := range values {
+		if secret {
+			value = secretMask
+		}
+		tr.add(ValueStep, value, as.Key)
+	}
+}
+
+// records the values of specs that were filled in with their defaults
+func (tr *parseTrace) addDefaults(values Values, specified map[string]bool, specs ...*argSpec) {
+	if tr == nil {
+		return
+	}
+
+	for _, as := range specs {
+		for _, valueSpec := range as.ValueSpecs {
+			if specified[valueSpec.OptionName] {
+				continue
+			}
+			value := values[valueSpec.OptionName]
+			if text, isString := value.(string); isString && valueSpec.TypeName == secretTypeName && len(text) > 0 {
+				value = secretMask
+			}
+			tr.steps = append(tr.steps, ParseStep{Kind: DefaultStep, Name: valueSpec.OptionName, Value: value})
+		}
+	}
+}
+
+func (as *argSpec) hasSecret() bool {
+	for _, valueSpec := range as.ValueSpecs {
+		if valueSpec.TypeName == secretTypeName {
+			return true
+		}
+	}
+	return false
+}
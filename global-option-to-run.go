@@ -1,8 +1,9 @@
 package cmdline
 
 type globalOptionToRun struct {
-	Option *globalOption
-	Values map[string]any
+	Option  *globalOption
+	Values  map[string]any
+	Sources map[string]ValueSource
 }
 
 func (cl *CommandLine) newGlobalOptionToRun(globalOpt *globalOption, colonValue *string, subsequentArgs []string) (*globalOptionToRun, int, error) {
@@ -21,5 +22,16 @@ func (cl *CommandLine) newGlobalOptionToRun(globalOpt *globalOption, colonValue
 		}
 	}
 
+	opt.Sources = cl.takeValueSources(opt.Values)
 	return &opt, argsUsed, nil
 }
+
+// finds the option to run for an earlier occurrence of a global option, or nil
+func findGlobalOptionToRun(globalOptionsToRun []*globalOptionToRun, globalOpt *globalOption) *globalOptionToRun {
+	for _, gotr := range globalOptionsToRun {
+		if gotr.Option == globalOpt {
+			return gotr
+		}
+	}
+	return nil
+}
@@ -1,11 +1,12 @@
 package cmdline
 
 type globalOption struct {
-	Handler CommandHandler
-	argSpec *argSpec
+	Handler  CommandHandlerCtx
+	Priority int
+	argSpec  *argSpec
 }
 
-func (cl *CommandLine) newGlobalOption(handler CommandHandler, spec string) *globalOption {
+func (cl *CommandLine) newGlobalOption(handler CommandHandlerCtx, spec string) *globalOption {
 	globalOpt := globalOption{}
 
 	globalOpt.Handler = handler
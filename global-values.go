@@ -0,0 +1,65 @@
+package cmdline
+
+// the prefix of the global option values given to a command handler
+const GlobalValuePrefix = "global."
+
+// makes the command handler's values include the global option values, with keys
+// prefixed by GlobalValuePrefix, such as "global.--env" and "global.name"; options
+// that weren't specified are given their defaults, as command options are
+func (cl *CommandLine) EnableGlobalValues() {
+	cl.globalValues = true
+}
+
+// adds the global option values to a command's values; when an option is repeated,
+// its last occurrence provides the values
+func (cl *CommandLine) addGlobalValues(cmdToRun *commandToRun, globalArgs *parsedGlobalArgs) error {
+	given := make(map[*globalOption]*globalOptionToRun)
+	for _, gotr := range globalArgs.globalOptionsToRun {
+		given[gotr.Option] = gotr
+	}
+
+	for _, name := range cl.globalOptions.order {
+		globalOpt := cl.globalOptions.values[name]
+		as := globalOpt.argSpec
+
+		values, sources := Values{}, map[string]ValueSource(nil)
+		gotr, specified := given[globalOpt]
+		if specified {
+			values, sources = gotr.Values, gotr.Sources
+		}
+
+		present := specified
+		if flag, isFlag := values[as.Key].(bool); isFlag {
+			present = flag
+		}
+		cmdToRun.values[GlobalValuePrefix+as.Key] = present
+		if !specified {
+			setValueSource(cmdToRun.values, GlobalValuePrefix+as.Key, SourceDefault)
+		}
+		if as.Negation != "" {
+			negated, _ := values[as.Negation].(bool)
+			cmdToRun.values[GlobalValuePrefix+as.Negation] = negated
+			if !specified {
+				setValueSource(cmdToRun.values, GlobalValuePrefix+as.Negation, SourceDefault)
+			}
+		}
+
+		for _, valueSpec := range as.ValueSpecs {
+			value, exists := values[valueSpec.OptionName]
+			source := valueSource(values, sources, valueSpec.OptionName)
+			if !exists {
+				var err error
+				value, source, err = as.defaultValue(valueSpec, values)
+				if err != nil {
+					return err
+				}
+			}
+			cmdToRun.values[GlobalValuePrefix+valueSpec.OptionName] = value
+			if source != SourceCommandLine {
+				setValueSource(cmdToRun.values, GlobalValuePrefix+valueSpec.OptionName, source)
+			}
+		}
+	}
+
+	return nil
+}
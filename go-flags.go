@@ -0,0 +1,77 @@
+package cmdline
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+
+	"github.com/jimsnab/go-simpleutils"
+)
+
+// a value name taken from a flag's usage must be a plain word
+var goFlagValueNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// registers the flags of a standard library flag set as global options, so flags
+// that packages such as glog or klog define in flag.CommandLine are accepted and
+// shown in help; a flag is given as -name or --name, with its value after a space
+// or '=', and a bool flag may be given alone or as -name=false. The handler sets
+// the flag, so an invalid value is an error from Process. A flag whose name is
+// already used by the command line, or isn't a valid option name, such as the
+// test.v flag of go test, is skipped.
+func (cl *CommandLine) BindGoFlags(fs *flag.FlagSet) {
+	if fs == nil {
+		panic(fmt.Errorf("argument error: BindGoFlags requires a flag set"))
+	}
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if !simpleutils.IsTokenNameWithMiddleChars(f.Name, cl.nameMiddleChars(false)) {
+			return
+		}
+
+		names := []string{"--" + f.Name, "-" + f.Name}
+		if len(f.Name) == 1 {
+			names[0], names[1] = names[1], names[0]
+		}
+		for _, name := range names {
+			if cl.globalNames[name] || cl.commandNames[name] {
+				return
+			}
+		}
+
+		spec, valueName := goFlagSpec(f, names)
+		cl.RegisterGlobalOption(func(values Values) error {
+			text := fmt.Sprint(values[valueName])
+			if err := fs.Set(f.Name, text); err != nil {
+				return newKindError(ErrInvalidValue, text, "", "Invalid value %s for -%s: %s", text, f.Name, err.Error())
+			}
+			return nil
+		}, spec)
+	})
+}
+
+// builds the global option spec of a flag, such as "--log_dir|-log_dir <string-dir>",
+// and provides the name of its value; a bool flag given alone is true
+func goFlagSpec(f *flag.Flag, names []string) (spec string, valueName string) {
+	valueName, usage := flag.UnquoteUsage(f)
+	if !goFlagValueNamePattern.MatchString(valueName) {
+		valueName = "value"
+	}
+
+	spec = names[0] + "|" + names[1]
+	if isGoBoolFlag(f) {
+		valueName = "value"
+		spec += "[:<bool-value=true>]"
+	} else {
+		spec += fmt.Sprintf(" <string-%s>", valueName)
+	}
+
+	if f.DefValue != "" && f.DefValue != "0" && f.DefValue != "false" {
+		usage += fmt.Sprintf(" (default %s)", f.DefValue)
+	}
+	return spec + "?" + usage, valueName
+}
+
+func isGoBoolFlag(f *flag.Flag) bool {
+	bf, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && bf.IsBoolFlag()
+}
@@ -6,10 +6,8 @@ require (
 	github.com/jimsnab/go-simpleutils v1.0.14
 	github.com/jimsnab/go-testutils v1.0.12
 	github.com/jimsnab/go-toolprinter v1.0.12
+	golang.org/x/sys v0.18.0
+	golang.org/x/term v0.18.0
 )
 
-require (
-	github.com/djherbis/atime v1.1.0 // indirect
-	golang.org/x/sys v0.18.0 // indirect
-	golang.org/x/term v0.18.0 // indirect
-)
+require github.com/djherbis/atime v1.1.0 // indirect
@@ -0,0 +1,85 @@
+package cmdline
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// the narrowest help width used, regardless of the terminal
+const minLineWidth = 40
+
+type terminalData interface {
+	IsTerminal(fd int) bool
+	GetSize(fd int) (width int, height int, err error)
+	ReadPassword(fd int) ([]byte, error)
+	// turns on the processing of ANSI sequences, reporting if they work
+	EnableVirtualTerminal(fd int) bool
+}
+
+type defaultTerminal struct {
+}
+
+func (t *defaultTerminal) IsTerminal(fd int) bool {
+	return term.IsTerminal(fd)
+}
+
+func (t *defaultTerminal) GetSize(fd int) (int, int, error) {
+	return term.GetSize(fd)
+}
+
+func (t *defaultTerminal) ReadPassword(fd int) ([]byte, error) {
+	return term.ReadPassword(fd)
+}
+
+func (t *defaultTerminal) EnableVirtualTerminal(fd int) bool {
+	return enableVirtualTerminal(fd)
+}
+
+var xterm = terminalData(&defaultTerminal{})
+
+// sets the column at which help text wraps; zero restores the terminal width
+func (cl *CommandLine) SetHelpWidth(width int) {
+	cl.helpWidth = width
+}
+
+// the help wrap column: the width set by SetHelpWidth, or the width of the
+// terminal up to maxLineWidth, or maxLineWidth when output isn't a terminal
+func (cl *CommandLine) helpLineWidth() int {
+	if cl.helpWidth > 0 {
+		return cl.helpWidth
+	}
+
+	if cl.output != nil {
+		return maxLineWidth
+	}
+
+	fd := int(os.Stdout.Fd())
+	if !xterm.IsTerminal(fd) {
+		return maxLineWidth
+	}
+
+	width, _, err := xterm.GetSize(fd)
+	if err != nil || width <= 0 {
+		return maxLineWidth
+	}
+
+	// stay off the last column, where some terminals wrap on their own
+	width--
+
+	if width > maxLineWidth {
+		return maxLineWidth
+	} else if width < minLineWidth {
+		return minLineWidth
+	}
+	return width
+}
+
+// the widest the first help column can be, narrowed on a narrow line
+func helpRiverLimit(lineWidth int) int {
+	limit := lineWidth / 3
+	if limit > maxRiver {
+		return maxRiver
+	}
+	return limit
+}
@@ -1,8 +1,120 @@
 package cmdline
 
-import "strings"
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// returned by Process after auto help prints the help
+var ErrHelpShown = errors.New("help shown")
+
+// the order of commands and global options in the help
+type HelpOrder int
+
+const (
+	// sorts commands and global options by name; this is the default
+	Alphabetical HelpOrder = iota
+	// lists commands and global options in the order they were registered
+	RegistrationOrder
+)
+
+// sets the display order of commands and global options; command options are
+// always listed in registration order
+func (cl *CommandLine) SetHelpOrder(order HelpOrder) {
+	cl.helpOrder = order
+}
+
+// makes Process handle --help, -h and help <filter> by printing help and returning ErrHelpShown
+func (cl *CommandLine) EnableAutoHelp() {
+	cl.autoHelpEnabled = true
+}
+
+// sets the application name printed in help; the default is the executable name
+func (cl *CommandLine) SetAppName(appName string) {
+	cl.appName = appName
+}
+
+// replaces the generated usage line of the full help, e.g. "mytool [global options] <command> [args]"
+func (cl *CommandLine) SetUsage(usage string) {
+	cl.usage = usage
+}
+
+func (cl *CommandLine) getAppName() string {
+	if cl.appName != "" {
+		return cl.appName
+	}
+	return filepath.Base(os.Args[0])
+}
+
+// prints the help requested by the args, when auto help is enabled
+func (cl *CommandLine) autoHelp(args []string) bool {
+	if !cl.autoHelpEnabled || len(args) == 0 {
+		return false
+	}
+
+	appName := cl.getAppName()
+	full, args := cl.fullHelpArgs(args)
+	if len(args) == 0 {
+		return false
+	}
+
+	// help <filter>, unless help is a command
+	_, helpIsCommand := cl.commands.values["help"]
+	if args[0] == "help" && !helpIsCommand {
+		cl.showHelp(full, appName, args)
+		return true
+	}
+
+	primary := cl.PrimaryCommand(args)
+	for i, arg := range args {
+		if arg != "--help" && arg != "-h" {
+			continue
+		}
+
+		// the switch might be registered by the app
+		_, exists := cl.globalOptions.lookup(arg)
+		if exists {
+			continue
+		}
+		if primary != "" {
+			_, exists = cl.commands.values[primary].OptionSpecs.lookup(arg)
+			if exists {
+				continue
+			}
+		}
+
+		hp := cl.newHelpPrinter()
+		hp.detailed = full
+		if primary != "" && hp.printCommandWorker(primary) == nil {
+			hp.helpRender()
+		} else if i == 0 && len(args) == 2 {
+			cl.showHelp(full, appName, []string{"--help", args[1]})
+		} else {
+			cl.showHelp(full, appName, []string{})
+		}
+		return true
+	}
+
+	return false
+}
 
 func (cl *CommandLine) Help(err error, appName string, args []string) {
+	cl.LoadLazyCommands()
+	if errors.Is(err, ErrVersionShown) || errors.Is(err, ErrHelpShown) || errors.Is(err, ErrCompletionShown) {
+		return
+	}
+
+	if err != nil && cl.errorFormat == JSONErrors {
+		cl.newHelpPrinter().helpPrintJSONError(err)
+		return
+	}
+
+	cl.newHelpPrinter().help(err, appName, args)
+}
+
+func (hp *helpPrinter) help(err error, appName string, args []string) {
 
 	ok := true
 	if err != nil {
@@ -27,31 +139,31 @@ func (cl *CommandLine) Help(err error, appName string, args []string) {
 					filter = ""
 				}
 			}
-			cl.printCommandsWorker(filter, true)
-		} else if len(args) > 0 && len(cl.PrimaryCommand(args)) > 0 {
+			hp.printCommandsWorker(filter, true)
+		} else if len(args) > 0 && len(hp.PrimaryCommand(args)) > 0 {
 			// command line specified a command but had an error; show help for the command
-			cl.helpPrintBlanklnFirst()
-			cl.helpPrintln("Syntax error.")
-			cl.helpPrintBlankln()
-			cl.helpPrintln("Command Help:")
-			cl.helpPrintBlankln()
-			cl.printCommandWorker(cl.PrimaryCommand(args))
-			cl.helpPrintBlankln()
+			hp.helpPrintBlanklnFirst()
+			hp.helpPrintln(hp.tr("Syntax error."))
+			hp.helpPrintBlankln()
+			hp.helpPrintln(hp.tr("Command Help:"))
+			hp.helpPrintBlankln()
+			hp.printCommandWorker(hp.PrimaryCommand(args))
+			hp.helpPrintBlankln()
 		} else {
 			// show full help
 			var options string
-			if len(cl.globalOptions.values) == 0 {
+			if len(hp.globalOptions.values) == 0 {
 				options = ""
-			} else if len(cl.commands.values) == 1 {
-				options = " <options>"
+			} else if len(hp.commands.values) == 1 {
+				options = " " + hp.tr("<options>")
 			} else {
-				options = " <global options>"
+				options = " " + hp.tr("<global options>")
 			}
 
 			cmdOptions := ""
-			for _, cmd := range cl.commands.values {
+			for _, cmd := range hp.commands.values {
 				if len(cmd.OptionSpecs.values) > 0 || len(cmd.PrimaryArgSpec.ValueSpecs) > 0 {
-					cmdOptions = " <options>"
+					cmdOptions = " " + hp.tr("<options>")
 					break
 				}
 			}
@@ -59,24 +171,28 @@ func (cl *CommandLine) Help(err error, appName string, args []string) {
 				cmdOptions = "" // remove redundancy
 			}
 
-			cmdToken := " <command>"
-			if cl.unnamedCmd != nil {
+			cmdToken := " " + hp.tr("<command>")
+			if hp.unnamedCmd != nil {
 				cmdToken = ""
 			}
 
-			cl.helpPrintln("Usage: " + appName + options + cmdToken + cmdOptions)
-			cl.helpPrintBlankln()
-			cl.printCommandsWorker("", true)
+			if len(hp.usage) > 0 {
+				hp.helpPrintln(hp.tr("Usage: %s", hp.usage))
+			} else {
+				hp.helpPrintln(hp.tr("Usage: %s", appName+options+cmdToken+cmdOptions))
+			}
+			hp.helpPrintBlankln()
+			hp.printCommandsWorker("", true)
 
 			helpLen := 0
-			for _, cmd := range cl.commands.values {
+			for _, cmd := range hp.commands.values {
 				helpLen += 60 // fudge factor for each line
-				helpLen += len(cmd.PrimaryArgSpec.HelpText)
-				helpLen += len(cmd.PrimaryArgSpec.String())
+				helpLen += displayWidth(cmd.PrimaryArgSpec.HelpText)
+				helpLen += displayWidth(cmd.PrimaryArgSpec.String())
 				for _, optionSpec := range cmd.OptionSpecs.values {
 					helpLen += 60 // fudge factor for each line
-					helpLen += len(optionSpec.HelpText)
-					helpLen += len(optionSpec.String())
+					helpLen += displayWidth(optionSpec.HelpText)
+					helpLen += displayWidth(optionSpec.String())
 				}
 			}
 
@@ -85,33 +201,36 @@ func (cl *CommandLine) Help(err error, appName string, args []string) {
 
 				// pick the first command's argument for an example
 				sampleArg := ""
-				if len(cl.commands.values) > 0 {
-					for _, cmdName := range cl.commands.order {
-						cmd := cl.commands.values[cmdName]
+				if len(hp.commands.values) > 0 {
+					for _, cmdName := range hp.commands.order {
+						cmd := hp.commands.values[cmdName]
+						if !cmd.enabled() {
+							continue
+						}
 						sampleArg = cmd.PrimaryArgSpec.Key
 						break
 					}
 				}
 
-				cl.helpPrintBlankln()
+				hp.helpPrintBlankln()
 
 				if sampleArg == "" || sampleArg == "~" {
 					// unnamed primary arg
-					cl.helpPrintln("Search help with: " + appName + " --help <filter text>")
+					hp.helpPrintln(hp.tr("Search help with: %s --help <filter text>", appName))
 				} else {
-					cl.helpPrintln("Search help with " + appName + " --help <filter text>. Example: " + appName + " --help " + sampleArg)
-					cl.helpPrintln("Or, put a question mark on the end. Example: " + appName + " " + sampleArg + "?")
+					hp.helpPrintln(hp.tr("Search help with %s --help <filter text>. Example: %s --help %s", appName, appName, sampleArg))
+					hp.helpPrintln(hp.tr("Or, put a question mark on the end. Example: %s %s?", appName, sampleArg))
 				}
 
-				cl.helpPrintBlankln()
+				hp.helpPrintBlankln()
 			}
 		}
 	} else {
 		// processing produced an error
-		cl.helpPrintln("")
-		cl.helpPrintln(err.Error())
-		cl.helpPrintln("")
+		hp.helpPrintln("")
+		hp.helpPrintln(err.Error())
+		hp.helpPrintln("")
 	}
 
-	cl.helpRender()
+	hp.helpRender()
 }
@@ -0,0 +1,53 @@
+package cmdline
+
+// the command line resolved by the most recent Process call
+type Invocation struct {
+	// the command key, such as "users create", or "~" for the unnamed command
+	Command string `json:"command"`
+	// a copy of the values given to the command handler, with secrets masked
+	Values Values `json:"values,omitempty"`
+	// copies of the values given to the global option handlers, in the order they ran,
+	// with secrets masked
+	GlobalOptions []ParsedGlobalOption `json:"global_options,omitempty"`
+
+	sources map[string]ValueSource
+}
+
+// provides the command line resolved by the most recent Process call, or nil if
+// the command line was invalid; it is recorded before the command handler runs
+func (cl *CommandLine) LastInvocation() *Invocation {
+	cl.lastMu.Lock()
+	defer cl.lastMu.Unlock()
+	return cl.lastInvocation
+}
+
+// records the resolved command line, or clears it when cmdToRun is nil
+func (cl *CommandLine) recordInvocation(globalArgs *parsedGlobalArgs, cmdToRun *commandToRun) {
+	var inv *Invocation
+	if cmdToRun != nil {
+		inv = &Invocation{
+			Command: cmdToRun.cmd.PrimaryArgSpec.Key,
+			Values:  maskCommandSecrets(cmdToRun.cmd, copyValues(cmdToRun.values)),
+			sources: cmdToRun.sources,
+		}
+		for _, gotr := range globalArgs.globalOptionsToRun {
+			inv.GlobalOptions = append(inv.GlobalOptions, ParsedGlobalOption{
+				Name:    gotr.Option.argSpec.Key,
+				Values:  maskSecretValues(copyValues(gotr.Values), gotr.Option.argSpec),
+				sources: gotr.Sources,
+			})
+		}
+	}
+
+	cl.lastMu.Lock()
+	defer cl.lastMu.Unlock()
+	cl.lastInvocation = inv
+}
+
+func copyValues(values Values) Values {
+	snapshot := make(Values, len(values))
+	for k, v := range values {
+		snapshot[k] = v
+	}
+	return snapshot
+}
@@ -0,0 +1,95 @@
+package cmdline
+
+import (
+	"fmt"
+	"strings"
+)
+
+// the specs of a lazily registered command: the primary spec followed by the
+// option specs, as given to RegisterCommand
+type CommandSpecSet struct {
+	Specs []string
+}
+
+// builds the specs and handler of a lazily registered command
+type LazyCommandLoader func() (*CommandSpecSet, CommandHandler)
+
+// registers a command by name only; the loader builds its specs and handler the
+// first time the command is on the command line, or when help, completion or the
+// metadata needs every command
+func (cl *CommandLine) RegisterLazyCommand(name string, loader LazyCommandLoader) {
+	if len(name) == 0 || loader == nil {
+		panic(fmt.Errorf("argument error: a lazy command requires a name and a loader"))
+	}
+
+	key := specKey(name, true)
+	if _, exists := cl.commands.values[key]; exists || cl.lazyCommands[key] != nil || cl.globalNames[key] {
+		panic(fmt.Errorf("%sunique argument \"%s\"", basePanic, key))
+	}
+
+	if cl.lazyCommands == nil {
+		cl.lazyCommands = map[string]LazyCommandLoader{}
+	}
+	cl.lazyCommands[key] = loader
+	cl.lazyOrder = append(cl.lazyOrder, key)
+}
+
+// registers every lazy command that hasn't been loaded yet; loading registers a
+// command, so call this before using the command line from multiple goroutines
+func (cl *CommandLine) LoadLazyCommands() {
+	for len(cl.lazyOrder) > 0 {
+		cl.loadLazyCommand(cl.lazyOrder[0])
+	}
+}
+
+func (cl *CommandLine) loadLazyCommand(key string) {
+	loader := cl.lazyCommands[key]
+	delete(cl.lazyCommands, key)
+	for i, name := range cl.lazyOrder {
+		if name == key {
+			cl.lazyOrder = append(cl.lazyOrder[:i], cl.lazyOrder[i+1:]...)
+			break
+		}
+	}
+
+	specSet, handler := loader()
+	if specSet == nil || len(specSet.Specs) == 0 || handler == nil {
+		panic(fmt.Errorf("argument error: lazy command \"%s\" requires specs and a handler", key))
+	}
+
+	cl.RegisterCommand(handler, specSet.Specs...)
+
+	if _, exists := cl.commands.values[key]; !exists {
+		panic(fmt.Errorf("argument error: lazy command \"%s\" loaded the spec \"%s\"", key, specSet.Specs[0]))
+	}
+}
+
+// loads the lazy commands that the args name, such as "users create"; the words
+// from an arg are taken only as far as they continue the name of a lazy command
+func (cl *CommandLine) loadNamedLazyCommands(args []string) {
+	if len(cl.lazyOrder) == 0 {
+		return
+	}
+
+	// the names of the lazy commands and their leading words, such as "users"
+	prefixes := map[string]bool{}
+	for _, key := range cl.lazyOrder {
+		words := strings.Split(key, " ")
+		for n := 1; n <= len(words); n++ {
+			prefixes[strings.Join(words[:n], " ")] = true
+		}
+	}
+
+	for i := range args {
+		key := args[i]
+		for n := i + 1; prefixes[key]; n++ {
+			if cl.lazyCommands[key] != nil {
+				cl.loadLazyCommand(key)
+			}
+			if n >= len(args) {
+				break
+			}
+			key += " " + args[n]
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package cmdline
+
+import (
+	"errors"
+	"fmt"
+)
+
+// translates the English messages, help headings and help text of a command line;
+// a message is a fmt format, such as "Unrecognized command: %s", and the
+// translation must keep its verbs in order
+type Translator interface {
+	// provides the translation of the message, or the message itself if there is none
+	Translate(message string) string
+}
+
+// a Translator from a map of English messages to their translations
+type MessageCatalog map[string]string
+
+func (mc MessageCatalog) Translate(message string) string {
+	if translation, exists := mc[message]; exists {
+		return translation
+	}
+	return message
+}
+
+// translates the errors returned by Process and Parse, and the help; the help text
+// of a spec is translated too, so it can be a key, such as "?users.create.help";
+// nil restores English
+func (cl *CommandLine) SetTranslator(translator Translator) {
+	cl.translator = translator
+}
+
+// formats a message in the translated form
+func (cl *CommandLine) tr(format string, args ...any) string {
+	if cl.translator != nil {
+		format = cl.translator.Translate(format)
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// translates help text from a spec
+func (cl *CommandLine) helpText(text string) string {
+	if cl.translator == nil || len(text) == 0 {
+		return text
+	}
+	return cl.translator.Translate(text)
+}
+
+// rewrites the message of a command line error in the translated form
+func (cl *CommandLine) localizeError(err error) error {
+	if cl.translator == nil || err == nil {
+		return err
+	}
+
+	var cle *CommandLineError
+	if errors.As(err, &cle) && len(cle.format) > 0 {
+		cle.reason = cl.tr(cle.format, cle.args...) + cle.hintText(cl.tr)
+	}
+	return err
+}
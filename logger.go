@@ -0,0 +1,41 @@
+package cmdline
+
+// receives debug messages about how a command line was processed, as alternating
+// keys and values; a *slog.Logger satisfies it
+type Logger interface {
+	Debug(msg string, args ...any)
+}
+
+// sets the logger of parse decisions, matched commands and handler errors; nil removes it
+func (cl *CommandLine) SetLogger(logger Logger) {
+	cl.logger = logger
+}
+
+func (cl *CommandLine) logDebug(msg string, args ...any) {
+	if cl.logger != nil {
+		cl.logger.Debug(msg, args...)
+	}
+}
+
+// logs the global options found, with secrets masked
+func (cl *CommandLine) logGlobalOptions(globalArgs *parsedGlobalArgs) {
+	if cl.logger == nil {
+		return
+	}
+	for _, gotr := range globalArgs.globalOptionsToRun {
+		values := maskSecretValues(withoutContext(gotr.Values), gotr.Option.argSpec)
+		cl.logDebug("cmdline global option", "option", gotr.Option.argSpec.Key, "values", values)
+	}
+	if len(globalArgs.commandArgs) > 0 {
+		cl.logDebug("cmdline command args", "count", len(globalArgs.commandArgs))
+	}
+}
+
+// logs the command matched, with secrets masked
+func (cl *CommandLine) logCommand(cmdToRun *commandToRun) {
+	if cl.logger == nil {
+		return
+	}
+	values := maskCommandSecrets(cmdToRun.cmd, withoutContext(cmdToRun.values))
+	cl.logDebug("cmdline command matched", "command", cmdToRun.cmd.PrimaryArgSpec.Key, "values", values)
+}
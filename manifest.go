@@ -0,0 +1,98 @@
+package cmdline
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// the declarative form of a command line, read by LoadCommandManifest
+type CommandManifest struct {
+	GlobalOptions []ManifestGlobalOption `json:"global_options,omitempty"`
+	Commands      []ManifestCommand      `json:"commands,omitempty"`
+}
+
+type ManifestGlobalOption struct {
+	// the name given to RegisterNamedHandler
+	Handler  string `json:"handler"`
+	Spec     string `json:"spec"`
+	Priority int    `json:"priority,omitempty"`
+}
+
+type ManifestCommand struct {
+	// the name given to RegisterNamedHandler
+	Handler string `json:"handler"`
+	// the primary spec followed by the option specs, as given to RegisterCommand
+	Specs []string `json:"specs"`
+	Group string   `json:"group,omitempty"`
+}
+
+// names a handler, so a command manifest can bind commands to it
+func (cl *CommandLine) RegisterNamedHandler(name string, handler CommandHandler) {
+	cl.RegisterNamedHandlerCtx(name, ctxHandler(handler))
+}
+
+// names a handler that receives the context given to ProcessContext
+func (cl *CommandLine) RegisterNamedHandlerCtx(name string, handler CommandHandlerCtx) {
+	if len(name) == 0 || handler == nil {
+		panic(fmt.Errorf("argument error: a named handler requires a name and a function"))
+	}
+
+	if cl.namedHandlers == nil {
+		cl.namedHandlers = map[string]CommandHandlerCtx{}
+	}
+	cl.namedHandlers[name] = handler
+}
+
+// registers the global options and commands of a JSON command manifest, binding
+// them to the handlers registered with RegisterNamedHandler; a malformed manifest
+// or spec is returned as an error, and stops the loading where it occurs
+func (cl *CommandLine) LoadCommandManifest(r io.Reader) (err error) {
+	var manifest CommandManifest
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(&manifest); err != nil {
+		return fmt.Errorf("command manifest error: %w", err)
+	}
+
+	// a spec syntax error panics, as it would for RegisterCommand
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = fmt.Errorf("command manifest error: %w", e)
+			} else {
+				err = fmt.Errorf("command manifest error: %v", r)
+			}
+		}
+	}()
+
+	for _, opt := range manifest.GlobalOptions {
+		handler, err := cl.namedHandler(opt.Handler)
+		if err != nil {
+			return err
+		}
+		cl.RegisterGlobalOptionCtxWithPriority(handler, opt.Spec, opt.Priority)
+	}
+
+	for _, cmd := range manifest.Commands {
+		handler, err := cl.namedHandler(cmd.Handler)
+		if err != nil {
+			return err
+		}
+		cl.RegisterCommandCtx(handler, cmd.Specs...)
+
+		if len(cmd.Group) > 0 {
+			cl.SetCommandGroup(cl.commands.order[len(cl.commands.order)-1], cmd.Group)
+		}
+	}
+
+	return nil
+}
+
+func (cl *CommandLine) namedHandler(name string) (CommandHandlerCtx, error) {
+	handler, exists := cl.namedHandlers[name]
+	if !exists {
+		return nil, fmt.Errorf("command manifest error: handler \"%s\" is not registered", name)
+	}
+	return handler, nil
+}
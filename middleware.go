@@ -0,0 +1,46 @@
+package cmdline
+
+import "context"
+
+// wraps a handler with cross-cutting behavior, such as timing or audit logging
+type Middleware func(next CommandHandler) CommandHandler
+
+// wraps a handler that receives the context given to ProcessContext
+type MiddlewareCtx func(next CommandHandlerCtx) CommandHandlerCtx
+
+// wraps every command handler with the middleware; the first middleware added is
+// the outermost, and the middleware applies to commands registered before or after
+func (cl *CommandLine) Use(middleware Middleware) {
+	cl.UseCtx(func(next CommandHandlerCtx) CommandHandlerCtx {
+		return func(ctx context.Context, values Values) error {
+			handler := middleware(func(values Values) error {
+				return next(ctx, values)
+			})
+			return handler(values)
+		}
+	})
+}
+
+// wraps every command handler with middleware that receives the context
+func (cl *CommandLine) UseCtx(middleware MiddlewareCtx) {
+	cl.middleware = append(cl.middleware, middleware)
+}
+
+// makes the middleware wrap the global option handlers too
+func (cl *CommandLine) SetMiddlewareForGlobalOptions(enable bool) {
+	cl.wrapGlobals = enable
+}
+
+func (cl *CommandLine) wrapHandler(handler CommandHandlerCtx) CommandHandlerCtx {
+	for i := len(cl.middleware) - 1; i >= 0; i-- {
+		handler = cl.middleware[i](handler)
+	}
+	return cl.recoverHandler(handler)
+}
+
+func (cl *CommandLine) wrapGlobalHandler(handler CommandHandlerCtx) CommandHandlerCtx {
+	if !cl.wrapGlobals {
+		return cl.recoverHandler(handler)
+	}
+	return cl.wrapHandler(handler)
+}
@@ -0,0 +1,57 @@
+package cmdline
+
+import (
+	"fmt"
+	"strings"
+)
+
+// canonicalizes the text of a value before it is converted to its type and validated
+type ValueNormalizer func(text string) string
+
+// the normalizers that can be named in a spec after the type, such as <string:lower-email>
+var specNormalizers = map[string]ValueNormalizer{
+	"trim":  strings.TrimSpace,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// adds a normalizer for every value spec with the given name, such as "email" in
+// <string-email>; normalizers run in the order added, after those of the spec,
+// before the value is converted and validated
+func (cl *CommandLine) AddNormalizer(valueName string, normalizer ValueNormalizer) {
+	if len(valueName) == 0 || normalizer == nil {
+		panic(fmt.Errorf("argument error: a normalizer requires a value name and a function"))
+	}
+
+	if cl.normalizers == nil {
+		cl.normalizers = map[string][]ValueNormalizer{}
+	}
+	cl.normalizers[valueName] = append(cl.normalizers[valueName], normalizer)
+}
+
+// splits the normalizers from a spec type at typePos, such as string:trim:lower,
+// providing the type name
+func parseSpecNormalizers(avs *argValueSpec, optionType string, s *specScanner, typePos int) string {
+	names := strings.Split(optionType, ":")
+	pos := typePos + len(names[0]) + 1
+	for _, name := range names[1:] {
+		normalizer := specNormalizers[name]
+		if normalizer == nil {
+			panic(s.errorAt(pos, "normalizer trim, lower or upper"))
+		}
+		avs.Normalizers = append(avs.Normalizers, normalizer)
+		avs.NormalizerNames = append(avs.NormalizerNames, name)
+		pos += len(name) + 1
+	}
+	return names[0]
+}
+
+func (cl *CommandLine) normalizeValue(spec *argValueSpec, text string) string {
+	for _, normalizer := range spec.Normalizers {
+		text = normalizer(text)
+	}
+	for _, normalizer := range cl.normalizers[spec.OptionName] {
+		text = normalizer(text)
+	}
+	return text
+}
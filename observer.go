@@ -0,0 +1,92 @@
+package cmdline
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// receives the usage of the command line, such as for metrics; the callbacks are
+// made on the goroutine that called Process
+type Observer interface {
+	// called when the command is resolved, before its handler runs
+	CommandStarted(command string)
+	// called when processing ends, including when the command line was invalid
+	CommandFinished(event CommandEvent)
+}
+
+// the outcome of processing a command line
+type CommandEvent struct {
+	// the command key, such as "users create", or "" when the command line was invalid
+	Command string
+	// the time from when the command was resolved until its handler returned, or zero
+	// if the command wasn't resolved
+	Duration time.Duration
+	// the error returned by Process, or nil
+	Err error
+	// classifies Err, such as "unknown_command", "help_shown" or "panic"; "" when Err is nil
+	Code string
+	// the exit code for Err
+	ExitCode int
+}
+
+// sets the observer of commands run by Process; nil removes it
+func (cl *CommandLine) SetObserver(o Observer) {
+	cl.observer = o
+}
+
+// tracks one command line for the observer
+type observation struct {
+	observer Observer
+	command  string
+	start    time.Time
+}
+
+func (cl *CommandLine) observe() *observation {
+	return &observation{observer: cl.observer}
+}
+
+func (o *observation) started(cmd *command) {
+	if o.observer == nil {
+		return
+	}
+	o.command = cmd.PrimaryArgSpec.Key
+	o.start = time.Now()
+	o.observer.CommandStarted(o.command)
+}
+
+func (o *observation) finished(err error) {
+	if o.observer == nil {
+		return
+	}
+
+	event := CommandEvent{Command: o.command, Err: err, Code: errorCode(err), ExitCode: ExitCode(err)}
+	if !o.start.IsZero() {
+		event.Duration = time.Since(o.start)
+	}
+	o.observer.CommandFinished(event)
+}
+
+// classifies an error for an observer
+func errorCode(err error) string {
+	var pe *PanicError
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrHelpShown):
+		return "help_shown"
+	case errors.Is(err, ErrVersionShown):
+		return "version_shown"
+	case errors.Is(err, ErrCompletionShown):
+		return "completion_shown"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	case errors.As(err, &pe):
+		return "panic"
+	default:
+		return NewErrorReport(err).Code
+	}
+}
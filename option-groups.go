@@ -0,0 +1,52 @@
+package cmdline
+
+import (
+	"fmt"
+	"strings"
+)
+
+// assigns an option of a command to a named group, so the command's help lists it
+// under "<group> options:"
+func (cl *CommandLine) SetOptionGroup(cmdName string, option string, group string) {
+	cmd := cl.lookupCommand(cmdName)
+
+	optionSpec, exists := cmd.OptionSpecs.lookup(option)
+	if !exists {
+		panic(fmt.Errorf("argument error: option \"%s\" is not registered for command \"%s\"", option, cmdName))
+	}
+	optionSpec.Group = group
+
+	for _, existing := range cmd.OptionGroups {
+		if existing == group {
+			return
+		}
+	}
+	cmd.OptionGroups = append(cmd.OptionGroups, group)
+}
+
+// prints the ungrouped options of a command, followed by a section for each group
+// in the order the groups were first assigned
+func (hp *helpPrinter) helpPrintOptions(indent int, cmd *command) {
+	groups := append([]string{""}, cmd.OptionGroups...)
+	for _, group := range groups {
+		heading := len(group) > 0
+		for _, optionName := range cmd.OptionSpecs.order {
+			option := cmd.OptionSpecs.values[optionName]
+			if option.Group != group {
+				continue
+			}
+
+			if heading {
+				hp.helpPrintln(strings.Repeat("  ", indent) + hp.tr("%s options:", group))
+				heading = false
+			}
+
+			optionIndent := indent
+			if len(group) > 0 {
+				optionIndent++
+			}
+			hp.helpPrintCols(optionIndent, option.String(), hp.helpText(option.HelpText))
+			hp.helpPrintDetails(optionIndent, option)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package cmdline
+
+// where options can appear among the args
+type OptionParsing int
+
+const (
+	// global options can appear anywhere, and command options follow the values of
+	// the command; this is the default
+	InterspersedOptions OptionParsing = iota
+	// options must precede the command and its values, and the first arg that isn't
+	// an option ends option parsing, like POSIXLY_CORRECT; the args that follow the
+	// values are unknown args, handled according to SetUnknownArgPolicy
+	OrderedOptions
+)
+
+// sets where options can appear among the args; wrappers of other tools use
+// OrderedOptions so the options of the wrapped tool aren't parsed
+func (cl *CommandLine) SetOptionParsing(mode OptionParsing) {
+	cl.optionParsing = mode
+}
+
+// applies the slash, abbreviation and equals forms to global option args;
+// abbreviations are expanded only when abbreviate is set
+func (cl *CommandLine) expandGlobalArgs(args []string, abbreviate bool) ([]string, error) {
+	if cl.slashOptions {
+		args = expandSlashArgs(args, cl.lookupGlobalArgSpec)
+	}
+	if cl.abbreviations && abbreviate {
+		var err error
+		args, err = expandAbbreviations(args, cl.globalOptions.names(), cl.lookupGlobalArgSpec)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return expandEqualsArgs(args, cl.lookupGlobalArgSpec), nil
+}
+
+// applies the slash, abbreviation, equals and short flag forms to command option args
+func (cl *CommandLine) expandOptionArgs(cmd *command, args []string) ([]string, error) {
+	if cl.slashOptions {
+		args = expandSlashArgs(args, cmd.OptionSpecs.lookup)
+	}
+	if cl.abbreviations {
+		var err error
+		args, err = expandAbbreviations(args, cmd.OptionSpecs.names(), cmd.OptionSpecs.lookup)
+		if err != nil {
+			return nil, err
+		}
+	}
+	args = expandEqualsArgs(args, cmd.OptionSpecs.lookup)
+	if cl.shortFlagClustering {
+		args = cl.expandShortFlags(cmd, args)
+	}
+	return args, nil
+}
+
+// an arg is an option when it has the form of a switch, except for a negative
+// number given to a numeric value of the command
+func (cmd *command) isOptionArg(arg string) bool {
+	if len(arg) < 2 || arg[0] != '-' {
+		return false
+	}
+	valueSpecs := cmd.PrimaryArgSpec.ValueSpecs
+	return len(valueSpecs) == 0 || !valueSpecs[0].isValueArg(arg)
+}
+
+// replaces args[i] with the expanded args
+func spliceArgs(args []string, i int, expanded []string) []string {
+	if len(expanded) == 1 && expanded[0] == args[i] {
+		return args
+	}
+	return append(append(append([]string{}, args[:i]...), expanded...), args[i+1:]...)
+}
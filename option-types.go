@@ -1,9 +1,14 @@
 package cmdline
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type OptionTypes interface {
@@ -26,17 +31,35 @@ const (
 	argTypeFloat64
 	argTypeString
 	argTypePath
+	argTypeDuration
+	argTypeFile
+	argTypeUint
+	argTypeInt64
+	argTypeSize
+	argTypeExistingFile
+	argTypeExistingDir
+	argTypeNewPath
+	argTypeCount
+	argTypeSecret
+	argTypeUUID
+	argTypeSemVer
 )
 
+// the default limit on the contents read for a file type value
+const DefaultMaxFileSize = 10 * 1024 * 1024
+
 type DefaultOptionTypes struct {
+	// the largest file, in bytes, that a file type value will read; zero means DefaultMaxFileSize
+	MaxFileSize int64
 }
 
-// Returns the OptionTypes interface for bool, int, float64, string and path. The lastIndex
+// Returns the OptionTypes interface for bool, int, float64, string, path, duration, file, uint, int64,
+// size, existingfile, existingdir, newpath, count, secret, uuid and semver. The lastIndex
 // helps the caller know what the type index range is (0..lastIndex), to extend with
 // custom types in a wrapper interface.
 func NewDefaultOptionTypes() (dot *DefaultOptionTypes, lastIndex int) {
-	dot = &DefaultOptionTypes{}
-	lastIndex = int(argTypePath) + 1
+	dot = &DefaultOptionTypes{MaxFileSize: DefaultMaxFileSize}
+	lastIndex = int(argTypeSemVer) + 1
 	return
 }
 
@@ -52,6 +75,30 @@ func (dot *DefaultOptionTypes) StringToAttributes(typeName string, spec string)
 		return &OptionTypeAttributes{Index: int(argTypeString), DefaultValue: ""}
 	case "path":
 		return &OptionTypeAttributes{Index: int(argTypePath), DefaultValue: ""}
+	case "duration":
+		return &OptionTypeAttributes{Index: int(argTypeDuration), DefaultValue: time.Duration(0)}
+	case "file":
+		return &OptionTypeAttributes{Index: int(argTypeFile), DefaultValue: []byte(nil)}
+	case "uint":
+		return &OptionTypeAttributes{Index: int(argTypeUint), DefaultValue: uint(0)}
+	case "int64":
+		return &OptionTypeAttributes{Index: int(argTypeInt64), DefaultValue: int64(0)}
+	case "size":
+		return &OptionTypeAttributes{Index: int(argTypeSize), DefaultValue: int64(0)}
+	case "existingfile":
+		return &OptionTypeAttributes{Index: int(argTypeExistingFile), DefaultValue: ""}
+	case "existingdir":
+		return &OptionTypeAttributes{Index: int(argTypeExistingDir), DefaultValue: ""}
+	case "newpath":
+		return &OptionTypeAttributes{Index: int(argTypeNewPath), DefaultValue: ""}
+	case "count":
+		return &OptionTypeAttributes{Index: int(argTypeCount), DefaultValue: int(0)}
+	case "secret":
+		return &OptionTypeAttributes{Index: int(argTypeSecret), DefaultValue: ""}
+	case "uuid":
+		return &OptionTypeAttributes{Index: int(argTypeUUID), DefaultValue: ""}
+	case "semver":
+		return &OptionTypeAttributes{Index: int(argTypeSemVer), DefaultValue: SemVer{}}
 	default:
 		panic(fmt.Errorf("%svalid arg type %s in %s", basePanic, typeName, spec))
 	}
@@ -65,19 +112,45 @@ func (dot *DefaultOptionTypes) MakeValue(typeIndex int, inputValue string) (any,
 	case argTypeBool:
 		result, err = strconv.ParseBool(inputValue)
 
-	case argTypeInt:
+	case argTypeInt, argTypeCount:
 		result, err = strconv.Atoi(inputValue)
 
 	case argTypeFloat64:
 		result, err = strconv.ParseFloat(inputValue, 64)
 
-	case argTypeString:
+	case argTypeString, argTypeSecret:
 		result = inputValue
 		err = nil
 
 	case argTypePath:
 		result, err = filepath.Abs(inputValue)
 
+	case argTypeDuration:
+		result, err = time.ParseDuration(inputValue)
+
+	case argTypeFile:
+		result, err = dot.readFile(inputValue)
+
+	case argTypeUint:
+		var n uint64
+		n, err = strconv.ParseUint(inputValue, 10, strconv.IntSize)
+		result = uint(n)
+
+	case argTypeInt64:
+		result, err = strconv.ParseInt(inputValue, 10, 64)
+
+	case argTypeSize:
+		result, err = ParseSize(inputValue)
+
+	case argTypeExistingFile, argTypeExistingDir, argTypeNewPath:
+		result, err = checkPath(argType(typeIndex), inputValue)
+
+	case argTypeUUID:
+		result, err = parseUUID(inputValue)
+
+	case argTypeSemVer:
+		result, err = ParseSemVer(inputValue)
+
 	default:
 		panic(fmt.Errorf("invalid arg type index"))
 	}
@@ -90,18 +163,36 @@ func (dot *DefaultOptionTypes) NewList(typeIndex int) (any, error) {
 	case argTypeBool:
 		return []bool{}, nil
 
-	case argTypeInt:
+	case argTypeInt, argTypeCount:
 		return []int{}, nil
 
 	case argTypeFloat64:
 		return []float64{}, nil
 
-	case argTypeString:
+	case argTypeString, argTypeSecret:
 		return []string{}, nil
 
 	case argTypePath:
 		return []string{}, nil
 
+	case argTypeDuration:
+		return []time.Duration{}, nil
+
+	case argTypeFile:
+		return [][]byte{}, nil
+
+	case argTypeUint:
+		return []uint{}, nil
+
+	case argTypeInt64, argTypeSize:
+		return []int64{}, nil
+
+	case argTypeExistingFile, argTypeExistingDir, argTypeNewPath, argTypeUUID:
+		return []string{}, nil
+
+	case argTypeSemVer:
+		return []SemVer{}, nil
+
 	default:
 		panic(fmt.Errorf("invalid arg type index"))
 	}
@@ -117,18 +208,106 @@ func (dot *DefaultOptionTypes) AppendList(typeIndex int, list any, inputValue st
 	case argTypeBool:
 		list = append(list.([]bool), value.(bool))
 
-	case argTypeInt:
+	case argTypeInt, argTypeCount:
 		list = append(list.([]int), value.(int))
 
 	case argTypeFloat64:
 		list = append(list.([]float64), value.(float64))
 
-	case argTypeString:
+	case argTypeString, argTypeSecret:
 		list = append(list.([]string), value.(string))
 
 	case argTypePath:
 		list = append(list.([]string), value.(string))
+
+	case argTypeDuration:
+		list = append(list.([]time.Duration), value.(time.Duration))
+
+	case argTypeFile:
+		list = append(list.([][]byte), value.([]byte))
+
+	case argTypeUint:
+		list = append(list.([]uint), value.(uint))
+
+	case argTypeInt64, argTypeSize:
+		list = append(list.([]int64), value.(int64))
+
+	case argTypeExistingFile, argTypeExistingDir, argTypeNewPath, argTypeUUID:
+		list = append(list.([]string), value.(string))
+
+	case argTypeSemVer:
+		list = append(list.([]SemVer), value.(SemVer))
 	}
 
 	return list, nil
 }
+
+// reads the contents of a file type value; a leading @ is allowed, as in --body @file
+func (dot *DefaultOptionTypes) readFile(inputValue string) ([]byte, error) {
+	path := strings.TrimPrefix(inputValue, "@")
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	limit := dot.MaxFileSize
+	if limit <= 0 {
+		limit = DefaultMaxFileSize
+	}
+
+	content, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(content)) > limit {
+		return nil, newKindError(ErrInvalidValue, inputValue, "", "File %s exceeds the %d byte size limit", path, limit)
+	}
+
+	return content, nil
+}
+
+// converts a path to its absolute form and checks the filesystem for the path type
+func checkPath(pathType argType, inputValue string) (string, error) {
+	path, err := filepath.Abs(inputValue)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	exists := err == nil
+
+	switch pathType {
+	case argTypeExistingFile:
+		if !exists {
+			return "", newKindError(ErrInvalidValue, inputValue, "", "File %s does not exist", inputValue)
+		}
+		if info.IsDir() {
+			return "", newKindError(ErrInvalidValue, inputValue, "", "%s is a directory, not a file", inputValue)
+		}
+
+	case argTypeExistingDir:
+		if !exists {
+			return "", newKindError(ErrInvalidValue, inputValue, "", "Directory %s does not exist", inputValue)
+		}
+		if !info.IsDir() {
+			return "", newKindError(ErrInvalidValue, inputValue, "", "%s is not a directory", inputValue)
+		}
+
+	case argTypeNewPath:
+		if exists {
+			return "", newKindError(ErrInvalidValue, inputValue, "", "%s already exists", inputValue)
+		}
+		parent, err := os.Stat(filepath.Dir(path))
+		if err != nil || !parent.IsDir() {
+			return "", newKindError(ErrInvalidValue, inputValue, "", "The directory for %s does not exist", inputValue)
+		}
+	}
+
+	return path, nil
+}
@@ -1,5 +1,7 @@
 package cmdline
 
+import "github.com/jimsnab/go-simpleutils"
+
 type orderedCommandLineMap struct {
 	values map[string]*command
 	order  []string
@@ -18,35 +20,81 @@ func (m *orderedCommandLineMap) add(name string, cmd *command) {
 }
 
 type orderedGlobalOptionMap struct {
-	values map[string]*globalOption
-	order  []string
+	values  map[string]*globalOption
+	order   []string
+	aliases map[string]string
 }
 
 func newOrderedGlobalOptionMap() *orderedGlobalOptionMap {
 	return &orderedGlobalOptionMap{
-		values: make(map[string]*globalOption),
-		order:  make([]string, 0),
+		values:  make(map[string]*globalOption),
+		order:   make([]string, 0),
+		aliases: make(map[string]string),
 	}
 }
 
 func (m *orderedGlobalOptionMap) add(name string, opt *globalOption) {
 	m.values[name] = opt
 	m.order = append(m.order, name)
+	for _, alias := range opt.argSpec.Aliases {
+		m.aliases[alias] = name
+	}
+	if opt.argSpec.Negation != "" {
+		m.aliases[opt.argSpec.Negation] = name
+	}
+}
+
+// finds a global option by its name or one of its aliases
+func (m *orderedGlobalOptionMap) lookup(name string) (*globalOption, bool) {
+	key, isAlias := m.aliases[name]
+	if isAlias {
+		name = key
+	}
+	opt, exists := m.values[name]
+	return opt, exists
+}
+
+// provides the names and aliases of the options
+func (m *orderedGlobalOptionMap) names() []string {
+	return append(append([]string{}, m.order...), simpleutils.SortedKeys(m.aliases)...)
 }
 
 type orderedArgSpecMap struct {
-	values map[string]*argSpec
-	order  []string
+	values  map[string]*argSpec
+	order   []string
+	aliases map[string]string
 }
 
 func newOrderedArgSpecMap() *orderedArgSpecMap {
 	return &orderedArgSpecMap{
-		values: make(map[string]*argSpec),
-		order:  make([]string, 0),
+		values:  make(map[string]*argSpec),
+		order:   make([]string, 0),
+		aliases: make(map[string]string),
 	}
 }
 
 func (m *orderedArgSpecMap) add(name string, as *argSpec) {
 	m.values[name] = as
 	m.order = append(m.order, name)
+	for _, alias := range as.Aliases {
+		m.aliases[alias] = name
+	}
+	if as.Negation != "" {
+		m.aliases[as.Negation] = name
+	}
+}
+
+// finds an option by its name or one of its aliases
+func (m *orderedArgSpecMap) lookup(name string) (*argSpec, bool) {
+	key, isAlias := m.aliases[name]
+	if isAlias {
+		name = key
+	}
+	as, exists := m.values[name]
+	return as, exists
+}
+
+// provides the names and aliases of the options
+func (m *orderedArgSpecMap) names() []string {
+	return append(append([]string{}, m.order...), simpleutils.SortedKeys(m.aliases)...)
 }
@@ -0,0 +1,248 @@
+package cmdline
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// the value name of the --output global option
+const outputFormatValue = "outputFormat"
+
+// serializes a result for Emit
+type OutputFormatter func(w io.Writer, v any) error
+
+// the formats Emit provides without a formatter
+var builtInOutputFormats = []string{"json", "table", "text"}
+
+// sets the formatter Emit uses for a format, such as "yaml"; it replaces a
+// built-in format of the same name
+func (cl *CommandLine) SetOutputFormatter(format string, formatter OutputFormatter) {
+	if len(format) == 0 || formatter == nil {
+		panic(fmt.Errorf("argument error: an output formatter requires a format and a function"))
+	}
+
+	if cl.outputFormatters == nil {
+		cl.outputFormatters = map[string]OutputFormatter{}
+	}
+	cl.outputFormatters[format] = formatter
+}
+
+// provides a formatter that executes a text/template with the value, for
+// SetOutputFormatter, such as "{{range .}}{{.Name}}\n{{end}}"
+func NewTemplateFormatter(text string) OutputFormatter {
+	tmpl, err := template.New("output").Parse(text)
+	if err != nil {
+		panic(fmt.Errorf("argument error: output template: %v", err))
+	}
+
+	return func(w io.Writer, v any) error {
+		return tmpl.Execute(w, v)
+	}
+}
+
+// registers the -o|--output global option, which selects one of the formats for
+// Emit; the first format is the default, and each must be built in (json, table
+// or text) or have a formatter set by SetOutputFormatter
+func (cl *CommandLine) EnableOutputFormat(formats ...string) {
+	if len(formats) == 0 {
+		formats = builtInOutputFormats
+	}
+
+	for _, format := range formats {
+		if !cl.hasOutputFormatter(format) {
+			panic(fmt.Errorf("argument error: no formatter for the output format %q", format))
+		}
+	}
+	cl.outputFormats = append([]string{}, formats...)
+	cl.outputFormat.Store(formats[0])
+
+	_, exists := cl.globalOptions.values["-o"]
+	if !exists {
+		cl.RegisterGlobalOption(
+			func(values Values) error { return nil },
+			fmt.Sprintf("-o|--output <string-%s>?Formats the output as %s; %s by default", outputFormatValue, strings.Join(formats, ", "), formats[0]),
+		)
+		cl.AddValidator(outputFormatValue, cl.validateOutputFormat)
+	}
+}
+
+// provides the output format of the most recent command line, or "" if
+// EnableOutputFormat wasn't called
+func (cl *CommandLine) OutputFormat() string {
+	format, _ := cl.outputFormat.Load().(string)
+	return format
+}
+
+// prints v in the output format with the command's data; json is indented, table
+// prints a slice of structs or maps as rows, or a struct or map as name and value
+// rows, and text prints each element of a slice on its own line
+func (cl *CommandLine) Emit(v any) error {
+	format := cl.OutputFormat()
+	if format == "" {
+		format = "text"
+	}
+
+	var lines []string
+	if formatter := cl.outputFormatters[format]; formatter != nil {
+		var buf bytes.Buffer
+		if err := formatter(&buf, v); err != nil {
+			return err
+		}
+		lines = strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	} else {
+		var err error
+		if lines, err = cl.builtInOutput(format, v); err != nil {
+			return err
+		}
+	}
+
+	for _, line := range lines {
+		cl.Dataln(line)
+	}
+	return nil
+}
+
+func (cl *CommandLine) hasOutputFormatter(format string) bool {
+	if cl.outputFormatters[format] != nil {
+		return true
+	}
+	for _, builtIn := range builtInOutputFormats {
+		if format == builtIn {
+			return true
+		}
+	}
+	return false
+}
+
+func (cl *CommandLine) validateOutputFormat(value any) error {
+	format, _ := value.(string)
+	for _, allowed := range cl.outputFormats {
+		if format == allowed {
+			return nil
+		}
+	}
+	return newKindError(ErrInvalidValue, format, "", "Output format must be one of: %s", strings.Join(cl.outputFormats, ", "))
+}
+
+// sets the output format from the global options given, before their handlers
+// run; the names and values are those of each option given
+func (cl *CommandLine) setOutputFormat(names []string, values []Values) {
+	if len(cl.outputFormats) == 0 {
+		return
+	}
+
+	format := cl.outputFormats[0]
+	for i, name := range names {
+		if name == "-o" {
+			format, _ = values[i][outputFormatValue].(string)
+		}
+	}
+	cl.outputFormat.Store(format)
+}
+
+func (cl *CommandLine) builtInOutput(format string, v any) ([]string, error) {
+	switch format {
+	case "json":
+		text, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return strings.Split(string(text), "\n"), nil
+
+	case "table":
+		headers, rows := tableOf(reflect.ValueOf(v))
+		return cl.tableLines(headers, rows), nil
+
+	default:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+			lines := make([]string, 0, rv.Len())
+			for i := 0; i < rv.Len(); i++ {
+				lines = append(lines, fmt.Sprint(rv.Index(i).Interface()))
+			}
+			return lines, nil
+		}
+		return strings.Split(fmt.Sprint(v), "\n"), nil
+	}
+}
+
+// lays out a value as table rows: a slice of structs or maps gives a row per
+// element, a struct or map gives a row per field, and anything else is one cell
+func tableOf(rv reflect.Value) (headers []string, rows [][]string) {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			names, cells := fieldsOf(rv.Index(i))
+			if headers == nil {
+				headers = names
+			}
+			rows = append(rows, cells)
+		}
+		return headers, rows
+
+	case reflect.Struct, reflect.Map:
+		names, cells := fieldsOf(rv)
+		for i := range names {
+			rows = append(rows, []string{names[i], cells[i]})
+		}
+		return []string{"Name", "Value"}, rows
+
+	default:
+		return nil, [][]string{{fmt.Sprint(rv.Interface())}}
+	}
+}
+
+// provides the names and texts of a struct's exported fields, named by their json
+// tags when they have them, or of a map's entries in key order
+func fieldsOf(rv reflect.Value) (names []string, cells []string) {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		rt := rv.Type()
+		for i := 0; i < rt.NumField(); i++ {
+			field := rt.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := field.Name
+			if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+			names = append(names, name)
+			cells = append(cells, fmt.Sprint(rv.Field(i).Interface()))
+		}
+
+	case reflect.Map:
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface()) })
+		for _, key := range keys {
+			names = append(names, fmt.Sprint(key.Interface()))
+			cells = append(cells, fmt.Sprint(rv.MapIndex(key).Interface()))
+		}
+
+	default:
+		cells = append(cells, fmt.Sprint(rv.Interface()))
+	}
+	return names, cells
+}
@@ -0,0 +1,46 @@
+package cmdline
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// a panic in a handler, recovered and returned by Process
+type PanicError struct {
+	// the value passed to panic
+	Value any
+	// the stack of the panicking goroutine
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("handler panic: %v", e.Value)
+}
+
+// provides the panic value when it is an error, so errors.Is and errors.As see it
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// makes Process recover a panic in a command or global option handler, including its
+// middleware, returning a *PanicError instead of crashing
+func (cl *CommandLine) SetRecoverPanics(enable bool) {
+	cl.recoverPanics = enable
+}
+
+func (cl *CommandLine) recoverHandler(handler CommandHandlerCtx) CommandHandlerCtx {
+	if !cl.recoverPanics {
+		return handler
+	}
+
+	return func(ctx context.Context, values Values) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = &PanicError{Value: r, Stack: debug.Stack()}
+			}
+		}()
+		return handler(ctx, values)
+	}
+}
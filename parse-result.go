@@ -0,0 +1,45 @@
+package cmdline
+
+import "context"
+
+// the command line resolved by ProcessEx, with the command's values and each global
+// option's values kept apart
+type ParseResult struct {
+	// the command key, such as "users create", or "~" for the unnamed command
+	Command string
+	// the values given to the command handler, without the processing context
+	CommandValues Values
+	// the values of each global option specified, keyed by the option; when an option
+	// is repeated, its last occurrence provides the values
+	GlobalValues map[string]Values
+	// a copy of the args processed
+	RawArgs []string
+	// the result returned by a command result handler, or nil
+	Result any
+}
+
+// processes the args like Process, and also provides the resolved command line; the
+// result is nil if the command line was invalid, and is set even if a handler fails
+func (cl *CommandLine) ProcessEx(args []string) (*ParseResult, error) {
+	result := &ParseResult{}
+	err := cl.localizeError(cl.processStream(context.Background(), nil, args, nil, result))
+	if result.RawArgs == nil {
+		return nil, err
+	}
+	return result, err
+}
+
+// sets the result from the resolved command line; a nil result is ignored
+func (pr *ParseResult) fill(args []string, globalArgs *parsedGlobalArgs, cmdToRun *commandToRun) {
+	if pr == nil {
+		return
+	}
+
+	pr.Command = cmdToRun.cmd.PrimaryArgSpec.Key
+	pr.CommandValues = withoutContext(cmdToRun.values)
+	pr.GlobalValues = make(map[string]Values, len(globalArgs.globalOptionsToRun))
+	for _, gotr := range globalArgs.globalOptionsToRun {
+		pr.GlobalValues[gotr.Option.argSpec.Key] = gotr.Values
+	}
+	pr.RawArgs = append([]string{}, args...)
+}
@@ -0,0 +1,124 @@
+package cmdline
+
+import "context"
+
+// a command line resolved by Parse, ready to be executed
+type ParsedCommand struct {
+	// the command key, such as "users create", or "~" for the unnamed command
+	Name string
+	// the command values, as a handler would receive them
+	Values Values
+	// the global options specified, in the order their handlers run
+	GlobalOptions []ParsedGlobalOption
+
+	cl      *CommandLine
+	cmd     *command
+	sources map[string]ValueSource
+}
+
+type ParsedGlobalOption struct {
+	Name   string `json:"name"`
+	Values Values `json:"values,omitempty"`
+
+	option  *globalOption
+	sources map[string]ValueSource
+}
+
+// resolves the command and its values without calling any handlers; a command
+// chain isn't split, since only Process runs a chain. Like Process, it prints the
+// help for an auto help request and returns ErrHelpShown, and it prints the output
+// of the completion command and returns ErrCompletionShown.
+func (cl *CommandLine) Parse(args []string) (*ParsedCommand, error) {
+	return cl.ParseWithContext(nil, args)
+}
+
+// resolves the command and its values like Parse, putting the processing context
+// in Values[""]
+func (cl *CommandLine) ParseWithContext(processingContext any, args []string) (*ParsedCommand, error) {
+	globalArgs, err := cl.parseGlobalArgs(args, nil)
+	if err != nil {
+		return nil, cl.localizeError(err)
+	}
+
+	cmdToRun, err := cl.parseCommandArgs(processingContext, globalArgs)
+	if err != nil {
+		return nil, cl.localizeError(err)
+	}
+
+	pc := ParsedCommand{
+		Name:    cmdToRun.cmd.PrimaryArgSpec.Key,
+		Values:  cmdToRun.values,
+		cl:      cl,
+		cmd:     cmdToRun.cmd,
+		sources: cmdToRun.sources,
+	}
+
+	for _, gotr := range globalArgs.globalOptionsToRun {
+		pc.GlobalOptions = append(pc.GlobalOptions, ParsedGlobalOption{
+			Name:    gotr.Option.argSpec.Key,
+			Values:  gotr.Values,
+			option:  gotr.Option,
+			sources: gotr.Sources,
+		})
+	}
+	return &pc, nil
+}
+
+// calls the global option handlers and then the command handler, as Process would
+func (pc *ParsedCommand) Execute(ctx context.Context) error {
+	return pc.cl.localizeError(pc.execute(ctx))
+}
+
+func (pc *ParsedCommand) execute(ctx context.Context) error {
+	names := make([]string, 0, len(pc.GlobalOptions))
+	values := make([]Values, 0, len(pc.GlobalOptions))
+	for _, parsedOpt := range pc.GlobalOptions {
+		names = append(names, parsedOpt.Name)
+		values = append(values, parsedOpt.Values)
+	}
+	pc.cl.setVerbosity(names)
+	pc.cl.setOutputFormat(names, values)
+
+	if pc.cl.isDryRun(names) {
+		return pc.cl.printDryRun(pc.invocation())
+	}
+
+	for _, parsedOpt := range pc.GlobalOptions {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := pc.cl.wrapGlobalHandler(parsedOpt.option.Handler)(ctx, parsedOpt.Values)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := pc.cl.confirm(pc.cmd, pc.Values); err != nil {
+		return err
+	}
+
+	pc.cl.recordResult(nil)
+	return pc.cl.wrapHandler(pc.cmd.Handler)(ctx, pc.Values)
+}
+
+// makes the invocation that Process would record for the parsed command line
+func (pc *ParsedCommand) invocation() *Invocation {
+	inv := Invocation{
+		Command: pc.Name,
+		Values:  maskCommandSecrets(pc.cmd, copyValues(pc.Values)),
+		sources: pc.sources,
+	}
+	for _, parsedOpt := range pc.GlobalOptions {
+		inv.GlobalOptions = append(inv.GlobalOptions, ParsedGlobalOption{
+			Name:    parsedOpt.Name,
+			Values:  maskSecretValues(copyValues(parsedOpt.Values), parsedOpt.option.argSpec),
+			sources: parsedOpt.sources,
+		})
+	}
+	return &inv
+}
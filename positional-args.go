@@ -0,0 +1,53 @@
+package cmdline
+
+import (
+	"fmt"
+	"strings"
+)
+
+// gives a positional value of a command its own help text, such as "src" in
+// "copy <path-src>..."; the command's help lists the values under "Arguments:"
+func (cl *CommandLine) SetArgumentHelp(cmdName string, valueName string, helpText string) {
+	cmd := cl.lookupCommand(cmdName)
+
+	for _, valueSpec := range cmd.PrimaryArgSpec.ValueSpecs {
+		if valueSpec.OptionName == valueName {
+			valueSpec.HelpText = helpText
+			return
+		}
+	}
+
+	panic(fmt.Errorf("argument error: value \"%s\" is not a positional argument of command \"%s\"", valueName, cmdName))
+}
+
+func hasArgumentHelp(cmd *command) bool {
+	for _, valueSpec := range cmd.PrimaryArgSpec.ValueSpecs {
+		if len(valueSpec.HelpText) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// the form of a positional value in the arguments section, such as [<dst>] or <src>...
+func (avs *argValueSpec) argumentText() string {
+	text := "<" + avs.OptionName + ">"
+	if avs.Multi {
+		text += "..."
+	}
+	if avs.Optional {
+		text = "[" + text + "]"
+	}
+	return text
+}
+
+func (hp *helpPrinter) helpPrintArguments(indent int, cmd *command) {
+	if !hasArgumentHelp(cmd) {
+		return
+	}
+
+	hp.helpPrintln(strings.Repeat("  ", indent) + hp.tr("Arguments:"))
+	for _, valueSpec := range cmd.PrimaryArgSpec.ValueSpecs {
+		hp.helpPrintCols(indent+1, valueSpec.argumentText(), hp.helpText(valueSpec.HelpText))
+	}
+}
@@ -0,0 +1,109 @@
+package cmdline
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jimsnab/go-toolprinter"
+)
+
+// the narrowest a progress bar is drawn
+const minBarWidth = 10
+
+// a progress bar drawn on the printer's status line
+type Progress struct {
+	mu        sync.Mutex
+	prn       toolprinter.ToolPrinter
+	label     string
+	total     int
+	done      int
+	lineWidth int
+	start     time.Time
+}
+
+// starts a progress bar for total units of work, drawn on the status line of the
+// help printer; the bar fits the terminal width and shows the percent done and an ETA
+func (cl *CommandLine) StartProgress(label string, total int) *Progress {
+	p := &Progress{
+		prn:       cl.printer(),
+		label:     label,
+		total:     total,
+		lineWidth: cl.helpLineWidth(),
+		start:     time.Now(),
+	}
+	p.draw()
+	return p
+}
+
+// records n more units of work as done, and redraws the bar; a negative n takes
+// work back, but not below none done
+func (p *Progress) Advance(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done += n
+	if p.done > p.total {
+		p.done = p.total
+	}
+	if p.done < 0 {
+		p.done = 0
+	}
+	p.draw()
+}
+
+// removes the bar from the status line
+func (p *Progress) Finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.prn.Clear()
+}
+
+// provides the bar's text, such as "copying [#####     ]  50% ETA 0:05"
+func (p *Progress) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.text()
+}
+
+func (p *Progress) text() string {
+	percent := 100
+	if p.total > 0 {
+		percent = p.done * 100 / p.total
+	}
+
+	suffix := fmt.Sprintf(" %3d%%", percent)
+	if p.done > 0 && p.done < p.total {
+		elapsed := time.Since(p.start)
+		remaining := time.Duration(int64(elapsed) / int64(p.done) * int64(p.total-p.done))
+		suffix += " ETA " + etaText(remaining)
+	}
+
+	prefix := ""
+	if p.label != "" {
+		prefix = p.label + " "
+	}
+
+	width := p.lineWidth - displayWidth(prefix) - displayWidth(suffix) - 2
+	if width < minBarWidth {
+		width = minBarWidth
+	}
+	filled := width * percent / 100
+
+	return prefix + "[" + strings.Repeat("#", filled) + strings.Repeat(" ", width-filled) + "]" + suffix
+}
+
+func (p *Progress) draw() {
+	p.prn.Status(p.text())
+}
+
+// formats a duration as m:ss, or h:mm:ss when it's an hour or more
+func etaText(d time.Duration) string {
+	seconds := int(d.Round(time.Second) / time.Second)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
@@ -0,0 +1,119 @@
+package cmdline
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// returned by Ask when stdin ends without an answer
+var ErrNoInput = errors.New("no input")
+
+// asks the user questions; the confirmations and secret prompts of Process go
+// through it, and tests can provide their own answers
+type Prompter interface {
+	ConfirmProvider
+	// asks for a line of text
+	Ask(prompt string) (string, error)
+	// asks for a line of text without echoing it
+	AskSecret(prompt string) (string, error)
+}
+
+// asks on stderr and reads the answers from stdin; when stdin isn't a terminal,
+// such as when answers are piped in, secrets are read as plain lines
+type stdioPrompter struct {
+	mu     sync.Mutex
+	reader *bufio.Reader
+}
+
+// the default prompter, shared since stdin is
+var stdioPrompt = &stdioPrompter{}
+
+func (p *stdioPrompter) Ask(prompt string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fmt.Fprint(os.Stderr, prompt)
+	return p.readLine()
+}
+
+func (p *stdioPrompter) AskSecret(prompt string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fmt.Fprint(os.Stderr, prompt)
+
+	fd := int(os.Stdin.Fd())
+	if !xterm.IsTerminal(fd) {
+		return p.readLine()
+	}
+
+	input, err := xterm.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(input), nil
+}
+
+// asks with a [y/N] suffix; only y or yes confirms
+func (p *stdioPrompter) Confirm(prompt string) (bool, error) {
+	answer, err := p.Ask(prompt + " [y/N] ")
+	if err != nil && err != ErrNoInput {
+		return false, err
+	}
+
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes", nil
+}
+
+// reads a line from stdin, without its surrounding spaces
+func (p *stdioPrompter) readLine() (string, error) {
+	if p.reader == nil {
+		p.reader = bufio.NewReader(os.Stdin)
+	}
+
+	line, err := p.reader.ReadString('\n')
+	if err == io.EOF {
+		if len(line) == 0 {
+			return "", ErrNoInput
+		}
+	} else if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// replaces the stdin prompts, such as for tests; nil restores them
+func (cl *CommandLine) SetPrompter(prompter Prompter) {
+	cl.prompter = prompter
+}
+
+func (cl *CommandLine) prompts() Prompter {
+	if cl.prompter != nil {
+		return cl.prompter
+	}
+	return stdioPrompt
+}
+
+// asks the user for a line of text
+func (cl *CommandLine) Ask(prompt string) (string, error) {
+	return cl.prompts().Ask(prompt)
+}
+
+// asks the user for a line of text without echoing it, when stdin is a terminal
+func (cl *CommandLine) AskSecret(prompt string) (string, error) {
+	return cl.prompts().AskSecret(prompt)
+}
+
+// asks the user a yes or no question; the answer is no unless the user says yes
+func (cl *CommandLine) Confirm(prompt string) (bool, error) {
+	if cl.confirmProvider != nil {
+		return cl.confirmProvider.Confirm(prompt)
+	}
+	return cl.prompts().Confirm(prompt)
+}
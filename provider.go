@@ -0,0 +1,35 @@
+package cmdline
+
+import "fmt"
+
+// a command for a CommandProvider to register; set Handler or HandlerCtx
+type CommandSpec struct {
+	Handler    CommandHandler
+	HandlerCtx CommandHandlerCtx
+	// the primary spec followed by the option specs, as given to RegisterCommand
+	Specs []string
+}
+
+// supplies a set of commands, so a package can register its own commands
+type CommandProvider interface {
+	Commands() []CommandSpec
+}
+
+// registers every command supplied by the provider, in order
+func (cl *CommandLine) RegisterProvider(provider CommandProvider) {
+	if provider == nil {
+		panic(fmt.Errorf("argument error: provider is required"))
+	}
+
+	for _, spec := range provider.Commands() {
+		if (spec.Handler == nil) == (spec.HandlerCtx == nil) {
+			panic(fmt.Errorf("argument error: command spec %v requires one of Handler or HandlerCtx", spec.Specs))
+		}
+
+		if spec.Handler != nil {
+			cl.RegisterCommand(spec.Handler, spec.Specs...)
+		} else {
+			cl.RegisterCommandCtx(spec.HandlerCtx, spec.Specs...)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package cmdline
+
+import (
+	"time"
+
+	"github.com/jimsnab/go-toolprinter"
+)
+
+// suppresses the status, counter, progress and spinner output of the help printer,
+// but not its printed lines, for the logs of CI runs and cron jobs
+func (cl *CommandLine) SetQuiet(quiet bool) {
+	cl.quiet = quiet
+}
+
+// a printer that prints the lines of another printer, and drops its status output
+type quietPrinter struct {
+	toolprinter.ToolPrinter
+}
+
+func (qp quietPrinter) Status(text ...interface{})                                           {}
+func (qp quietPrinter) Statusf(format string, args ...interface{})                           {}
+func (qp quietPrinter) Clear()                                                               {}
+func (qp quietPrinter) ChattyStatus(text ...interface{})                                     {}
+func (qp quietPrinter) ChattyStatusf(format string, args ...interface{})                     {}
+func (qp quietPrinter) SetCounterMax(max int, text ...interface{})                           {}
+func (qp quietPrinter) UpdateCountStatus(extraStatusText ...interface{})                     {}
+func (qp quietPrinter) Count()                                                               {}
+func (qp quietPrinter) DateRangeStatus(from time.Time, to time.Time, purpose ...interface{}) {}
+func (qp quietPrinter) StatusLine(id string, text string)                                    {}
@@ -0,0 +1,65 @@
+package cmdline
+
+import (
+	"strconv"
+	"strings"
+)
+
+// parses the {min..max} limits of a repeated option at the cursor
+func (as *argSpec) parseRepeatCount(s *specScanner) {
+	start := s.pos
+	closeBrace := s.index("}", s.pos)
+	if closeBrace < 0 {
+		panic(s.errorAt(s.end, "'}'"))
+	}
+
+	minText, maxText, found := strings.Cut(s.spec[start+1:closeBrace], "..")
+	if !found || (minText == "" && maxText == "") {
+		panic(s.errorAt(start+1, "repeat count min..max"))
+	}
+
+	parseBound := func(text string, pos int) int {
+		if text == "" {
+			return 0
+		}
+		bound, err := strconv.Atoi(text)
+		if err != nil || bound < 1 {
+			panic(s.errorAt(pos, "positive repeat count"))
+		}
+		return bound
+	}
+
+	as.MinRepeat = parseBound(minText, start+1)
+	as.MaxRepeat = parseBound(maxText, start+1+len(minText)+2)
+	if as.MaxRepeat > 0 && as.MinRepeat > as.MaxRepeat {
+		panic(s.errorAt(start+1, "repeat count min at or below max"))
+	}
+
+	s.pos = closeBrace + 1
+}
+
+func (as *argSpec) repeatCountText() string {
+	if as.MinRepeat == 0 && as.MaxRepeat == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("{")
+	if as.MinRepeat > 0 {
+		sb.WriteString(strconv.Itoa(as.MinRepeat))
+	}
+	sb.WriteString("..")
+	if as.MaxRepeat > 0 {
+		sb.WriteString(strconv.Itoa(as.MaxRepeat))
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// checks the number of times a repeated option was given against its limits
+func (as *argSpec) validateRepeatCount(count int) error {
+	if count < as.MinRepeat || (as.MaxRepeat > 0 && count > as.MaxRepeat) {
+		return newKindError(ErrRepeatCount, as.Key, as.String(), "Argument %s is given %d time(s); expected %s", as.Key, count, as.repeatCountText())
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package cmdline
+
+import (
+	"errors"
+	"os"
+)
+
+// the exit codes used by Run
+const (
+	ExitOK      = 0
+	ExitFailure = 1
+	ExitUsage   = 2
+)
+
+var osExit = os.Exit
+
+// maps an error from Process to an exit code: ExitOK for none or for shown help,
+// version and completion, ExitUsage for a command line error, and ExitFailure otherwise
+func ExitCode(err error) int {
+	if err == nil || errors.Is(err, ErrHelpShown) || errors.Is(err, ErrVersionShown) || errors.Is(err, ErrCompletionShown) {
+		return ExitOK
+	}
+
+	var cle *CommandLineError
+	if errors.As(err, &cle) && !errors.Is(err, ErrNotConfirmed) {
+		return ExitUsage
+	}
+	return ExitFailure
+}
+
+// processes the program's args, prints help for an error, and exits with the
+// error's ExitCode
+func Run(cl *CommandLine, appName string) {
+	osExit(runArgs(cl, appName, os.Args[1:]))
+}
+
+func runArgs(cl *CommandLine, appName string, args []string) int {
+	err := cl.Process(args)
+	if err != nil {
+		cl.Help(err, appName, args)
+	}
+	return ExitCode(err)
+}
@@ -0,0 +1,249 @@
+package cmdline
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jimsnab/go-toolprinter"
+)
+
+// moves the cursor up a line and erases it
+const eraseLineAbove = "\x1b[1A\x1b[2K"
+
+// SafePrinter is a ToolPrinter that serializes the calls to another printer, so
+// goroutines can share it, and that draws status lines that each worker can own.
+// The package printer Prn is a SafePrinter.
+type SafePrinter struct {
+	mu    sync.Mutex
+	prn   toolprinter.ToolPrinter
+	out   io.Writer
+	ids   []string
+	lines map[string]string
+	drawn int
+	// a segmented print is in progress, so the status lines wait
+	segmented bool
+	// the status output is stopped
+	disabled bool
+}
+
+// provides a printer that is safe for concurrent use, printing with prn
+func NewSafePrinter(prn toolprinter.ToolPrinter) *SafePrinter {
+	return &SafePrinter{prn: prn, out: os.Stdout, lines: map[string]string{}}
+}
+
+// sets the status line owned by id, below the printed output; the lines are kept
+// in the order they were first set, and empty text removes the line. Status lines
+// are only drawn when stdout is a terminal.
+func (sp *SafePrinter) StatusLine(id string, text string) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	sp.eraseLines()
+
+	if text == "" {
+		delete(sp.lines, id)
+		for i, lineID := range sp.ids {
+			if lineID == id {
+				sp.ids = append(sp.ids[:i], sp.ids[i+1:]...)
+				break
+			}
+		}
+	} else {
+		if _, exists := sp.lines[id]; !exists {
+			sp.ids = append(sp.ids, id)
+		}
+		sp.lines[id] = text
+	}
+
+	sp.drawLines()
+}
+
+func (sp *SafePrinter) eraseLines() {
+	if sp.drawn > 0 {
+		io.WriteString(sp.out, strings.Repeat(eraseLineAbove, sp.drawn))
+		sp.drawn = 0
+	}
+}
+
+func (sp *SafePrinter) drawLines() {
+	if len(sp.ids) == 0 || sp.segmented || sp.disabled {
+		return
+	}
+
+	fd := int(os.Stdout.Fd())
+	if !xterm.IsTerminal(fd) {
+		return
+	}
+	width, _, err := xterm.GetSize(fd)
+	if err != nil || width <= 0 {
+		return
+	}
+
+	var sb strings.Builder
+	for _, id := range sp.ids {
+		text := sp.lines[id]
+		if displayWidth(text) >= width {
+			text = truncateToWidth(text, width-1)
+		}
+		sb.WriteString(text)
+		sb.WriteString("\n")
+	}
+	io.WriteString(sp.out, sb.String())
+	sp.drawn = len(sp.ids)
+}
+
+// runs fn with the status lines removed, so printed output goes above them
+func (sp *SafePrinter) printing(fn func()) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	sp.eraseLines()
+	fn()
+	sp.drawLines()
+}
+
+// runs fn while holding the lock, for calls that don't print lines
+func (sp *SafePrinter) locked(fn func()) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	fn()
+}
+
+// runs fn while holding the lock, unless the status output is stopped
+func (sp *SafePrinter) status(fn func()) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if !sp.disabled {
+		fn()
+	}
+}
+
+// stops the status, counter and status line output, such as for a log, until
+// Enable; printed lines are unaffected
+func (sp *SafePrinter) Disable() {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	sp.eraseLines()
+	sp.prn.Clear()
+	sp.disabled = true
+}
+
+// resumes the status output stopped by Disable
+func (sp *SafePrinter) Enable() {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	sp.disabled = false
+	sp.drawLines()
+}
+
+func (sp *SafePrinter) Status(text ...interface{}) {
+	sp.status(func() { sp.prn.Status(text...) })
+}
+
+func (sp *SafePrinter) Statusf(format string, args ...interface{}) {
+	sp.status(func() { sp.prn.Statusf(format, args...) })
+}
+
+func (sp *SafePrinter) Clear() {
+	sp.locked(func() { sp.prn.Clear() })
+}
+
+func (sp *SafePrinter) ChattyStatus(text ...interface{}) {
+	sp.status(func() { sp.prn.ChattyStatus(text...) })
+}
+
+func (sp *SafePrinter) ChattyStatusf(format string, args ...interface{}) {
+	sp.status(func() { sp.prn.ChattyStatusf(format, args...) })
+}
+
+func (sp *SafePrinter) SetCounterMax(max int, text ...interface{}) {
+	sp.locked(func() { sp.prn.SetCounterMax(max, text...) })
+}
+
+func (sp *SafePrinter) UpdateCountStatus(extraStatusText ...interface{}) {
+	sp.status(func() { sp.prn.UpdateCountStatus(extraStatusText...) })
+}
+
+func (sp *SafePrinter) Count() {
+	sp.status(func() { sp.prn.Count() })
+}
+
+func (sp *SafePrinter) PauseStatus() {
+	sp.locked(func() { sp.prn.PauseStatus() })
+}
+
+func (sp *SafePrinter) ResumeStatus() {
+	sp.locked(func() { sp.prn.ResumeStatus() })
+}
+
+func (sp *SafePrinter) DateRangeStatus(from time.Time, to time.Time, purpose ...interface{}) {
+	sp.status(func() { sp.prn.DateRangeStatus(from, to, purpose...) })
+}
+
+func (sp *SafePrinter) EnableVerbose(enabled bool) {
+	sp.locked(func() { sp.prn.EnableVerbose(enabled) })
+}
+
+func (sp *SafePrinter) Println(text ...interface{}) {
+	sp.printing(func() { sp.prn.Println(text...) })
+}
+
+func (sp *SafePrinter) Printlnf(format string, args ...interface{}) {
+	sp.printing(func() { sp.prn.Printlnf(format, args...) })
+}
+
+func (sp *SafePrinter) BeginPrint(text ...interface{}) {
+	sp.locked(func() {
+		sp.eraseLines()
+		sp.prn.BeginPrint(text...)
+		sp.segmented = true
+	})
+}
+
+func (sp *SafePrinter) ContinuePrint(text ...interface{}) {
+	sp.locked(func() { sp.prn.ContinuePrint(text...) })
+}
+
+func (sp *SafePrinter) ContinuePrintf(format string, args ...interface{}) {
+	sp.locked(func() { sp.prn.ContinuePrintf(format, args...) })
+}
+
+func (sp *SafePrinter) EndPrint(text ...interface{}) {
+	sp.locked(func() {
+		sp.prn.EndPrint(text...)
+		sp.segmented = false
+		sp.drawLines()
+	})
+}
+
+func (sp *SafePrinter) EndPrintIfStarted() {
+	sp.locked(func() {
+		sp.prn.EndPrintIfStarted()
+		sp.segmented = false
+		sp.drawLines()
+	})
+}
+
+func (sp *SafePrinter) VerbosePrintln(text ...interface{}) {
+	sp.printing(func() { sp.prn.VerbosePrintln(text...) })
+}
+
+func (sp *SafePrinter) VerbosePrintlnf(format string, args ...interface{}) {
+	sp.printing(func() { sp.prn.VerbosePrintlnf(format, args...) })
+}
+
+// sets the status line owned by id, when the help printer supports status lines as
+// SafePrinter does; otherwise, the text is shown as the printer's status
+func (cl *CommandLine) StatusLine(id string, text string) {
+	prn := cl.printer()
+	if liner, ok := prn.(interface{ StatusLine(id string, text string) }); ok {
+		liner.StatusLine(id, text)
+	} else {
+		prn.Status(text)
+	}
+}
@@ -0,0 +1,106 @@
+package cmdline
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// the value type for passwords and other secrets, which are never echoed
+const secretTypeName = "secret"
+
+// replaces a secret value wherever it would be shown
+const secretMask = "********"
+
+// makes Process prompt for a missing secret value with hidden input, when stdin
+// is a terminal
+func (cl *CommandLine) EnableSecretPrompts() {
+	cl.secretPrompts = true
+}
+
+// asks for a missing secret value, if prompting is enabled
+func (as *argSpec) promptSecret(spec *argValueSpec) (*string, error) {
+	if !as.CmdLine.secretPrompts || spec.TypeName != secretTypeName {
+		return nil, nil
+	}
+
+	// without a terminal, only a prompter set by SetPrompter is asked
+	if as.CmdLine.prompter == nil && !xterm.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, nil
+	}
+
+	text, err := as.CmdLine.AskSecret(spec.OptionName + ": ")
+	if err != nil {
+		return nil, err
+	}
+	return &text, nil
+}
+
+// removes a secret input from an error about it
+func maskSecretError(spec *argValueSpec, input string, err error) error {
+	if spec.TypeName != secretTypeName || err == nil || len(input) == 0 {
+		return err
+	}
+
+	var cle *CommandLineError
+	if errors.As(err, &cle) {
+		cle.reason = strings.ReplaceAll(cle.reason, input, secretMask)
+		cle.token = strings.ReplaceAll(cle.token, input, secretMask)
+		for i, arg := range cle.args {
+			if text, isString := arg.(string); isString {
+				cle.args[i] = strings.ReplaceAll(text, input, secretMask)
+			}
+		}
+		return err
+	}
+
+	return newKindError(ErrInvalidValue, secretMask, "", "%s", strings.ReplaceAll(err.Error(), input, secretMask))
+}
+
+// masks the secret values in a copy of values, for output such as LastInvocation
+func maskSecretValues(values Values, specs ...*argSpec) Values {
+	return maskPrefixedSecrets(values, "", specs...)
+}
+
+// masks the secret values of specs whose keys start with prefix
+func maskPrefixedSecrets(values Values, prefix string, specs ...*argSpec) Values {
+	for _, as := range specs {
+		for _, valueSpec := range as.ValueSpecs {
+			if valueSpec.TypeName != secretTypeName {
+				continue
+			}
+
+			key := prefix + valueSpec.OptionName
+			switch v := values[key].(type) {
+			case string:
+				if len(v) > 0 {
+					values[key] = secretMask
+				}
+			case []string:
+				masked := make([]string, len(v))
+				for i := range masked {
+					masked[i] = secretMask
+				}
+				values[key] = masked
+			}
+		}
+	}
+	return values
+}
+
+func maskCommandSecrets(cmd *command, values Values) Values {
+	specs := []*argSpec{cmd.PrimaryArgSpec}
+	for _, name := range cmd.OptionSpecs.order {
+		specs = append(specs, cmd.OptionSpecs.values[name])
+	}
+	maskSecretValues(values, specs...)
+
+	// global values, when they are given to the command
+	cl := cmd.PrimaryArgSpec.CmdLine
+	if cl != nil && cl.globalValues {
+		for _, name := range cl.globalOptions.order {
+			maskPrefixedSecrets(values, GlobalValuePrefix, cl.globalOptions.values[name].argSpec)
+		}
+	}
+	return values
+}
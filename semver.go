@@ -0,0 +1,150 @@
+package cmdline
+
+import (
+	"strconv"
+	"strings"
+)
+
+// a semantic version, such as 1.4.2-rc.1+build.5, as described at semver.org
+type SemVer struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease string // the dot-separated identifiers after '-', if any
+	Build      string // the dot-separated identifiers after '+', if any
+}
+
+// parses a semantic version in its canonical form, without a leading 'v'
+func ParseSemVer(input string) (SemVer, error) {
+	var sv SemVer
+	invalid := func() (SemVer, error) {
+		return SemVer{}, newKindError(ErrInvalidValue, input, "", "Invalid semantic version: %s", input)
+	}
+
+	text, build, hasBuild := strings.Cut(input, "+")
+	if hasBuild {
+		if !validIdentifiers(build, false) {
+			return invalid()
+		}
+		sv.Build = build
+	}
+
+	text, prerelease, hasPrerelease := strings.Cut(text, "-")
+	if hasPrerelease {
+		if !validIdentifiers(prerelease, true) {
+			return invalid()
+		}
+		sv.Prerelease = prerelease
+	}
+
+	parts := strings.Split(text, ".")
+	if len(parts) != 3 {
+		return invalid()
+	}
+	numbers := []*uint64{&sv.Major, &sv.Minor, &sv.Patch}
+	for i, part := range parts {
+		if !isNumericIdentifier(part) {
+			return invalid()
+		}
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return invalid()
+		}
+		*numbers[i] = n
+	}
+
+	return sv, nil
+}
+
+func (sv SemVer) String() string {
+	text := strconv.FormatUint(sv.Major, 10) + "." + strconv.FormatUint(sv.Minor, 10) + "." + strconv.FormatUint(sv.Patch, 10)
+	if sv.Prerelease != "" {
+		text += "-" + sv.Prerelease
+	}
+	if sv.Build != "" {
+		text += "+" + sv.Build
+	}
+	return text
+}
+
+// encodes the version as its string, such as in JSON
+func (sv SemVer) MarshalText() ([]byte, error) {
+	return []byte(sv.String()), nil
+}
+
+// orders two versions by semver precedence, returning -1, 0 or 1; build metadata
+// is ignored, and a prerelease comes before its release
+func (sv SemVer) Compare(other SemVer) int {
+	for _, pair := range [][2]uint64{{sv.Major, other.Major}, {sv.Minor, other.Minor}, {sv.Patch, other.Patch}} {
+		if pair[0] != pair[1] {
+			return compareOrdered(pair[0], pair[1])
+		}
+	}
+
+	if sv.Prerelease == other.Prerelease {
+		return 0
+	} else if sv.Prerelease == "" {
+		return 1
+	} else if other.Prerelease == "" {
+		return -1
+	}
+
+	a := strings.Split(sv.Prerelease, ".")
+	b := strings.Split(other.Prerelease, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if order := compareIdentifiers(a[i], b[i]); order != 0 {
+			return order
+		}
+	}
+	return compareOrdered(len(a), len(b))
+}
+
+// numeric identifiers compare as numbers, and before alphanumeric ones
+func compareIdentifiers(a string, b string) int {
+	aNumeric := isNumericIdentifier(a)
+	bNumeric := isNumericIdentifier(b)
+	if aNumeric && bNumeric {
+		an, _ := strconv.ParseUint(a, 10, 64)
+		bn, _ := strconv.ParseUint(b, 10, 64)
+		return compareOrdered(an, bn)
+	} else if aNumeric {
+		return -1
+	} else if bNumeric {
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// a number without leading zeros
+func isNumericIdentifier(text string) bool {
+	if len(text) == 0 || (len(text) > 1 && text[0] == '0') {
+		return false
+	}
+	for _, c := range text {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// dot-separated identifiers of alphanumerics and hyphens; a prerelease can't have
+// leading zeros in its numeric identifiers
+func validIdentifiers(text string, prerelease bool) bool {
+	for _, identifier := range strings.Split(text, ".") {
+		if len(identifier) == 0 {
+			return false
+		}
+		numeric := true
+		for _, c := range identifier {
+			if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '-') {
+				return false
+			}
+			numeric = numeric && c >= '0' && c <= '9'
+		}
+		if prerelease && numeric && !isNumericIdentifier(identifier) {
+			return false
+		}
+	}
+	return true
+}
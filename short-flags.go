@@ -0,0 +1,48 @@
+package cmdline
+
+import "strings"
+
+// makes Process expand a cluster of single-letter boolean options, such as
+// -abc, into -a -b -c
+func (cl *CommandLine) EnableShortFlagClustering() {
+	cl.shortFlagClustering = true
+}
+
+func (cl *CommandLine) expandShortFlags(cmd *command, args []string) []string {
+	expanded := make([]string, 0, len(args))
+
+	for _, arg := range args {
+		flags := cl.shortFlagCluster(cmd, arg)
+		if flags == nil {
+			expanded = append(expanded, arg)
+		} else {
+			expanded = append(expanded, flags...)
+		}
+	}
+
+	return expanded
+}
+
+// splits an arg like -abc into its flags, or returns nil if it isn't a cluster
+func (cl *CommandLine) shortFlagCluster(cmd *command, arg string) []string {
+	if len(arg) < 3 || arg[0] != '-' || arg[1] == '-' || strings.Contains(arg, ":") {
+		return nil
+	}
+
+	_, exists := cmd.OptionSpecs.lookup(arg)
+	if exists {
+		return nil
+	}
+
+	flags := make([]string, 0, len(arg)-1)
+	for _, c := range arg[1:] {
+		flag := "-" + string(c)
+		optionSpec, exists := cmd.OptionSpecs.lookup(flag)
+		if !exists || (len(optionSpec.ValueSpecs) > 0 && !optionSpec.isCount()) {
+			return nil
+		}
+		flags = append(flags, flag)
+	}
+
+	return flags
+}
@@ -0,0 +1,65 @@
+package cmdline
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+var sizeMultipliers = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kib": 1 << 10,
+	"kb":  1e3,
+	"m":   1 << 20,
+	"mib": 1 << 20,
+	"mb":  1e6,
+	"g":   1 << 30,
+	"gib": 1 << 30,
+	"gb":  1e9,
+	"t":   1 << 40,
+	"tib": 1 << 40,
+	"tb":  1e12,
+	"p":   1 << 50,
+	"pib": 1 << 50,
+	"pb":  1e15,
+}
+
+// parses a byte count with an optional, case-insensitive unit suffix. The
+// single letter suffixes (k, m, g, t, p) and the IEC suffixes (KiB, MiB, ...)
+// are powers of 1024, while the SI suffixes (KB, MB, ...) are powers of 1000.
+// For example, 10k is 10240, 4MiB is 4194304 and 2GB is 2000000000.
+func ParseSize(input string) (int64, error) {
+	text := strings.TrimSpace(input)
+
+	end := 0
+	for end < len(text) && (text[end] == '.' || (text[end] >= '0' && text[end] <= '9')) {
+		end++
+	}
+
+	multiplier, exists := sizeMultipliers[strings.ToLower(strings.TrimSpace(text[end:]))]
+	if end == 0 || !exists {
+		return 0, newKindError(ErrInvalidValue, input, "", "Invalid size: %s", input)
+	}
+
+	number := text[:end]
+	if !strings.Contains(number, ".") {
+		n, err := strconv.ParseInt(number, 10, 64)
+		if err == nil && n <= math.MaxInt64/int64(multiplier) {
+			return n * int64(multiplier), nil
+		}
+	}
+
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, newKindError(ErrInvalidValue, input, "", "Invalid size: %s", input)
+	}
+
+	size := math.Round(f * multiplier)
+	if size >= math.MaxInt64 {
+		return 0, newKindError(ErrInvalidValue, input, "", "Size out of range: %s", input)
+	}
+
+	return int64(size), nil
+}
@@ -0,0 +1,46 @@
+package cmdline
+
+import "strings"
+
+// makes Process accept Windows-style /name and /name:value options, which are
+// mapped onto the registered --name or -name option
+func (cl *CommandLine) EnableSlashOptions() {
+	cl.slashOptions = true
+}
+
+// rewrites /name[:value] into the registered option's form; args that don't name
+// a known option, such as paths, are left alone
+func expandSlashArgs(args []string, lookup func(name string) (*argSpec, bool)) []string {
+	expanded := make([]string, 0, len(args))
+
+	for _, arg := range args {
+		if len(arg) < 2 || arg[0] != '/' {
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(arg[1:], ":")
+		option, as := slashOption(name, lookup)
+		if as == nil {
+			expanded = append(expanded, arg)
+		} else if !hasValue {
+			expanded = append(expanded, option)
+		} else if as.ValuesDelim == ' ' {
+			expanded = append(expanded, option, value)
+		} else {
+			expanded = append(expanded, option+":"+value)
+		}
+	}
+
+	return expanded
+}
+
+// finds the dash option for a slash name, preferring --name over -name
+func slashOption(name string, lookup func(name string) (*argSpec, bool)) (string, *argSpec) {
+	for _, option := range []string{"--" + name, "-" + name} {
+		if as, exists := lookup(option); exists {
+			return option, as
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,37 @@
+package cmdline
+
+// identifies a compiled spec; a spec compiles differently as a primary arg
+type specCacheKey struct {
+	spec       string
+	primaryArg bool
+}
+
+// provides the compiled form of a spec; the same option spec is often registered
+// on many commands, so each spec is only parsed once
+func (cl *CommandLine) newArgSpec(spec string, primaryArg bool) *argSpec {
+	key := specCacheKey{spec: spec, primaryArg: primaryArg}
+	if compiled, exists := cl.specCache[key]; exists {
+		return compiled.clone()
+	}
+
+	as := cl.parseArgSpec(spec, primaryArg)
+
+	if cl.specCache == nil {
+		cl.specCache = map[specCacheKey]*argSpec{}
+	}
+	cl.specCache[key] = as.clone()
+	return as
+}
+
+// copies a spec, so a registration can change its copy without affecting the cache
+func (as *argSpec) clone() *argSpec {
+	copied := *as
+	copied.Aliases = append([]string(nil), as.Aliases...)
+
+	copied.ValueSpecs = make([]*argValueSpec, 0, len(as.ValueSpecs))
+	for _, valueSpec := range as.ValueSpecs {
+		copiedValue := *valueSpec
+		copied.ValueSpecs = append(copied.ValueSpecs, &copiedValue)
+	}
+	return &copied
+}
@@ -0,0 +1,110 @@
+package cmdline
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// a syntax error in a spec, panicked by the registration that parses the spec
+type SpecError struct {
+	Spec     string
+	Column   int    // the 1-based column where the error was found, in runes
+	Expected string // what the parser expected at the column, such as "']'"
+}
+
+// the message, followed by the spec with a caret under the column
+func (e *SpecError) Error() string {
+	return fmt.Sprintf("%s%s at column %d of \"%s\"\n    %s\n    %s^", basePanic, e.Expected, e.Column, e.Spec, e.Spec, strings.Repeat(" ", e.Column-1))
+}
+
+// a cursor over a spec; positions are byte offsets in the whole spec, so an error
+// reports the column in the spec as written
+type specScanner struct {
+	spec string
+	pos  int
+	end  int // the end of the part being parsed, before the help or an enclosing ']'
+}
+
+func newSpecScanner(spec string) *specScanner {
+	return &specScanner{spec: spec, end: len(spec)}
+}
+
+func (s *specScanner) done() bool {
+	return s.pos >= s.end
+}
+
+// provides the byte at an offset from the cursor, or 0 past the end
+func (s *specScanner) peekAt(offset int) byte {
+	if s.pos+offset >= s.end {
+		return 0
+	}
+	return s.spec[s.pos+offset]
+}
+
+func (s *specScanner) peek() byte {
+	return s.peekAt(0)
+}
+
+// advances past c, reporting if it was there
+func (s *specScanner) accept(c byte) bool {
+	if s.done() || s.spec[s.pos] != c {
+		return false
+	}
+	s.pos++
+	return true
+}
+
+func (s *specScanner) hasPrefix(prefix string) bool {
+	return strings.HasPrefix(s.spec[s.pos:s.end], prefix)
+}
+
+// finds substr at or after from, before the end, or returns -1
+func (s *specScanner) index(substr string, from int) int {
+	if from > s.end {
+		return -1
+	}
+	index := strings.Index(s.spec[from:s.end], substr)
+	if index >= 0 {
+		index += from
+	}
+	return index
+}
+
+// finds the first of the chars at or after the cursor, before the end, or returns -1
+func (s *specScanner) indexAny(chars string) int {
+	index := strings.IndexAny(s.spec[s.pos:s.end], chars)
+	if index >= 0 {
+		index += s.pos
+	}
+	return index
+}
+
+func (s *specScanner) errorAt(pos int, expected string) *SpecError {
+	if pos > len(s.spec) {
+		pos = len(s.spec)
+	}
+	return &SpecError{Spec: s.spec, Column: utf8.RuneCountInString(s.spec[:pos]) + 1, Expected: expected}
+}
+
+// provides the attributes of a value type; an unknown type is reported at its column
+func (s *specScanner) typeAttributes(optionTypes OptionTypes, typeName string, pos int) *OptionTypeAttributes {
+	attribs := func() *OptionTypeAttributes {
+		defer func() {
+			if r := recover(); r != nil {
+				// the default types panic with a syntax error for an unknown type
+				if err, isError := r.(error); isError && strings.HasPrefix(err.Error(), basePanic) {
+					panic(s.errorAt(pos, "valid arg type "+typeName))
+				}
+				panic(r)
+			}
+		}()
+		return optionTypes.StringToAttributes(typeName, s.spec)
+	}()
+
+	// defensive
+	if attribs == nil {
+		panic(s.errorAt(pos, "valid option type"))
+	}
+	return attribs
+}
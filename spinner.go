@@ -0,0 +1,109 @@
+package cmdline
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jimsnab/go-toolprinter"
+)
+
+// how often a spinner advances to its next frame
+const spinnerInterval = 100 * time.Millisecond
+
+var defaultSpinnerFrames = []string{"|", "/", "-", "\\"}
+
+// an activity indicator drawn on the printer's status line
+type Spinner struct {
+	mu     sync.Mutex
+	prn    toolprinter.ToolPrinter
+	text   string
+	frames []string
+	frame  int
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// sets the frames a spinner cycles through; none restores the default | / - \
+func (cl *CommandLine) SetSpinnerFrames(frames ...string) {
+	cl.spinnerFrames = append([]string(nil), frames...)
+}
+
+// starts a spinner with text on the status line of the help printer, for a wait
+// of unknown length; it isn't drawn unless stdout is a terminal
+func (cl *CommandLine) StartSpinner(text string) *Spinner {
+	s := &Spinner{
+		prn:    cl.printer(),
+		text:   text,
+		frames: cl.spinnerFrames,
+	}
+	if len(s.frames) == 0 {
+		s.frames = defaultSpinnerFrames
+	}
+
+	if cl.output != nil || cl.quiet || !xterm.IsTerminal(int(os.Stdout.Fd())) {
+		return s
+	}
+
+	stop := make(chan struct{})
+	s.stop = stop
+	s.done = make(chan struct{})
+	s.draw()
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(spinnerInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				s.advance()
+			}
+		}
+	}()
+
+	return s
+}
+
+// replaces the text shown beside the spinner
+func (s *Spinner) SetText(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.text = text
+	if s.stop != nil {
+		s.draw()
+	}
+}
+
+// removes the spinner from the status line and prints result, unless it's empty
+func (s *Spinner) Stop(result string) {
+	s.mu.Lock()
+	stop := s.stop
+	s.stop = nil
+	s.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-s.done
+		s.prn.Clear()
+	}
+
+	if result != "" {
+		s.prn.Println(result)
+	}
+}
+
+func (s *Spinner) advance() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.frame = (s.frame + 1) % len(s.frames)
+	s.draw()
+}
+
+func (s *Spinner) draw() {
+	s.prn.Status(s.frames[s.frame] + " " + s.text)
+}
@@ -0,0 +1,85 @@
+package cmdline
+
+import (
+	"context"
+	"strings"
+)
+
+// splits a raw command line into args, in the manner of a POSIX shell:
+// whitespace separates args, single quotes are literal, double quotes allow
+// backslash escapes of \ " $ and `, and an unquoted backslash escapes the
+// next character
+func SplitArgs(raw string) ([]string, error) {
+	args := []string{}
+
+	var sb strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+
+	for _, c := range raw {
+		if escaped {
+			if quote == '"' && !strings.ContainsRune("\\\"$`", c) {
+				sb.WriteRune('\\')
+			}
+			sb.WriteRune(c)
+			escaped = false
+			continue
+		}
+
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				sb.WriteRune(c)
+			}
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else if c == '\\' {
+				escaped = true
+			} else {
+				sb.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inArg = true
+		case c == '\\':
+			escaped = true
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inArg {
+				args = append(args, sb.String())
+				sb.Reset()
+				inArg = false
+			}
+		default:
+			sb.WriteRune(c)
+			inArg = true
+		}
+	}
+
+	if escaped {
+		return nil, newKindError(ErrMalformedLine, "\\", "", "Unterminated escape at end of command line")
+	}
+	if quote != 0 {
+		return nil, newKindError(ErrMalformedLine, string(quote), "", "Unterminated %c quote in command line", quote)
+	}
+
+	if inArg {
+		args = append(args, sb.String())
+	}
+
+	return args, nil
+}
+
+// splits a raw command line with SplitArgs and processes it
+func (cl *CommandLine) ProcessLine(raw string) error {
+	args, err := SplitArgs(raw)
+	if err != nil {
+		return cl.localizeError(err)
+	}
+
+	return cl.localizeError(cl.process(context.Background(), nil, args))
+}
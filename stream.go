@@ -0,0 +1,112 @@
+package cmdline
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// receives the values of a streamed value one at a time, as they are parsed
+type ValueStreamer func(value any) error
+
+// reads args from a reader one line at a time, so a long list of args, such as
+// piped file names, never has to be held in memory
+type Tokenizer struct {
+	scanner *bufio.Scanner
+	token   string
+}
+
+func NewTokenizer(r io.Reader) *Tokenizer {
+	return &Tokenizer{scanner: bufio.NewScanner(r)}
+}
+
+// advances to the next arg, skipping blank lines; false at the end or on an error
+func (t *Tokenizer) Next() bool {
+	for t.scanner.Scan() {
+		t.token = strings.TrimSuffix(t.scanner.Text(), "\r")
+		if len(t.token) > 0 {
+			return true
+		}
+	}
+	t.token = ""
+	return false
+}
+
+// provides the arg that Next advanced to
+func (t *Tokenizer) Token() string {
+	return t.token
+}
+
+// provides the error that ended Next, if any
+func (t *Tokenizer) Err() error {
+	return t.scanner.Err()
+}
+
+// passes the values of a repeated value of a command to streamer one at a time,
+// instead of collecting them into a list in Values
+func (cl *CommandLine) StreamValues(cmdName string, valueName string, streamer ValueStreamer) {
+	cmd := cl.lookupCommand(cmdName)
+
+	specs := []*argSpec{cmd.PrimaryArgSpec}
+	for _, name := range cmd.OptionSpecs.order {
+		specs = append(specs, cmd.OptionSpecs.values[name])
+	}
+
+	for _, as := range specs {
+		for _, valueSpec := range as.ValueSpecs {
+			if valueSpec.OptionName != valueName {
+				continue
+			}
+			if !valueSpec.Multi && !as.MultiValue {
+				panic(fmt.Errorf("argument error: value \"%s\" of command \"%s\" is not repeated", valueName, cmdName))
+			}
+			valueSpec.Streamer = streamer
+			cmd.Streamed = valueSpec
+			return
+		}
+	}
+
+	panic(fmt.Errorf("argument error: value \"%s\" is not registered for command \"%s\"", valueName, cmdName))
+}
+
+// processes args, followed by args read from r one line at a time; the args from
+// r are values of the command's streamed value, and are passed to its streamer
+// after the command line is parsed, before the command's handler runs
+func (cl *CommandLine) ProcessReader(args []string, r io.Reader) error {
+	return cl.localizeError(cl.processStream(context.Background(), nil, args, NewTokenizer(r), nil))
+}
+
+func (cl *CommandLine) streamTokens(ctx context.Context, cmdToRun *commandToRun, stream *Tokenizer) error {
+	spec := cmdToRun.cmd.Streamed
+
+	for stream.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if spec == nil {
+			return newKindError(ErrUnexpectedValue, stream.Token(), cmdToRun.cmd.PrimaryArgSpec.String(), "Unexpected command argument: %s", stream.Token())
+		}
+
+		if err := cmdToRun.cmd.argSpecOf(spec).storeArg(&cmdToRun.values, spec, stream.Token()); err != nil {
+			return err
+		}
+	}
+
+	return stream.Err()
+}
+
+// finds the arg spec that holds a value spec of the command
+func (cmd *command) argSpecOf(spec *argValueSpec) *argSpec {
+	for _, name := range cmd.OptionSpecs.order {
+		as := cmd.OptionSpecs.values[name]
+		for _, valueSpec := range as.ValueSpecs {
+			if valueSpec == spec {
+				return as
+			}
+		}
+	}
+	return cmd.PrimaryArgSpec
+}
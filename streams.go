@@ -0,0 +1,48 @@
+package cmdline
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// directs command data and diagnostics to separate writers; a nil data writer
+// restores the help printer, which prints to stdout, and a nil diagnostics writer
+// restores stderr
+func (cl *CommandLine) SetStreams(data, diagnostics io.Writer) {
+	cl.dataStream = data
+	cl.diagnosticStream = diagnostics
+}
+
+// prints a line of the command's data, such as output meant to be piped to another
+// program; status output is paused while it prints
+func (cl *CommandLine) Dataln(text ...any) {
+	if cl.dataStream == nil {
+		cl.printer().Println(fmt.Sprint(text...))
+		return
+	}
+
+	cl.printStream(cl.dataStream, fmt.Sprint(text...))
+}
+
+// prints a line of diagnostics, such as an error or a warning, apart from the
+// command's data; status output is paused while it prints
+func (cl *CommandLine) Errorln(text ...any) {
+	w := cl.diagnosticStream
+	if w == nil {
+		w = os.Stderr
+	}
+
+	cl.printStream(w, fmt.Sprint(text...))
+}
+
+func (cl *CommandLine) printStream(w io.Writer, line string) {
+	cl.renderMu.Lock()
+	defer cl.renderMu.Unlock()
+
+	prn := cl.printer()
+	prn.PauseStatus()
+	defer prn.ResumeStatus()
+
+	fmt.Fprintln(w, line)
+}
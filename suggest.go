@@ -0,0 +1,132 @@
+package cmdline
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// computes the Levenshtein edit distance between two strings
+func editDistance(a string, b string) int {
+	ar := []rune(a)
+	br := []rune(b)
+
+	prior := make([]int, len(br)+1)
+	current := make([]int, len(br)+1)
+	for j := range prior {
+		prior[j] = j
+	}
+
+	for i := 1; i <= len(ar); i++ {
+		current[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+
+			current[j] = prior[j-1] + cost
+			if prior[j]+1 < current[j] {
+				current[j] = prior[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		prior, current = current, prior
+	}
+
+	return prior[len(br)]
+}
+
+// provides the edit distance when the candidate is close enough to the input to suggest it
+func suggestDistance(input string, candidate string) (int, bool) {
+	maxDistance := utf8.RuneCountInString(candidate) / 3
+	if maxDistance < 1 {
+		maxDistance = 1
+	}
+
+	distance := editDistance(input, candidate)
+	return distance, distance <= maxDistance
+}
+
+// suggests a registered command or global option for an unrecognized command
+func (cl *CommandLine) suggestCommand(args []string) string {
+	best := ""
+	bestDistance := 0
+
+	candidates := make([]string, 0, len(cl.commands.order)+len(cl.globalOptions.order))
+	for _, name := range cl.commands.order {
+		if cl.commands.values[name].enabled() {
+			candidates = append(candidates, name)
+		}
+	}
+	candidates = append(candidates, cl.lazyOrder...)
+	candidates = append(candidates, cl.globalOptions.order...)
+
+	for _, candidate := range candidates {
+		if candidate == "~" {
+			continue
+		}
+
+		// compare subcommand paths with the same number of input tokens
+		tokens := strings.Count(candidate, " ") + 1
+		if tokens > len(args) {
+			continue
+		}
+		input, _ := cl.splitColon(strings.Join(args[:tokens], " "))
+
+		distance, isClose := suggestDistance(input, candidate)
+		if !isClose {
+			continue
+		}
+
+		if best == "" || distance < bestDistance || (distance == bestDistance && candidate < best) {
+			best = candidate
+			bestDistance = distance
+		}
+	}
+
+	return best
+}
+
+// makes the error for an option the command doesn't have, suggesting the closest
+// option, or listing the options when none is close
+func (cl *CommandLine) unknownOptionError(cmd *command, token string) error {
+	err := newKindError(ErrUnknownOption, token, cmd.PrimaryArgSpec.String(), "Unrecognized command argument: %s", token)
+	if !strings.HasPrefix(token, "-") {
+		return err
+	}
+
+	input, _, _ := strings.Cut(token, "=")
+	bestDistance := 0
+	for _, candidate := range cmd.OptionSpecs.names() {
+		if !strings.HasPrefix(candidate, "-") {
+			continue
+		}
+
+		distance, isClose := suggestDistance(input, candidate)
+		if distance == 0 {
+			// a registered option out of place
+			return err
+		}
+		if !isClose {
+			continue
+		}
+		if err.suggestion == "" || distance < bestDistance || (distance == bestDistance && candidate < err.suggestion) {
+			err.suggestion = candidate
+			bestDistance = distance
+		}
+	}
+
+	if err.suggestion == "" {
+		for _, name := range cmd.OptionSpecs.order {
+			if strings.HasPrefix(name, "-") {
+				err.alternatives = append(err.alternatives, name)
+			}
+		}
+	}
+
+	err.reason += err.hintText(fmt.Sprintf)
+	return err
+}
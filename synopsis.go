@@ -0,0 +1,149 @@
+package cmdline
+
+import (
+	"fmt"
+	"strings"
+)
+
+// shows a one-line synopsis, such as "users [--create <name> | --list]", at the
+// top of the help of a command
+func (cl *CommandLine) EnableSynopsis() {
+	cl.synopsisEnabled = true
+}
+
+// provides the one-line synopsis of a command, derived from its values, its options
+// and their optionality; options that conflict are joined with " | "
+func (cl *CommandLine) Synopsis(cmdstr string) (string, error) {
+	cl.loadNamedLazyCommands(strings.Fields(cmdstr))
+	if len(cmdstr) == 0 {
+		cmdstr = "~"
+	}
+
+	cmd, exists := cl.enabledCommand(cmdstr)
+	if !exists {
+		if cmdstr == "~" {
+			return "", fmt.Errorf("unnamed command not found")
+		}
+		return "", fmt.Errorf("command \"%s\" not found", cmdstr)
+	}
+
+	return cl.synopsis(cmd), nil
+}
+
+func (cl *CommandLine) synopsis(cmd *command) string {
+	var parts []string
+	if cmd.PrimaryArgSpec.Unnamed {
+		parts = append(parts, cl.getAppName()+synopsisValues(cmd.PrimaryArgSpec))
+	} else {
+		parts = append(parts, cmd.PrimaryArgSpec.Key+synopsisValues(cmd.PrimaryArgSpec))
+	}
+
+	// an option listed in a conflict is shown with the rest of its group, at the
+	// position of the group's first option
+	grouped := map[string]*optionConstraint{}
+	for _, constraint := range cmd.Constraints {
+		if constraint.kind != constraintConflicts {
+			continue
+		}
+		for _, option := range constraint.options {
+			if _, exists := grouped[option]; !exists {
+				grouped[option] = constraint
+			}
+		}
+	}
+
+	shown := map[string]bool{}
+	for _, optionName := range cmd.OptionSpecs.order {
+		if shown[optionName] {
+			continue
+		}
+
+		constraint := grouped[optionName]
+		if constraint == nil {
+			parts = append(parts, synopsisOption(cmd.OptionSpecs.values[optionName], false))
+			shown[optionName] = true
+			continue
+		}
+
+		// brackets when every option of the group is optional, otherwise parens
+		alternatives := make([]string, 0, len(constraint.options))
+		optional := true
+		for _, name := range constraint.options {
+			if shown[name] || grouped[name] != constraint {
+				continue
+			}
+			as := cmd.OptionSpecs.values[name]
+			alternatives = append(alternatives, synopsisOption(as, true))
+			optional = optional && as.Optional
+			shown[name] = true
+		}
+
+		if len(alternatives) == 1 {
+			parts = append(parts, synopsisOption(cmd.OptionSpecs.values[optionName], false))
+		} else if optional {
+			parts = append(parts, "["+strings.Join(alternatives, " | ")+"]")
+		} else {
+			parts = append(parts, "("+strings.Join(alternatives, " | ")+")")
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// an option without its aliases, ranges or patterns; bare omits the brackets
+// of an optional option, for use in a group
+func synopsisOption(as *argSpec, bare bool) string {
+	var text string
+	if as.Negation != "" {
+		text = "--[no-]" + strings.TrimPrefix(as.Key, "--")
+	} else {
+		text = as.Key
+	}
+	text += synopsisValues(as)
+
+	if as.Optional && !bare {
+		text = "[" + text + "]"
+	}
+	if as.MultiValue || as.isCount() {
+		text += "..."
+	}
+	return text
+}
+
+func synopsisValues(as *argSpec) string {
+	if as.isCount() {
+		return ""
+	}
+
+	var sb strings.Builder
+	for i, valueSpec := range as.ValueSpecs {
+		delim := string(as.ValueDelim)
+		if i == 0 {
+			delim = string(as.ValuesDelim)
+			if as.Unnamed {
+				delim = " "
+			}
+		}
+
+		text := "<" + valueSpec.OptionName + ">"
+		if valueSpec.Multi {
+			text += "..."
+		}
+		if valueSpec.Optional && delim == " " {
+			sb.WriteString(" [" + text + "]")
+		} else if valueSpec.Optional {
+			sb.WriteString("[" + delim + text + "]")
+		} else {
+			sb.WriteString(delim + text)
+		}
+	}
+	return sb.String()
+}
+
+func (hp *helpPrinter) helpPrintSynopsis(cmd *command) {
+	if !hp.synopsisEnabled {
+		return
+	}
+	hp.helpPrintln(hp.tr("Usage: %s", hp.synopsis(cmd)))
+	hp.helpPrintBlankln()
+}
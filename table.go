@@ -0,0 +1,155 @@
+package cmdline
+
+import (
+	"strings"
+)
+
+// the space between table columns
+const tableGap = 2
+
+// the narrowest a table column is made to fit the line
+const minColumnWidth = 6
+
+// prints rows in columns sized to their contents, under headers if any are given;
+// when the table is wider than the help width, the widest columns are narrowed and
+// their text wraps
+func (cl *CommandLine) PrintTable(headers []string, rows [][]string) {
+	lines := cl.tableLines(headers, rows)
+
+	cl.renderMu.Lock()
+	defer cl.renderMu.Unlock()
+
+	prn := cl.printer()
+	for _, line := range lines {
+		prn.Println(line)
+	}
+}
+
+// renders the table as lines that fit the help width
+func (cl *CommandLine) tableLines(headers []string, rows [][]string) (lines []string) {
+	columns := len(headers)
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	if columns == 0 {
+		return nil
+	}
+
+	widths := make([]int, columns)
+	measure := func(row []string) {
+		for i, cell := range row {
+			for _, line := range strings.Split(cell, "\n") {
+				if width := displayWidth(line); width > widths[i] {
+					widths[i] = width
+				}
+			}
+		}
+	}
+	measure(headers)
+	for _, row := range rows {
+		measure(row)
+	}
+
+	fitColumns(widths, cl.helpLineWidth())
+
+	printRow := func(row []string) {
+		cells := make([][]string, columns)
+		height := 1
+		for i := range cells {
+			if i < len(row) {
+				cells[i] = wrapText(row[i], widths[i])
+			}
+			if len(cells[i]) > height {
+				height = len(cells[i])
+			}
+		}
+
+		for line := 0; line < height; line++ {
+			var sb strings.Builder
+			for i, cell := range cells {
+				text := ""
+				if line < len(cell) {
+					text = cell[line]
+				}
+				if i > 0 {
+					sb.WriteString(strings.Repeat(" ", tableGap))
+				}
+				sb.WriteString(padToWidth(text, widths[i]))
+			}
+			lines = append(lines, strings.TrimRight(sb.String(), " "))
+		}
+	}
+
+	if len(headers) > 0 {
+		printRow(headers)
+		underlines := make([]string, columns)
+		for i, width := range widths {
+			underlines[i] = strings.Repeat("-", width)
+		}
+		printRow(underlines)
+	}
+	for _, row := range rows {
+		printRow(row)
+	}
+	return lines
+}
+
+// narrows the widest columns until the table fits the line width, or until each
+// column is at its minimum
+func fitColumns(widths []int, lineWidth int) {
+	total := tableGap * (len(widths) - 1)
+	for _, width := range widths {
+		total += width
+	}
+
+	for total > lineWidth {
+		widest := 0
+		for i, width := range widths {
+			if width > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= minColumnWidth {
+			return
+		}
+		widths[widest]--
+		total--
+	}
+}
+
+// splits text into lines no wider than width columns, breaking at spaces where possible
+func wrapText(text string, width int) []string {
+	lines := []string{}
+	for _, paragraph := range strings.Split(text, "\n") {
+		var line strings.Builder
+		lineWidth := 0
+		endLine := func() {
+			lines = append(lines, line.String())
+			line.Reset()
+			lineWidth = 0
+		}
+
+		for _, word := range strings.Fields(paragraph) {
+			if lineWidth > 0 && lineWidth+1+displayWidth(word) > width {
+				endLine()
+			}
+			if lineWidth > 0 {
+				line.WriteByte(' ')
+				lineWidth++
+			}
+
+			// a word wider than the line is split where it reaches the edge
+			for _, r := range word {
+				if lineWidth > 0 && lineWidth+runeWidth(r) > width {
+					endLine()
+				}
+				line.WriteRune(r)
+				lineWidth += runeWidth(r)
+			}
+		}
+		endLine()
+	}
+	return lines
+}
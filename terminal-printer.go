@@ -0,0 +1,171 @@
+package cmdline
+
+import (
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jimsnab/go-toolprinter"
+)
+
+// returns to the start of the line, and erases from there to the end of the line
+const (
+	carriageReturn = "\r"
+	eraseToLineEnd = "\x1b[K"
+)
+
+// a toolprinter printer whose status line is drawn with a carriage return rather than
+// with backspaces; the status, counter and pause logic is toolprinter's, kept by a
+// test printer that doesn't draw, and the lines are printed by the toolprinter printer
+type terminalPrinter struct {
+	toolprinter.ToolPrinter
+	state      *toolprinter.TestPrinter
+	pauseCount int
+	shown      string
+	shownWidth int // the columns of the status on the terminal
+	// a segmented print is in progress
+	segmented bool
+}
+
+// provides a printer for stdout that rewrites its status line in place with a
+// carriage return and an ANSI erase, rather than with backspaces, which some Windows
+// consoles don't handle; on Windows, it turns on the console's virtual terminal
+// processing, and where that isn't available, the prior status is overwritten with
+// spaces
+func NewTerminalPrinter() toolprinter.ToolPrinter {
+	return &terminalPrinter{ToolPrinter: toolprinter.NewToolPrinter(), state: toolprinter.NewTestPrinter()}
+}
+
+// draws the status text, unless it's already shown
+func (tp *terminalPrinter) draw(text string) {
+	fd := int(os.Stdout.Fd())
+	if !xterm.IsTerminal(fd) {
+		return
+	}
+
+	width, _, err := xterm.GetSize(fd)
+	if err != nil {
+		return
+	}
+
+	if displayWidth(text) >= width {
+		text = truncateToWidth(text, width-1)
+	}
+	if text == tp.shown {
+		return
+	}
+
+	if xterm.EnableVirtualTerminal(fd) {
+		io.WriteString(os.Stdout, carriageReturn+text+eraseToLineEnd)
+	} else {
+		// blank the prior status, then write the new one
+		blank := ""
+		if tp.shownWidth > 0 {
+			blank = strings.Repeat(" ", tp.shownWidth) + carriageReturn
+		}
+		io.WriteString(os.Stdout, carriageReturn+blank+text)
+	}
+
+	tp.shown = text
+	tp.shownWidth = displayWidth(text)
+}
+
+// applies a status change, and draws the status unless it is paused
+func (tp *terminalPrinter) update(fn func()) {
+	fn()
+	if tp.pauseCount == 0 {
+		tp.draw(tp.state.GetStatusText())
+	}
+}
+
+// runs fn with the status removed, so printed output starts at the line start
+func (tp *terminalPrinter) printing(fn func()) {
+	tp.PauseStatus()
+	defer tp.ResumeStatus()
+	fn()
+}
+
+func (tp *terminalPrinter) Status(text ...interface{}) {
+	tp.update(func() { tp.state.Status(text...) })
+}
+
+func (tp *terminalPrinter) Statusf(format string, args ...interface{}) {
+	tp.update(func() { tp.state.Statusf(format, args...) })
+}
+
+func (tp *terminalPrinter) Clear() {
+	tp.update(func() { tp.state.Clear() })
+}
+
+func (tp *terminalPrinter) ChattyStatus(text ...interface{}) {
+	tp.update(func() { tp.state.ChattyStatus(text...) })
+}
+
+func (tp *terminalPrinter) ChattyStatusf(format string, args ...interface{}) {
+	tp.update(func() { tp.state.ChattyStatusf(format, args...) })
+}
+
+func (tp *terminalPrinter) SetCounterMax(max int, text ...interface{}) {
+	tp.update(func() { tp.state.SetCounterMax(max, text...) })
+}
+
+func (tp *terminalPrinter) UpdateCountStatus(extraStatusText ...interface{}) {
+	tp.update(func() { tp.state.UpdateCountStatus(extraStatusText...) })
+}
+
+func (tp *terminalPrinter) Count() {
+	tp.update(func() { tp.state.Count() })
+}
+
+func (tp *terminalPrinter) DateRangeStatus(from time.Time, to time.Time, purpose ...interface{}) {
+	tp.update(func() { tp.state.DateRangeStatus(from, to, purpose...) })
+}
+
+func (tp *terminalPrinter) PauseStatus() {
+	tp.state.PauseStatus()
+	tp.pauseCount++
+	tp.draw("")
+}
+
+func (tp *terminalPrinter) ResumeStatus() {
+	if tp.pauseCount == 0 {
+		return
+	}
+	tp.pauseCount--
+	tp.update(func() { tp.state.ResumeStatus() })
+}
+
+func (tp *terminalPrinter) Println(text ...interface{}) {
+	tp.printing(func() { tp.ToolPrinter.Println(text...) })
+}
+
+func (tp *terminalPrinter) Printlnf(format string, args ...interface{}) {
+	tp.printing(func() { tp.ToolPrinter.Printlnf(format, args...) })
+}
+
+func (tp *terminalPrinter) VerbosePrintln(text ...interface{}) {
+	tp.printing(func() { tp.ToolPrinter.VerbosePrintln(text...) })
+}
+
+func (tp *terminalPrinter) VerbosePrintlnf(format string, args ...interface{}) {
+	tp.printing(func() { tp.ToolPrinter.VerbosePrintlnf(format, args...) })
+}
+
+func (tp *terminalPrinter) BeginPrint(text ...interface{}) {
+	tp.PauseStatus()
+	tp.ToolPrinter.BeginPrint(text...)
+	tp.segmented = true
+}
+
+func (tp *terminalPrinter) EndPrint(text ...interface{}) {
+	tp.ToolPrinter.EndPrint(text...)
+	tp.segmented = false
+	tp.ResumeStatus()
+}
+
+func (tp *terminalPrinter) EndPrintIfStarted() {
+	if tp.segmented {
+		tp.EndPrint("")
+	}
+}
@@ -4,7 +4,8 @@ import (
 	"github.com/jimsnab/go-toolprinter"
 )
 
-var Prn = toolprinter.NewToolPrinter()
+// the package printer, which is safe for concurrent use
+var Prn toolprinter.ToolPrinter = NewSafePrinter(toolprinter.NewToolPrinter())
 
 func SetPrinter(prn toolprinter.ToolPrinter) toolprinter.ToolPrinter {
 	prior := prn
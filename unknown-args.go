@@ -0,0 +1,36 @@
+package cmdline
+
+// what Process does with an argument the command doesn't recognize
+type UnknownArgPolicy int
+
+const (
+	// fails with an unrecognized argument error; this is the default
+	UnknownArgsError UnknownArgPolicy = iota
+	// collects the arguments, in order, into the []string value UnknownArgsKey
+	UnknownArgsCollect
+	// skips the arguments
+	UnknownArgsIgnore
+)
+
+// the key of the unrecognized arguments with UnknownArgsCollect
+const UnknownArgsKey = "_unknown"
+
+// sets what Process does with an argument the command doesn't recognize, such as
+// passing it on, for a wrapper of another tool
+func (cl *CommandLine) SetUnknownArgPolicy(policy UnknownArgPolicy) {
+	cl.unknownArgPolicy = policy
+}
+
+// handles an unrecognized argument according to the policy
+func (cl *CommandLine) unknownArg(cmd *command, values Values, arg string, token string) error {
+	switch cl.unknownArgPolicy {
+	case UnknownArgsCollect:
+		unknown, _ := values[UnknownArgsKey].([]string)
+		values[UnknownArgsKey] = append(unknown, arg)
+		return nil
+	case UnknownArgsIgnore:
+		return nil
+	default:
+		return cl.unknownOptionError(cmd, token)
+	}
+}
@@ -0,0 +1,26 @@
+package cmdline
+
+import "strings"
+
+// checks a UUID in its canonical 8-4-4-4-12 hex form, such as
+// 123e4567-e89b-12d3-a456-426614174000, providing it in lowercase
+func parseUUID(input string) (string, error) {
+	if len(input) != 36 {
+		return "", newKindError(ErrInvalidValue, input, "", "Invalid UUID: %s", input)
+	}
+
+	for i, c := range input {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return "", newKindError(ErrInvalidValue, input, "", "Invalid UUID: %s", input)
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+				return "", newKindError(ErrInvalidValue, input, "", "Invalid UUID: %s", input)
+			}
+		}
+	}
+
+	return strings.ToLower(input), nil
+}
@@ -0,0 +1,61 @@
+package cmdline
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// checks a parsed value, returning an error to reject it
+type ValueValidator func(value any) error
+
+// adds a validator for every value spec with the given name, such as "port" in
+// <int-port>; validators run in the order added, as each value is parsed, and
+// the first error is returned by Process
+func (cl *CommandLine) AddValidator(valueName string, validator ValueValidator) {
+	if len(valueName) == 0 || validator == nil {
+		panic(fmt.Errorf("argument error: a validator requires a value name and a function"))
+	}
+
+	if cl.validators == nil {
+		cl.validators = map[string][]ValueValidator{}
+	}
+	cl.validators[valueName] = append(cl.validators[valueName], validator)
+}
+
+func (cl *CommandLine) validateValue(spec *argValueSpec, value any) error {
+	if err := spec.checkRange(value); err != nil {
+		return err
+	}
+
+	if spec.Pattern != nil {
+		if err := checkPattern(spec.Pattern, spec.OptionName, value); err != nil {
+			return err
+		}
+	}
+
+	if pattern := cl.patterns[spec.OptionName]; pattern != nil {
+		if err := checkPattern(pattern, spec.OptionName, value); err != nil {
+			return err
+		}
+	}
+
+	for _, validator := range cl.validators[spec.OptionName] {
+		if err := validator(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validates the element just appended to a list value
+func (cl *CommandLine) validateLastElement(spec *argValueSpec, list any) error {
+	if spec.RangeText == "" && spec.Pattern == nil && cl.patterns[spec.OptionName] == nil && len(cl.validators[spec.OptionName]) == 0 {
+		return nil
+	}
+
+	rv := reflect.ValueOf(list)
+	if rv.Kind() != reflect.Slice || rv.Len() == 0 {
+		return cl.validateValue(spec, list)
+	}
+	return cl.validateValue(spec, rv.Index(rv.Len()-1).Interface())
+}
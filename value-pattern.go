@@ -0,0 +1,77 @@
+package cmdline
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// requires every value with the given name to match the pattern, such as
+// "name" in <string-name>; a nil pattern removes it
+func (cl *CommandLine) SetPattern(valueName string, pattern *regexp.Regexp) {
+	if len(valueName) == 0 {
+		panic(fmt.Errorf("argument error: a pattern requires a value name"))
+	}
+
+	if pattern == nil {
+		delete(cl.patterns, valueName)
+		return
+	}
+
+	if cl.patterns == nil {
+		cl.patterns = map[string]*regexp.Regexp{}
+	}
+	cl.patterns[valueName] = pattern
+}
+
+func parseValuePattern(avs *argValueSpec, defaultValue any, patternText string, s *specScanner, patternPos int) {
+	if _, isString := defaultValue.(string); !isString {
+		panic(s.errorAt(patternPos, "string type for a pattern"))
+	}
+
+	pattern, err := regexp.Compile(patternText)
+	if err != nil {
+		panic(s.errorAt(patternPos+1, "valid pattern"))
+	}
+
+	avs.Pattern = pattern
+}
+
+// finds the last occurrence of substr that isn't within a value pattern such as <string/^a?$/-name>
+func lastIndexOutsidePatterns(spec string, substr string) int {
+	spans := valuePatternSpans.FindAllStringIndex(spec, -1)
+
+	end := len(spec)
+	for {
+		index := strings.LastIndex(spec[:end], substr)
+		if index < 0 {
+			return index
+		}
+
+		inPattern := false
+		for _, span := range spans {
+			if index >= span[0] && index < span[1] {
+				inPattern = true
+				break
+			}
+		}
+		if !inPattern {
+			return index
+		}
+		end = index
+	}
+}
+
+var valuePatternSpans = regexp.MustCompile(`<[A-Za-z0-9_]+/.*?/-`)
+
+func checkPattern(pattern *regexp.Regexp, valueName string, value any) error {
+	text, isString := value.(string)
+	if !isString {
+		text = fmt.Sprint(value)
+	}
+
+	if !pattern.MatchString(text) {
+		return newKindError(ErrInvalidValue, text, "", "Value %s must match the pattern %s", valueName, pattern.String())
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package cmdline
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// parses the min..max text of a value range; either bound can be omitted
+func (cl *CommandLine) parseValueRange(avs *argValueSpec, typeIndex int, rangeText string, s *specScanner, rangePos int) {
+	minText, maxText, found := strings.Cut(rangeText, "..")
+	if !found || (minText == "" && maxText == "") {
+		panic(s.errorAt(rangePos+1, "range min..max"))
+	}
+
+	parseBound := func(text string, pos int) any {
+		if text == "" {
+			return nil
+		}
+		bound, err := cl.optionTypes.MakeValue(typeIndex, text)
+		if err != nil {
+			panic(s.errorAt(pos, "valid range bound"))
+		}
+		if _, ok := compareValues(bound, bound); !ok {
+			panic(s.errorAt(pos, "numeric type for a range"))
+		}
+		return bound
+	}
+
+	avs.RangeMin = parseBound(minText, rangePos+1)
+	avs.RangeMax = parseBound(maxText, rangePos+1+len(minText)+2)
+
+	if avs.RangeMin != nil && avs.RangeMax != nil {
+		if order, _ := compareValues(avs.RangeMin, avs.RangeMax); order > 0 {
+			panic(s.errorAt(rangePos+1, "range min at or below max"))
+		}
+	}
+
+	avs.RangeText = rangeText
+}
+
+// orders two values of the same numeric or version type, returning false for other types
+func compareValues(a, b any) (int, bool) {
+	switch av := a.(type) {
+	case int:
+		return compareOrdered(av, b.(int)), true
+	case int64:
+		return compareOrdered(av, b.(int64)), true
+	case uint:
+		return compareOrdered(av, b.(uint)), true
+	case float64:
+		return compareOrdered(av, b.(float64)), true
+	case time.Duration:
+		return compareOrdered(av, b.(time.Duration)), true
+	case SemVer:
+		return av.Compare(b.(SemVer)), true
+	default:
+		return 0, false
+	}
+}
+
+func compareOrdered[T int | int64 | uint | uint64 | float64 | time.Duration](a, b T) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+func (avs *argValueSpec) checkRange(value any) error {
+	if avs.RangeMin != nil {
+		if order, _ := compareValues(value, avs.RangeMin); order < 0 {
+			return avs.rangeError(value)
+		}
+	}
+	if avs.RangeMax != nil {
+		if order, _ := compareValues(value, avs.RangeMax); order > 0 {
+			return avs.rangeError(value)
+		}
+	}
+	return nil
+}
+
+func (avs *argValueSpec) rangeError(value any) error {
+	token := fmt.Sprint(value)
+	if avs.RangeMax == nil {
+		return newKindError(ErrInvalidValue, token, "", "Value %s must be at least %v", avs.OptionName, avs.RangeMin)
+	} else if avs.RangeMin == nil {
+		return newKindError(ErrInvalidValue, token, "", "Value %s must be at most %v", avs.OptionName, avs.RangeMax)
+	}
+	return newKindError(ErrInvalidValue, token, "", "Value %s must be in the range %v to %v", avs.OptionName, avs.RangeMin, avs.RangeMax)
+}
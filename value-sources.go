@@ -0,0 +1,94 @@
+package cmdline
+
+// where a value came from
+type ValueSource string
+
+const (
+	// the value was given on the command line
+	SourceCommandLine ValueSource = "command line"
+	// the value is the default of its spec
+	SourceDefault ValueSource = "default"
+	// the value is a default that refers to an environment variable, as in ${HOME}
+	SourceEnvironment ValueSource = "environment"
+	// the value was asked for, as a missing secret is after EnableSecretPrompts
+	SourcePrompt ValueSource = "prompt"
+)
+
+// the key of the value sources after EnableValueSources, which maps a value name to
+// the source of a value that wasn't given on the command line; Values.Source reads it
+const ValueSourcesKey = "_sources"
+
+// makes the values given to handlers keep the source of each value, under
+// ValueSourcesKey, so that a handler can call Values.Source
+func (cl *CommandLine) EnableValueSources() {
+	cl.valueSources = true
+}
+
+// provides where a value came from, or "" if there is no value of that name or the
+// values don't keep their sources, as they do after EnableValueSources
+func (v Values) Source(name string) ValueSource {
+	sources, kept := v[ValueSourcesKey].(map[string]ValueSource)
+	if !kept {
+		return ""
+	}
+	return valueSource(v, sources, name)
+}
+
+// provides where a command value came from, or "" if there is no value of that name
+func (pc *ParsedCommand) Source(name string) ValueSource {
+	return valueSource(pc.Values, pc.sources, name)
+}
+
+// provides where a command value came from, or "" if there is no value of that name
+func (inv *Invocation) Source(name string) ValueSource {
+	return valueSource(inv.Values, inv.sources, name)
+}
+
+// provides where a global option value came from, or "" if there is no value of
+// that name
+func (opt *ParsedGlobalOption) Source(name string) ValueSource {
+	return valueSource(opt.Values, opt.sources, name)
+}
+
+func valueSource(values Values, sources map[string]ValueSource, name string) ValueSource {
+	if _, exists := values[name]; !exists || name == "" || name == ValueSourcesKey {
+		return ""
+	}
+	if source, exists := sources[name]; exists {
+		return source
+	}
+	return SourceCommandLine
+}
+
+// records the source of a value that wasn't given on the command line; while the
+// values are parsed, the sources are kept in them
+func setValueSource(values map[string]any, name string, source ValueSource) {
+	sources, _ := values[ValueSourcesKey].(map[string]ValueSource)
+	if sources == nil {
+		sources = map[string]ValueSource{}
+		values[ValueSourcesKey] = sources
+	}
+	sources[name] = source
+}
+
+// forgets the source of a value that is given on the command line after all
+func clearValueSource(values map[string]any, name string) {
+	if sources, _ := values[ValueSourcesKey].(map[string]ValueSource); sources != nil {
+		delete(sources, name)
+	}
+}
+
+// provides the sources of the parsed values, taking them out of the values unless
+// they are to be kept there
+func (cl *CommandLine) takeValueSources(values map[string]any) map[string]ValueSource {
+	sources, _ := values[ValueSourcesKey].(map[string]ValueSource)
+	if sources == nil {
+		sources = map[string]ValueSource{}
+	}
+	if cl.valueSources {
+		values[ValueSourcesKey] = sources
+	} else {
+		delete(values, ValueSourcesKey)
+	}
+	return sources
+}
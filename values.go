@@ -0,0 +1,286 @@
+package cmdline
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Bind fills the fields of the struct pointed to by target, using the
+// `cmdline:"name"` field tag to select the value. Values are converted to
+// the field type when the field holds the value exactly, e.g., int to int64,
+// or a string to a number; a float isn't bound to an integer field, and a number
+// out of the field's range is an error.
+func (v Values) Bind(target any) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind target must be a pointer to a struct")
+	}
+
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name, tagged := field.Tag.Lookup("cmdline")
+		if !tagged || name == "-" {
+			continue
+		}
+
+		if !field.IsExported() {
+			return fmt.Errorf("cannot bind \"%s\" to unexported field %s", name, field.Name)
+		}
+
+		value, exists := v[name]
+		if !exists || value == nil {
+			continue
+		}
+
+		err := bindValue(rv.Field(i), value)
+		if err != nil {
+			return fmt.Errorf("cannot bind \"%s\" to field %s: %w", name, field.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func isNumericKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func bindValue(field reflect.Value, value any) error {
+	rv := reflect.ValueOf(value)
+	ft := field.Type()
+
+	if rv.Type().AssignableTo(ft) {
+		field.Set(rv)
+		return nil
+	}
+
+	if rv.Kind() == reflect.String && ft.Kind() != reflect.String {
+		return bindString(field, rv.String())
+	}
+
+	if rv.Kind() == reflect.Slice && ft.Kind() == reflect.Slice {
+		list := reflect.MakeSlice(ft, rv.Len(), rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			err := bindValue(list.Index(i), rv.Index(i).Interface())
+			if err != nil {
+				return err
+			}
+		}
+		field.Set(list)
+		return nil
+	}
+
+	if isNumericKind(rv.Kind()) && isNumericKind(ft.Kind()) {
+		return bindNumber(field, rv)
+	}
+
+	if rv.Type().ConvertibleTo(ft) && rv.Kind() == ft.Kind() {
+		field.Set(rv.Convert(ft))
+		return nil
+	}
+
+	return fmt.Errorf("type %s is not convertible to %s", rv.Type(), ft)
+}
+
+// sets a numeric field from a number of another type, when the field holds it exactly;
+// a float isn't bound to an integer, and a number out of the field's range is an error
+func bindNumber(field reflect.Value, rv reflect.Value) error {
+	ft := field.Type()
+	overflows := false
+
+	switch {
+	case isFloatKind(rv.Kind()) && !isFloatKind(ft.Kind()):
+		return fmt.Errorf("type %s is not convertible to %s", rv.Type(), ft)
+
+	case isFloatKind(ft.Kind()):
+		overflows = isFloatKind(rv.Kind()) && field.OverflowFloat(rv.Float())
+
+	case isIntKind(ft.Kind()):
+		if isIntKind(rv.Kind()) {
+			overflows = field.OverflowInt(rv.Int())
+		} else {
+			overflows = rv.Uint() > math.MaxInt64 || field.OverflowInt(int64(rv.Uint()))
+		}
+
+	default:
+		if isIntKind(rv.Kind()) {
+			overflows = rv.Int() < 0 || field.OverflowUint(uint64(rv.Int()))
+		} else {
+			overflows = field.OverflowUint(rv.Uint())
+		}
+	}
+
+	if overflows {
+		return fmt.Errorf("value %v overflows %s", rv.Interface(), ft)
+	}
+	field.Set(rv.Convert(ft))
+	return nil
+}
+
+func isIntKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	default:
+		return false
+	}
+}
+
+func isFloatKind(kind reflect.Kind) bool {
+	return kind == reflect.Float32 || kind == reflect.Float64
+}
+
+func bindString(field reflect.Value, input string) error {
+	switch field.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(input)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(input, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(input, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(input, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+
+	default:
+		return fmt.Errorf("type string is not convertible to %s", field.Type())
+	}
+
+	return nil
+}
+
+func valueAs[T any](v Values, name string) (T, bool) {
+	value, ok := v[name].(T)
+	return value, ok
+}
+
+// provides the string value, or an empty string if it isn't a string
+func (v Values) String(name string) string {
+	value, _ := v.StringOk(name)
+	return value
+}
+
+func (v Values) StringOk(name string) (string, bool) {
+	return valueAs[string](v, name)
+}
+
+// provides the int value, or zero if it isn't an int
+func (v Values) Int(name string) int {
+	value, _ := v.IntOk(name)
+	return value
+}
+
+func (v Values) IntOk(name string) (int, bool) {
+	return valueAs[int](v, name)
+}
+
+// provides the bool value, or false if it isn't a bool
+func (v Values) Bool(name string) bool {
+	value, _ := v.BoolOk(name)
+	return value
+}
+
+func (v Values) BoolOk(name string) (bool, bool) {
+	return valueAs[bool](v, name)
+}
+
+// provides the float64 value, or zero if it isn't a float64
+func (v Values) Float64(name string) float64 {
+	value, _ := v.Float64Ok(name)
+	return value
+}
+
+func (v Values) Float64Ok(name string) (float64, bool) {
+	return valueAs[float64](v, name)
+}
+
+// provides the duration value, or zero if it isn't a duration
+func (v Values) Duration(name string) time.Duration {
+	value, _ := v.DurationOk(name)
+	return value
+}
+
+func (v Values) DurationOk(name string) (time.Duration, bool) {
+	return valueAs[time.Duration](v, name)
+}
+
+// provides the string list value, or nil if it isn't a string list
+func (v Values) StringSlice(name string) []string {
+	value, _ := v.StringSliceOk(name)
+	return value
+}
+
+func (v Values) StringSliceOk(name string) ([]string, bool) {
+	return valueAs[[]string](v, name)
+}
+
+// provides the int list value, or nil if it isn't an int list
+func (v Values) IntSlice(name string) []int {
+	value, _ := v.IntSliceOk(name)
+	return value
+}
+
+func (v Values) IntSliceOk(name string) ([]int, bool) {
+	return valueAs[[]int](v, name)
+}
+
+// provides the contents read for a file value, or nil if it isn't a file value
+func (v Values) Bytes(name string) []byte {
+	value, _ := v.BytesOk(name)
+	return value
+}
+
+func (v Values) BytesOk(name string) ([]byte, bool) {
+	return valueAs[[]byte](v, name)
+}
+
+// provides the uint value, or zero if it isn't a uint
+func (v Values) Uint(name string) uint {
+	value, _ := v.UintOk(name)
+	return value
+}
+
+func (v Values) UintOk(name string) (uint, bool) {
+	return valueAs[uint](v, name)
+}
+
+// provides the int64 value, or zero if it isn't an int64; size values are int64
+func (v Values) Int64(name string) int64 {
+	value, _ := v.Int64Ok(name)
+	return value
+}
+
+func (v Values) Int64Ok(name string) (int64, bool) {
+	return valueAs[int64](v, name)
+}
@@ -0,0 +1,55 @@
+package cmdline
+
+// the level of each verbosity global option
+var verbosityLevels = map[string]int{
+	"-v":   1,
+	"-vv":  2,
+	"-vvv": 3,
+}
+
+// registers the -v, -vv, -vvv and --quiet global options, which set the level
+// provided by Verbosity; the printer's verbose output follows the level
+func (cl *CommandLine) EnableVerbosity() {
+	noop := func(values Values) error { return nil }
+
+	cl.RegisterGlobalOption(noop, "-v?Prints more detail")
+	cl.RegisterGlobalOption(noop, "-vv?Prints even more detail")
+	cl.RegisterGlobalOption(noop, "-vvv?Prints all detail")
+	cl.RegisterGlobalOption(noop, "--quiet?Prints only errors")
+
+	cl.verbosityEnabled = true
+}
+
+// provides the verbosity of the most recent command line: 0 by default, 1 to 3 for
+// -v to -vvv, with repeats adding up, or -1 for --quiet, which takes precedence
+func (cl *CommandLine) Verbosity() int {
+	return int(cl.verbosity.Load())
+}
+
+// reports if the spec is of a verbosity global option, whose repeats add up
+func (cl *CommandLine) isVerbosityOption(as *argSpec) bool {
+	if _, counted := verbosityLevels[as.Key]; !counted || !cl.verbosityEnabled {
+		return false
+	}
+	globalOpt, exists := cl.globalOptions.values[as.Key]
+	return exists && globalOpt.argSpec == as
+}
+
+// sets the verbosity from the global options given, before their handlers run
+func (cl *CommandLine) setVerbosity(globalOptions []string) {
+	if !cl.verbosityEnabled {
+		return
+	}
+
+	level := 0
+	for _, name := range globalOptions {
+		if name == "--quiet" {
+			level = -1
+			break
+		}
+		level += verbosityLevels[name]
+	}
+
+	cl.verbosity.Store(int32(level))
+	cl.printer().EnableVerbose(level > 0)
+}
@@ -0,0 +1,56 @@
+package cmdline
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// returned by Process after the --version global option prints the version
+var ErrVersionShown = errors.New("version shown")
+
+const defaultVersionTemplate = "Version: {{.Version}}\n" +
+	"{{if .Commit}}Commit:  {{.Commit}}\n{{end}}" +
+	"{{if .Date}}Date:    {{.Date}}\n{{end}}"
+
+// the data available to the version template
+type VersionInfo struct {
+	Version string
+	Commit  string
+	Date    string
+}
+
+// registers a --version global option that prints the version and stops processing
+func (cl *CommandLine) SetVersion(version, commit, date string) {
+	cl.versionInfo = &VersionInfo{Version: version, Commit: commit, Date: date}
+
+	if cl.versionTemplate == nil {
+		cl.SetVersionTemplate(defaultVersionTemplate)
+	}
+
+	_, exists := cl.globalOptions.values["--version"]
+	if !exists {
+		cl.RegisterGlobalOption(cl.versionHandler, "--version?Prints the version")
+	}
+}
+
+// replaces the text/template used to print the version; the template receives a VersionInfo
+func (cl *CommandLine) SetVersionTemplate(text string) {
+	tmpl, err := template.New("version").Parse(text)
+	if err != nil {
+		panic(fmt.Errorf("argument error: invalid version template: %w", err))
+	}
+	cl.versionTemplate = tmpl
+}
+
+func (cl *CommandLine) versionHandler(values Values) error {
+	var sb strings.Builder
+	err := cl.versionTemplate.Execute(&sb, cl.versionInfo)
+	if err != nil {
+		return err
+	}
+
+	cl.printer().Println(strings.TrimSuffix(sb.String(), "\n"))
+	return ErrVersionShown
+}
@@ -0,0 +1,94 @@
+package cmdline
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jimsnab/go-toolprinter"
+)
+
+// writerPrinter is a ToolPrinter that prints to an io.Writer. A writer isn't
+// treated as a terminal, so status output is dropped.
+type writerPrinter struct {
+	w              io.Writer
+	nestedPrint    bool
+	verboseEnabled bool
+}
+
+func NewWriterPrinter(w io.Writer) toolprinter.ToolPrinter {
+	return &writerPrinter{w: w}
+}
+
+func (wp *writerPrinter) Status(text ...interface{})                       {}
+func (wp *writerPrinter) Statusf(format string, args ...interface{})       {}
+func (wp *writerPrinter) Clear()                                           {}
+func (wp *writerPrinter) ChattyStatus(text ...interface{})                 {}
+func (wp *writerPrinter) ChattyStatusf(format string, args ...interface{}) {}
+func (wp *writerPrinter) SetCounterMax(max int, text ...interface{})       {}
+func (wp *writerPrinter) UpdateCountStatus(extraStatusText ...interface{}) {}
+func (wp *writerPrinter) Count()                                           {}
+func (wp *writerPrinter) PauseStatus()                                     {}
+func (wp *writerPrinter) ResumeStatus()                                    {}
+
+func (wp *writerPrinter) DateRangeStatus(from time.Time, to time.Time, purpose ...interface{}) {}
+
+func (wp *writerPrinter) Println(text ...interface{}) {
+	if wp.nestedPrint {
+		panic(fmt.Errorf("in a nested print"))
+	}
+	fmt.Fprintln(wp.w, fmt.Sprint(text...))
+}
+
+func (wp *writerPrinter) Printlnf(format string, args ...interface{}) {
+	wp.Println(fmt.Sprintf(format, args...))
+}
+
+func (wp *writerPrinter) BeginPrint(text ...interface{}) {
+	if wp.nestedPrint {
+		panic(fmt.Errorf("in a nested print"))
+	}
+	fmt.Fprint(wp.w, fmt.Sprint(text...))
+	wp.nestedPrint = true
+}
+
+func (wp *writerPrinter) ContinuePrint(text ...interface{}) {
+	if !wp.nestedPrint {
+		panic(fmt.Errorf("segmented printing didn't begin yet"))
+	}
+	fmt.Fprint(wp.w, fmt.Sprint(text...))
+}
+
+func (wp *writerPrinter) ContinuePrintf(format string, args ...interface{}) {
+	wp.ContinuePrint(fmt.Sprintf(format, args...))
+}
+
+func (wp *writerPrinter) EndPrint(text ...interface{}) {
+	if !wp.nestedPrint {
+		panic(fmt.Errorf("segmented printing didn't begin yet"))
+	}
+	fmt.Fprintln(wp.w, fmt.Sprint(text...))
+	wp.nestedPrint = false
+}
+
+func (wp *writerPrinter) EndPrintIfStarted() {
+	if wp.nestedPrint {
+		wp.EndPrint("")
+	}
+}
+
+func (wp *writerPrinter) VerbosePrintln(text ...interface{}) {
+	if wp.verboseEnabled {
+		wp.Println(text...)
+	}
+}
+
+func (wp *writerPrinter) VerbosePrintlnf(format string, args ...interface{}) {
+	if wp.verboseEnabled {
+		wp.Printlnf(format, args...)
+	}
+}
+
+func (wp *writerPrinter) EnableVerbose(enabled bool) {
+	wp.verboseEnabled = enabled
+}